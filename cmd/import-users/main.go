@@ -0,0 +1,165 @@
+// Command import-users bulk-loads accounts from another system with their
+// password hashes intact. Each line of the input file is a JSON object:
+//
+//	{"email": "jane@example.com", "name": "Jane", "password_hash": "pbkdf2_sha256$...", "email_verified": true}
+//
+// Accepted hash formats are argon2id, bcrypt, PBKDF2-SHA256 (Django or passlib
+// encoding) and scrypt (PHC encoding). Foreign hashes are replaced with the
+// configured algorithm the first time the user logs in. Existing emails are skipped.
+//
+//	go run ./cmd/import-users -file users.jsonl
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type importedUser struct {
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	PasswordHash  string `json:"password_hash"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+func main() {
+	var (
+		configPath string
+		file       string
+		dryRun     bool
+	)
+	flag.StringVar(&configPath, "config", "", "Path to config file")
+	flag.StringVar(&file, "file", "", "JSON Lines file with the users to import")
+	flag.BoolVar(&dryRun, "dry-run", false, "Validate the file without writing anything")
+	flag.Parse()
+
+	if file == "" {
+		flag.Usage()
+		log.Fatal("❌ -file is required")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("❌ Error loading config: %v", err)
+	}
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		log.Fatalf("❌ Error creating logger: %v", err)
+	}
+	defer logger.Sync()
+
+	passwordHasher, err := utils.NewPasswordHasher(&cfg.PasswordHashing)
+	if err != nil {
+		logger.Fatal("❌ Error creating password hasher", zap.Error(err))
+	}
+
+	input, err := os.Open(file)
+	if err != nil {
+		logger.Fatal("❌ Error opening input file", zap.Error(err))
+	}
+	defer input.Close()
+
+	var userRepo repository.UserRepository
+	if !dryRun {
+		db, err := database.NewDB(cfg, logger)
+		if err != nil {
+			logger.Fatal("❌ Error connecting to database", zap.Error(err))
+		}
+		defer db.Close()
+		userRepo = repository.NewUserRepository(db, logger)
+	}
+
+	var imported, skipped, failed int
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		user, err := parseUser(scanner.Bytes(), passwordHasher)
+		if err != nil {
+			logger.Error("Invalid user record", zap.Int("line", line), zap.Error(err))
+			failed++
+			continue
+		}
+		if dryRun {
+			imported++
+			continue
+		}
+
+		err = importUser(userRepo, user)
+		switch {
+		case err == nil:
+			imported++
+		case err.Error() == constants.ErrUserExists:
+			logger.Warn("User already exists, skipping", zap.Int("line", line), zap.String("email", user.Email))
+			skipped++
+		default:
+			logger.Error("Error importing user", zap.Int("line", line), zap.String("email", user.Email), zap.Error(err))
+			failed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Fatal("❌ Error reading input file", zap.Error(err))
+	}
+
+	fmt.Printf("imported: %d\n", imported)
+	fmt.Printf("skipped:  %d\n", skipped)
+	fmt.Printf("failed:   %d\n", failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseUser(data []byte, passwordHasher *utils.PasswordHasher) (*importedUser, error) {
+	var user importedUser
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	user.Email = utils.NormalizeEmail(user.Email)
+	if _, err := mail.ParseAddress(user.Email); err != nil {
+		return nil, fmt.Errorf("invalid email %q", user.Email)
+	}
+	if !passwordHasher.Supports(user.PasswordHash) {
+		return nil, errors.New("unsupported password hash format")
+	}
+	if user.Name == "" {
+		user.Name, _, _ = strings.Cut(user.Email, "@")
+	}
+
+	return &user, nil
+}
+
+func importUser(userRepo repository.UserRepository, user *importedUser) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	created, err := userRepo.CreateUser(ctx, user.Email, user.PasswordHash, user.Name)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return userRepo.MarkEmailVerified(ctx, created.ID)
+	}
+	return nil
+}
@@ -2,18 +2,25 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/manish-npx/go-echo-pg/internal/config"
 	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/federation"
 	"github.com/manish-npx/go-echo-pg/internal/handler"
+	"github.com/manish-npx/go-echo-pg/internal/mailer"
 	"github.com/manish-npx/go-echo-pg/internal/repository"
 	"github.com/manish-npx/go-echo-pg/internal/routes"
 	"github.com/manish-npx/go-echo-pg/internal/service"
@@ -65,10 +72,17 @@ func main() {
 }
 
 type App struct {
-	cfg    *config.Config
-	db     *database.DB
-	logger *zap.Logger
-	echo   *echo.Echo
+	cfg         *config.Config
+	db          *database.DB
+	logger      *zap.Logger
+	echo        *echo.Echo
+	cleanupJobs []cleanupJob
+}
+
+// cleanupJob deletes rows that are no longer needed and returns how many it removed
+type cleanupJob struct {
+	name string
+	run  func(ctx context.Context) (int, error)
 }
 
 func NewApp(cfg *config.Config, db *database.DB, logger *zap.Logger) (*App, error) {
@@ -84,13 +98,116 @@ func NewApp(cfg *config.Config, db *database.DB, logger *zap.Logger) (*App, erro
 	// Validator
 	e.Validator = utils.NewValidator()
 
+	// Client IPs: X-Forwarded-For is only believed from loopback, private ranges
+	// and the configured proxies, so it cannot be spoofed to dodge the rate limits
+	ipExtractor, err := newIPExtractor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e.IPExtractor = ipExtractor
+
+	// Signing keys
+	keys, err := utils.NewKeyRing(&cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("error loading jwt keys: %w", err)
+	}
+	if keys.ActiveAlgorithm() == "HS256" {
+		logger.Warn("⚠️ Tokens are signed with HS256, OIDC clients cannot verify ID tokens without the shared secret")
+	}
+
+	// Encryption of secrets at rest
+	encryptionKey, err := loadEncryptionKey(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	encryptor, err := utils.NewEncryptor(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("error creating encryptor: %w", err)
+	}
+
+	// Password hashing
+	passwordHasher, err := utils.NewPasswordHasher(&cfg.PasswordHashing)
+	if err != nil {
+		return nil, fmt.Errorf("error creating password hasher: %w", err)
+	}
+
+	// Breached password list (optional)
+	var breachedPasswords *utils.BreachedPasswords
+	if path := cfg.PasswordPolicy.BreachedPasswordsFile; path != "" {
+		if breachedPasswords, err = utils.OpenBreachedPasswords(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Outgoing mail
+	mail, err := mailer.New(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error creating mailer: %w", err)
+	}
+
+	// Upstream identity providers
+	providers, err := federation.New(&cfg.Federation, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring identity providers: %w", err)
+	}
+
 	// Initialize layers
 	userRepo := repository.NewUserRepository(db, logger)
-	authService := service.NewAuthService(userRepo, cfg, logger)
-	authHandler := handler.NewAuthHandler(authService, logger)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db, logger)
+	revocationRepo := repository.NewRevocationRepository(db, logger)
+	userTokenRepo := repository.NewUserTokenRepository(db, logger)
+	mfaRepo := repository.NewMFARepository(db, logger)
+	webAuthnRepo := repository.NewWebAuthnRepository(db, logger)
+	oauthRepo := repository.NewOAuthRepository(db, logger)
+	identityRepo := repository.NewIdentityRepository(db, logger)
+	apiKeyRepo := repository.NewAPIKeyRepository(db, logger)
+	roleRepo := repository.NewRoleRepository(db, logger)
+	lockoutRepo := repository.NewLockoutRepository(db, logger)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db, logger)
+	sessionRepo := repository.NewSessionRepository(db, logger)
+	magicLinkRepo := repository.NewMagicLinkRepository(db, logger)
+	orgRepo := repository.NewOrganizationRepository(db, logger)
+	tokenService := service.NewTokenService(userRepo, refreshTokenRepo, roleRepo, sessionRepo, keys, cfg, logger)
+	revocationService := service.NewTokenRevocationService(revocationRepo, refreshTokenRepo, sessionRepo, oauthRepo, cfg, logger)
+	emailService := service.NewEmailService(mail, cfg, logger)
+	lockoutService := service.NewLockoutService(lockoutRepo, cfg, logger)
+	mfaService := service.NewMFAService(userRepo, mfaRepo, userTokenRepo, tokenService, lockoutService, encryptor, keys, cfg, logger)
+	passwordPolicyService := service.NewPasswordPolicyService(passwordHistoryRepo, passwordHasher, breachedPasswords, cfg, logger)
+	authService := service.NewAuthService(userRepo, userTokenRepo, magicLinkRepo, tokenService, revocationService, emailService, mfaService, lockoutService, passwordHasher, passwordPolicyService, keys, cfg, logger)
+	webAuthnService, err := service.NewWebAuthnService(userRepo, webAuthnRepo, tokenService, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	oauthService := service.NewOAuthService(oauthRepo, refreshTokenRepo, sessionRepo, apiKeyRepo, authService, tokenService, revocationService, keys, cfg, logger)
+	federationService := service.NewFederationService(providers, userRepo, identityRepo, tokenService, mfaService, passwordHasher, encryptor, cfg, logger)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, cfg, logger)
+	orgService := service.NewOrganizationService(orgRepo, userRepo, sessionRepo, tokenService, emailService, cfg, logger)
+	impersonationService := service.NewImpersonationService(userRepo, keys, cfg, logger)
+	rbacService := service.NewRBACService(userRepo, roleRepo, revocationService, passwordHasher, cfg, logger)
+	loginGuardService := service.NewLoginGuardService(encryptor, cfg, logger)
+	sessionService := service.NewSessionService(sessionRepo, revocationService, cfg, logger)
+	accountService := service.NewAccountService(userRepo, roleRepo, mfaRepo, webAuthnRepo, apiKeyRepo, sessionRepo, refreshTokenRepo, revocationRepo, userTokenRepo, identityRepo, oauthRepo, orgRepo, lockoutRepo, passwordHistoryRepo, magicLinkRepo, revocationService, cfg, logger)
+	authHandler := handler.NewAuthHandler(authService, cfg, logger)
+	mfaHandler := handler.NewMFAHandler(mfaService, cfg, logger)
+	webAuthnHandler := handler.NewWebAuthnHandler(webAuthnService, cfg, logger)
+	oauthHandler := handler.NewOAuthHandler(oauthService, cfg, logger)
+	federationHandler := handler.NewFederationHandler(federationService, cfg, logger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, logger)
+	sessionHandler := handler.NewSessionHandler(sessionService, logger)
+	orgHandler := handler.NewOrganizationHandler(orgService, logger)
+	accountHandler := handler.NewAccountHandler(accountService, cfg, logger)
+	adminHandler := handler.NewAdminHandler(rbacService, revocationService, lockoutService, impersonationService, logger)
+	wellKnownHandler := handler.NewWellKnownHandler(keys, logger)
+
+	// Seed the bootstrap admin
+	seedCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := rbacService.SeedBootstrapAdmin(seedCtx); err != nil {
+		return nil, fmt.Errorf("error seeding bootstrap admin: %w", err)
+	}
 
 	// Register routes
-	routes := routes.NewRoutes(cfg, authHandler, logger)
+	routes := routes.NewRoutes(cfg, keys, authHandler, mfaHandler, webAuthnHandler, oauthHandler, federationHandler, apiKeyHandler, sessionHandler, orgHandler, accountHandler, adminHandler, wellKnownHandler, revocationService, apiKeyService, rbacService, loginGuardService, orgService, sessionService, logger)
 	routes.RegisterRoutes(e)
 
 	return &App{
@@ -98,6 +215,11 @@ func NewApp(cfg *config.Config, db *database.DB, logger *zap.Logger) (*App, erro
 		db:     db,
 		logger: logger,
 		echo:   e,
+		cleanupJobs: []cleanupJob{
+			{name: "deleted accounts", run: accountService.PurgeDeletedAccounts},
+			{name: "expired token revocations", run: revocationService.PurgeExpired},
+			{name: "magic link requests", run: authService.PruneMagicLinkRequests},
+		},
 	}, nil
 }
 
@@ -110,6 +232,11 @@ func (a *App) Start() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Purge deleted accounts and expired rows in the background
+	cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+	defer stopCleanup()
+	go a.runCleanup(cleanupCtx)
+
 	// Start server in goroutine
 	go func() {
 		a.logger.Info("🚀 Starting server",
@@ -145,6 +272,37 @@ func (a *App) waitForShutdown(server *http.Server) error {
 	return nil
 }
 
+// runCleanup runs the cleanup jobs on auth.cleanup_interval until ctx is
+// cancelled. A failing job is logged and retried on the next tick.
+func (a *App) runCleanup(ctx context.Context) {
+	interval := a.cfg.Auth.CleanupInterval
+	if interval <= 0 {
+		a.logger.Warn("⚠️ Background cleanup is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, job := range a.cleanupJobs {
+				removed, err := job.run(ctx)
+				if err != nil {
+					a.logger.Error("❌ Cleanup failed", zap.String("job", job.name), zap.Error(err))
+					continue
+				}
+				if removed > 0 {
+					a.logger.Info("🧹 Cleaned up", zap.String("job", job.name), zap.Int("count", removed))
+				}
+			}
+		}
+	}
+}
+
 func createLogger(cfg *config.Config) (*zap.Logger, error) {
 	var logger *zap.Logger
 	var err error
@@ -180,6 +338,41 @@ func createLogger(cfg *config.Config) (*zap.Logger, error) {
 	return logger, err
 }
 
+// loadEncryptionKey decodes security.encryption_key. Outside production a key is
+// derived from the JWT secret when none is configured, so local setups keep working.
+func loadEncryptionKey(cfg *config.Config, logger *zap.Logger) ([]byte, error) {
+	if cfg.Security.EncryptionKey == "" {
+		logger.Warn("⚠️ No encryption key configured, deriving one from the JWT secret")
+		key := sha256.Sum256([]byte(cfg.JWT.Secret))
+		return key[:], nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.Security.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encryption key: %w", err)
+	}
+	return key, nil
+}
+
+// newIPExtractor trusts X-Forwarded-For from loopback, link-local and private
+// addresses (echo's defaults) plus every CIDR in http_server.trusted_proxies
+func newIPExtractor(cfg *config.Config) (echo.IPExtractor, error) {
+	var options []echo.TrustOption
+	for _, cidr := range strings.Split(cfg.Server.TrustedProxies, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
 func customHTTPErrorHandler(err error, c echo.Context) {
 	code := http.StatusInternalServerError
 	message := "Internal Server Error"
@@ -9,16 +9,29 @@ import (
 )
 
 type Config struct {
-	Env     string        `mapstructure:"env"`
-	Server  ServerConfig  `mapstructure:"http_server"`
-	DB      DBConfig      `mapstructure:"db"`
-	JWT     JWTConfig     `mapstructure:"jwt"`
-	CORS    CORSConfig    `mapstructure:"cors"`
-	Logging LoggingConfig `mapstructure:"logging"`
+	Env             string                `mapstructure:"env"`
+	Server          ServerConfig          `mapstructure:"http_server"`
+	DB              DBConfig              `mapstructure:"db"`
+	JWT             JWTConfig             `mapstructure:"jwt"`
+	CORS            CORSConfig            `mapstructure:"cors"`
+	Logging         LoggingConfig         `mapstructure:"logging"`
+	Auth            AuthConfig            `mapstructure:"auth"`
+	Mail            MailConfig            `mapstructure:"mail"`
+	Security        SecurityConfig        `mapstructure:"security"`
+	WebAuthn        WebAuthnConfig        `mapstructure:"webauthn"`
+	OIDC            OIDCConfig            `mapstructure:"oidc"`
+	Federation      FederationConfig      `mapstructure:"federation"`
+	LoginGuard      LoginGuardConfig      `mapstructure:"login_guard"`
+	SessionCookie   SessionCookieConfig   `mapstructure:"session_cookie"`
+	PasswordHashing PasswordHashingConfig `mapstructure:"password_hashing"`
+	PasswordPolicy  PasswordPolicyConfig  `mapstructure:"password_policy"`
 }
 
 type ServerConfig struct {
 	Address string `mapstructure:"address"`
+	// TrustedProxies is a comma-separated list of CIDRs whose X-Forwarded-For
+	// header is believed, in addition to loopback and private ranges
+	TrustedProxies string `mapstructure:"trusted_proxies"`
 }
 
 type DBConfig struct {
@@ -35,14 +48,191 @@ type DBConfig struct {
 }
 
 type JWTConfig struct {
-	Secret    string `mapstructure:"secret"`
-	ExpiresIn int    `mapstructure:"expires_in"`
+	Secret             string         `mapstructure:"secret"`
+	ExpiresIn          int            `mapstructure:"expires_in"`
+	RefreshExpiresIn   int            `mapstructure:"refresh_expires_in"`
+	RevocationCacheTTL time.Duration  `mapstructure:"revocation_cache_ttl"`
+	ActiveKeyID        string         `mapstructure:"active_kid"`
+	Keys               []JWTKeyConfig `mapstructure:"keys"`
+}
+
+// JWTKeyConfig describes one entry of the signing key ring. HS256 keys use Secret;
+// RS256, ES256 and EdDSA keys use PEM material inline or from a file. Keys with
+// only public material can verify but never sign.
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKey     string `mapstructure:"private_key"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKey      string `mapstructure:"public_key"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
 }
 
 type CORSConfig struct {
 	AllowedOrigins string `mapstructure:"allowed_origins"`
 }
 
+type AuthConfig struct {
+	AppBaseURL               string        `mapstructure:"app_base_url"`
+	RequireEmailVerification bool          `mapstructure:"require_email_verification"`
+	EmailVerificationTTL     time.Duration `mapstructure:"email_verification_ttl"`
+	PasswordResetTTL         time.Duration `mapstructure:"password_reset_ttl"`
+	PasswordResetCooldown    time.Duration `mapstructure:"password_reset_cooldown"`
+	MFAIssuer                string        `mapstructure:"mfa_issuer"`
+	MFAChallengeTTL          time.Duration `mapstructure:"mfa_challenge_ttl"`
+	MFAMaxAttempts           int           `mapstructure:"mfa_max_attempts"`
+	// EmailChangeTTL is how long the confirmation link sent to a new address is
+	// valid; EmailChangeUndoTTL how long the old address can undo the change
+	EmailChangeTTL     time.Duration `mapstructure:"email_change_ttl"`
+	EmailChangeUndoTTL time.Duration `mapstructure:"email_change_undo_ttl"`
+	// BootstrapAdminEmail is granted the admin role at startup while no admin
+	// exists. The account is created with BootstrapAdminPassword when it does
+	// not exist yet.
+	BootstrapAdminEmail    string `mapstructure:"bootstrap_admin_email"`
+	BootstrapAdminPassword string `mapstructure:"bootstrap_admin_password"`
+	// After ThrottleAfter consecutive failures each further attempt must wait
+	// ThrottleBaseDelay, doubling up to ThrottleMaxDelay. LockoutThreshold
+	// failures lock the account for LockoutDuration.
+	ThrottleAfter     int           `mapstructure:"throttle_after"`
+	ThrottleBaseDelay time.Duration `mapstructure:"throttle_base_delay"`
+	ThrottleMaxDelay  time.Duration `mapstructure:"throttle_max_delay"`
+	LockoutThreshold  int           `mapstructure:"lockout_threshold"`
+	LockoutDuration   time.Duration `mapstructure:"lockout_duration"`
+	// Magic links are valid for MagicLinkTTL. Each email may request at most
+	// MagicLinkRateLimit links per MagicLinkRateWindow.
+	MagicLinkTTL        time.Duration `mapstructure:"magic_link_ttl"`
+	MagicLinkRateLimit  int           `mapstructure:"magic_link_rate_limit"`
+	MagicLinkRateWindow time.Duration `mapstructure:"magic_link_rate_window"`
+	// ImpersonationTTL is the lifetime of tokens admins obtain to act as a user
+	ImpersonationTTL time.Duration `mapstructure:"impersonation_ttl"`
+	// InvitationTTL is how long an organization invitation can be accepted
+	InvitationTTL time.Duration `mapstructure:"invitation_ttl"`
+	// AccountDeletionGracePeriod is how long a deleted account can still be
+	// restored by logging in before it is purged
+	AccountDeletionGracePeriod time.Duration `mapstructure:"account_deletion_grace_period"`
+	// CleanupInterval is how often due accounts are purged and expired rows,
+	// such as revocations of expired tokens, are deleted
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+}
+
+type SecurityConfig struct {
+	// EncryptionKey is a base64-encoded 32-byte key for secrets encrypted at rest
+	EncryptionKey string `mapstructure:"encryption_key"`
+}
+
+// LoginGuardConfig tunes credential stuffing detection on the login endpoint.
+// Failed logins are counted per source IP and per subnet across distinct
+// accounts within Window. Crossing a challenge threshold requires a solved
+// proof-of-work challenge, crossing a block threshold rejects the source for
+// BlockDuration.
+type LoginGuardConfig struct {
+	Enabled                  bool          `mapstructure:"enabled"`
+	Window                   time.Duration `mapstructure:"window"`
+	IPChallengeThreshold     int           `mapstructure:"ip_challenge_threshold"`
+	IPBlockThreshold         int           `mapstructure:"ip_block_threshold"`
+	SubnetChallengeThreshold int           `mapstructure:"subnet_challenge_threshold"`
+	SubnetBlockThreshold     int           `mapstructure:"subnet_block_threshold"`
+	BlockDuration            time.Duration `mapstructure:"block_duration"`
+	ChallengeDifficulty      int           `mapstructure:"challenge_difficulty"`
+	ChallengeTTL             time.Duration `mapstructure:"challenge_ttl"`
+}
+
+// SessionCookieConfig describes the cookies of the browser session mode. Name
+// holds the HttpOnly session secret, CSRFName the token that unsafe requests must
+// echo in the X-CSRF-Token header. Sessions end TTL after login. SameSite is one
+// of lax, strict or none.
+type SessionCookieConfig struct {
+	Name     string        `mapstructure:"name"`
+	CSRFName string        `mapstructure:"csrf_name"`
+	Domain   string        `mapstructure:"domain"`
+	Path     string        `mapstructure:"path"`
+	TTL      time.Duration `mapstructure:"ttl"`
+	Secure   bool          `mapstructure:"secure"`
+	SameSite string        `mapstructure:"same_site"`
+}
+
+// PasswordHashingConfig selects the algorithm new password hashes are created
+// with. Stored hashes with another algorithm or weaker parameters are upgraded
+// on the next successful login. Argon2Memory is in KiB.
+type PasswordHashingConfig struct {
+	Algorithm         string `mapstructure:"algorithm"`
+	BcryptCost        int    `mapstructure:"bcrypt_cost"`
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"`
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+	Argon2SaltLength  uint32 `mapstructure:"argon2_salt_length"`
+	Argon2KeyLength   uint32 `mapstructure:"argon2_key_length"`
+}
+
+// PasswordPolicyConfig is enforced whenever a password is set. HistorySize is
+// how many previous passwords cannot be reused, MaxAge how long a password stays
+// valid; zero disables either. BreachedPasswordsFile points to a sorted SHA-1
+// list (see utils.BreachedPasswords) and is optional.
+type PasswordPolicyConfig struct {
+	MinLength             int           `mapstructure:"min_length"`
+	MaxLength             int           `mapstructure:"max_length"`
+	RequireUppercase      bool          `mapstructure:"require_uppercase"`
+	RequireLowercase      bool          `mapstructure:"require_lowercase"`
+	RequireDigit          bool          `mapstructure:"require_digit"`
+	RequireSymbol         bool          `mapstructure:"require_symbol"`
+	DisallowUserInfo      bool          `mapstructure:"disallow_user_info"`
+	HistorySize           int           `mapstructure:"history_size"`
+	MaxAge                time.Duration `mapstructure:"max_age"`
+	BreachedPasswordsFile string        `mapstructure:"breached_passwords_file"`
+}
+
+type WebAuthnConfig struct {
+	RPID          string        `mapstructure:"rp_id"`
+	RPDisplayName string        `mapstructure:"rp_display_name"`
+	RPOrigins     string        `mapstructure:"rp_origins"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+}
+
+// OIDCConfig configures the built-in OpenID Connect provider. LoginURL is the
+// page of the first-party app that logs the user in and asks for consent.
+type OIDCConfig struct {
+	Issuer      string        `mapstructure:"issuer"`
+	LoginURL    string        `mapstructure:"login_url"`
+	AuthCodeTTL time.Duration `mapstructure:"auth_code_ttl"`
+	IDTokenTTL  time.Duration `mapstructure:"id_token_ttl"`
+}
+
+// FederationConfig lists upstream OpenID Connect providers users may sign in with
+type FederationConfig struct {
+	StateTTL  time.Duration             `mapstructure:"state_ttl"`
+	Providers []FederatedProviderConfig `mapstructure:"providers"`
+}
+
+// FederatedProviderConfig describes one upstream identity provider. RedirectURL
+// must point at /auth/federated/<name>/callback and be registered with the provider.
+type FederatedProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	DisplayName  string   `mapstructure:"display_name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+type MailConfig struct {
+	Driver  string     `mapstructure:"driver"`
+	From    string     `mapstructure:"from"`
+	FileDir string     `mapstructure:"file_dir"`
+	SMTP    SMTPConfig `mapstructure:"smtp"`
+	// LogBody makes the log driver log message bodies. They contain live
+	// tokens, so only enable it on a development machine.
+	LogBody bool `mapstructure:"log_body"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
 type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
@@ -101,9 +291,78 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("db.max_conn_lifetime", time.Hour)
 	v.SetDefault("db.max_conn_idle_time", 30*time.Minute)
 	v.SetDefault("jwt.expires_in", 3600)
+	v.SetDefault("jwt.refresh_expires_in", 2592000)
+	v.SetDefault("jwt.revocation_cache_ttl", 30*time.Second)
 	v.SetDefault("cors.allowed_origins", "http://localhost:3000")
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "json")
+	v.SetDefault("auth.app_base_url", "http://localhost:3000")
+	v.SetDefault("auth.require_email_verification", false)
+	v.SetDefault("auth.email_verification_ttl", 24*time.Hour)
+	v.SetDefault("auth.password_reset_ttl", time.Hour)
+	v.SetDefault("auth.password_reset_cooldown", 5*time.Minute)
+	v.SetDefault("auth.email_change_ttl", 24*time.Hour)
+	v.SetDefault("auth.email_change_undo_ttl", 7*24*time.Hour)
+	v.SetDefault("auth.mfa_issuer", "go-echo-pg")
+	v.SetDefault("auth.mfa_challenge_ttl", 5*time.Minute)
+	v.SetDefault("auth.mfa_max_attempts", 5)
+	v.SetDefault("auth.throttle_after", 3)
+	v.SetDefault("auth.throttle_base_delay", time.Second)
+	v.SetDefault("auth.throttle_max_delay", 5*time.Minute)
+	v.SetDefault("auth.lockout_threshold", 10)
+	v.SetDefault("auth.lockout_duration", 15*time.Minute)
+	v.SetDefault("auth.magic_link_ttl", 15*time.Minute)
+	v.SetDefault("auth.magic_link_rate_limit", 3)
+	v.SetDefault("auth.magic_link_rate_window", time.Hour)
+	v.SetDefault("auth.impersonation_ttl", 15*time.Minute)
+	v.SetDefault("auth.invitation_ttl", 7*24*time.Hour)
+	v.SetDefault("auth.account_deletion_grace_period", 30*24*time.Hour)
+	v.SetDefault("auth.cleanup_interval", time.Hour)
+	v.SetDefault("webauthn.rp_id", "localhost")
+	v.SetDefault("webauthn.rp_display_name", "go-echo-pg")
+	v.SetDefault("webauthn.rp_origins", "http://localhost:3000")
+	v.SetDefault("webauthn.timeout", 5*time.Minute)
+	v.SetDefault("oidc.issuer", "http://localhost:8080")
+	v.SetDefault("oidc.login_url", "http://localhost:3000/oauth/consent")
+	v.SetDefault("oidc.auth_code_ttl", time.Minute)
+	v.SetDefault("oidc.id_token_ttl", time.Hour)
+	v.SetDefault("federation.state_ttl", 10*time.Minute)
+	v.SetDefault("login_guard.enabled", true)
+	v.SetDefault("login_guard.window", 15*time.Minute)
+	v.SetDefault("login_guard.ip_challenge_threshold", 5)
+	v.SetDefault("login_guard.ip_block_threshold", 20)
+	v.SetDefault("login_guard.subnet_challenge_threshold", 15)
+	v.SetDefault("login_guard.subnet_block_threshold", 60)
+	v.SetDefault("login_guard.block_duration", time.Hour)
+	v.SetDefault("login_guard.challenge_difficulty", 18)
+	v.SetDefault("login_guard.challenge_ttl", 2*time.Minute)
+	v.SetDefault("session_cookie.name", "session")
+	v.SetDefault("session_cookie.csrf_name", "csrf_token")
+	v.SetDefault("session_cookie.path", "/")
+	v.SetDefault("session_cookie.ttl", 7*24*time.Hour)
+	v.SetDefault("session_cookie.secure", true)
+	v.SetDefault("session_cookie.same_site", "lax")
+	v.SetDefault("password_hashing.algorithm", "argon2id")
+	v.SetDefault("password_hashing.bcrypt_cost", 12)
+	v.SetDefault("password_hashing.argon2_memory", 64*1024)
+	v.SetDefault("password_hashing.argon2_iterations", 3)
+	v.SetDefault("password_hashing.argon2_parallelism", 2)
+	v.SetDefault("password_hashing.argon2_salt_length", 16)
+	v.SetDefault("password_hashing.argon2_key_length", 32)
+	v.SetDefault("password_policy.min_length", 10)
+	v.SetDefault("password_policy.max_length", 128)
+	v.SetDefault("password_policy.require_uppercase", true)
+	v.SetDefault("password_policy.require_lowercase", true)
+	v.SetDefault("password_policy.require_digit", true)
+	v.SetDefault("password_policy.require_symbol", false)
+	v.SetDefault("password_policy.disallow_user_info", true)
+	v.SetDefault("password_policy.history_size", 5)
+	v.SetDefault("password_policy.max_age", 0)
+	v.SetDefault("mail.driver", "log")
+	v.SetDefault("mail.from", "no-reply@localhost")
+	v.SetDefault("mail.file_dir", "./tmp/mail")
+	v.SetDefault("mail.log_body", false)
+	v.SetDefault("mail.smtp.port", 587)
 }
 
 func bindEnvVars(v *viper.Viper) {
@@ -122,16 +381,38 @@ func bindEnvVars(v *viper.Viper) {
 	v.BindEnv("jwt.secret", "APP_JWT_SECRET")
 	v.BindEnv("cors.allowed_origins", "APP_CORS_ALLOWED_ORIGINS")
 	v.BindEnv("logging.level", "APP_LOG_LEVEL")
+	v.BindEnv("security.encryption_key", "APP_ENCRYPTION_KEY")
+	v.BindEnv("auth.bootstrap_admin_email", "APP_BOOTSTRAP_ADMIN_EMAIL")
+	v.BindEnv("auth.bootstrap_admin_password", "APP_BOOTSTRAP_ADMIN_PASSWORD")
+	v.BindEnv("mail.smtp.host", "APP_SMTP_HOST")
+	v.BindEnv("mail.smtp.username", "APP_SMTP_USERNAME")
+	v.BindEnv("mail.smtp.password", "APP_SMTP_PASSWORD")
 }
 
 func validateConfig(config *Config) error {
+	switch strings.ToLower(config.SessionCookie.SameSite) {
+	case "lax", "strict":
+	case "none":
+		if !config.SessionCookie.Secure {
+			return fmt.Errorf("session cookies with SameSite=None must be secure")
+		}
+	default:
+		return fmt.Errorf("unsupported session cookie SameSite mode %q", config.SessionCookie.SameSite)
+	}
+
 	if config.Env == "production" {
 		if config.JWT.Secret == "your-super-secret-key-change-in-production-2025" {
 			return fmt.Errorf("JWT secret must be changed in production")
 		}
+		if config.Security.EncryptionKey == "" {
+			return fmt.Errorf("encryption key is required in production")
+		}
 		if config.DB.Password == "" {
 			return fmt.Errorf("database password is required in production")
 		}
+		if !config.SessionCookie.Secure {
+			return fmt.Errorf("session cookies must be secure in production")
+		}
 		if !strings.EqualFold(config.DB.SSLMode, "require") && !strings.EqualFold(config.DB.SSLMode, "verify-full") {
 			return fmt.Errorf("SSL mode must be 'require' or 'verify-full' in production")
 		}
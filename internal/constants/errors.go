@@ -6,4 +6,54 @@ const (
 	ErrInvalidCredentials = "invalid email or password"
 	ErrInvalidToken       = "invalid token"
 	ErrTokenExpired       = "token has expired"
+
+	ErrInvalidRefreshToken = "invalid refresh token"
+	ErrRefreshTokenReused  = "refresh token has already been used"
+
+	ErrEmailNotVerified   = "email address has not been verified"
+	ErrInvalidActionToken = "invalid or expired link"
+	ErrEmailUnchanged     = "new email is the same as the current one"
+
+	ErrMFARequired       = "multi-factor authentication required"
+	ErrMFANotEnrolled    = "multi-factor authentication is not enrolled"
+	ErrMFAAlreadyEnabled = "multi-factor authentication is already enabled"
+	ErrInvalidMFACode    = "invalid authentication code"
+
+	ErrWebAuthnSessionNotFound    = "webauthn session not found or expired"
+	ErrWebAuthnCredentialNotFound = "webauthn credential not found"
+	ErrWebAuthnVerification       = "webauthn verification failed"
+
+	ErrOAuthClientNotFound      = "oauth client not found"
+	ErrOAuthClientExists        = "oauth client already exists"
+	ErrInvalidAuthorizationCode = "invalid or expired authorization code"
+
+	ErrIdentityProviderNotFound = "identity provider not found"
+	ErrIdentityNotFound         = "identity not found"
+	ErrIdentityExists           = "identity is already linked to a user"
+	ErrInvalidFederationState   = "invalid or expired login state"
+	ErrFederatedLoginFailed     = "federated login failed"
+
+	ErrAPIKeyNotFound = "api key not found"
+	ErrInvalidAPIKey  = "invalid or expired api key"
+
+	ErrRoleNotFound = "role not found"
+
+	ErrAccountLocked  = "account is temporarily locked"
+	ErrLoginThrottled = "too many failed login attempts"
+
+	ErrInvalidLoginChallenge = "invalid or expired login challenge"
+
+	ErrPasswordPolicy  = "password does not meet the password policy"
+	ErrPasswordExpired = "password has expired"
+
+	ErrSessionNotFound = "session not found"
+
+	ErrMagicLinkRateLimited = "too many login link requests"
+
+	ErrOrganizationNotFound = "organization not found"
+	ErrMembershipNotFound   = "user is not a member of this organization"
+	ErrAlreadyMember        = "user is already a member of this organization"
+	ErrInvitationNotFound   = "invalid or expired invitation"
+	ErrLastOrgOwner         = "organization must keep at least one owner"
+	ErrInsufficientOrgRole  = "insufficient organization role"
 )
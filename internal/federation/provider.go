@@ -0,0 +1,326 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	httpTimeout = 10 * time.Second
+	// maxResponseBytes caps what we read from an upstream provider
+	maxResponseBytes = 1 << 20
+	// jwksMinRefresh limits refetching the key set when an unknown kid shows up
+	jwksMinRefresh = time.Minute
+)
+
+// IDTokenClaims are the upstream ID token claims we rely on
+type IDTokenClaims struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	jwt.RegisteredClaims
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Provider talks to one upstream OpenID Connect provider. The discovery document
+// and signing keys are fetched lazily and cached.
+type Provider struct {
+	cfg    config.FederatedProviderConfig
+	client *http.Client
+	logger *zap.Logger
+
+	mu          sync.Mutex
+	discovery   *discoveryDocument
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+// New builds a provider for every configured entry, keyed by name
+func New(cfg *config.FederationConfig, logger *zap.Logger) (map[string]*Provider, error) {
+	providers := make(map[string]*Provider, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		if p.Name == "" || p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			return nil, fmt.Errorf("federated provider %q needs name, issuer, client_id and redirect_url", p.Name)
+		}
+		if _, exists := providers[p.Name]; exists {
+			return nil, fmt.Errorf("duplicate federated provider %q", p.Name)
+		}
+		providers[p.Name] = NewProvider(p, logger)
+	}
+	return providers, nil
+}
+
+func NewProvider(cfg config.FederatedProviderConfig, logger *zap.Logger) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: httpTimeout},
+		logger: logger,
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) DisplayName() string {
+	if p.cfg.DisplayName != "" {
+		return p.cfg.DisplayName
+	}
+	return p.cfg.Name
+}
+
+// AuthCodeURL returns the upstream authorization URL for a PKCE S256 challenge
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {utils.PKCEMethodS256},
+	}
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified ID token claims
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IDTokenClaims, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	var tokens tokenResponse
+	status, err := p.doJSON(req, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging code with %s: %w", p.cfg.Name, err)
+	}
+	if status != http.StatusOK || tokens.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint of %s returned %d: %s %s", p.cfg.Name, status, tokens.Error, tokens.ErrorDescription)
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// VerifyIDToken checks signature, issuer, audience, expiry and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+
+	_, err := jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.getKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token from %s: %w", p.cfg.Name, err)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("invalid id token from %s: nonce mismatch", p.cfg.Name)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("invalid id token from %s: missing subject", p.cfg.Name)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc discoveryDocument
+	status, err := p.doJSON(req, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching discovery document of %s: %w", p.cfg.Name, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery document of %s returned %d", p.cfg.Name, status)
+	}
+
+	// The document must describe the issuer we were configured with (OIDC Discovery 4.3)
+	if strings.TrimSuffix(doc.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery document of %s is for issuer %q", p.cfg.Name, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s is incomplete", p.cfg.Name)
+	}
+
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// getKey returns the signing key for kid, refetching the key set at most once a
+// minute so a provider rotating its keys does not require a restart
+func (p *Provider) getKey(ctx context.Context, kid string) (any, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetched) < jwksMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := p.fetchKeys(ctx, doc.JWKSURI); err != nil {
+		return nil, err
+	}
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds kid in the cached set. Tokens without a kid are accepted only
+// when the provider publishes a single key.
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid == "" {
+		if len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key, true
+			}
+		}
+		return nil, false
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return err
+	}
+
+	var set utils.JWKSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return fmt.Errorf("error fetching keys of %s: %w", p.cfg.Name, err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("key set of %s returned %d", p.cfg.Name, status)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			p.logger.Warn("Skipping unusable key from identity provider",
+				zap.String("provider", p.cfg.Name),
+				zap.String("kid", jwk.Kid),
+				zap.Error(err),
+			)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.keys = keys
+	p.keysFetched = time.Now()
+	return nil
+}
+
+func (p *Provider) doJSON(req *http.Request, out any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		return resp.StatusCode, errors.New("response is not valid JSON")
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type AccountHandler struct {
+	accountService service.AccountService
+	cfg            *config.Config
+	response       *utils.ResponseHelper
+	logger         *zap.Logger
+}
+
+func NewAccountHandler(accountService service.AccountService, cfg *config.Config, logger *zap.Logger) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		cfg:            cfg,
+		response:       utils.NewResponseHelper(logger),
+		logger:         logger,
+	}
+}
+
+// Delete schedules the account for purging and ends all of its sessions
+func (h *AccountHandler) Delete(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	deletion, err := h.accountService.DeleteAccount(c.Request().Context(), userID)
+	if err != nil {
+		if err.Error() == constants.ErrUserNotFound {
+			return h.response.NotFound(c, "User not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	utils.ClearSessionCookies(c, &h.cfg.SessionCookie)
+	return h.response.Success(c, deletion)
+}
+
+// Export sends the archive as a file download rather than in the usual envelope
+func (h *AccountHandler) Export(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	export, err := h.accountService.ExportAccount(c.Request().Context(), userID)
+	if err != nil {
+		if err.Error() == constants.ErrUserNotFound {
+			return h.response.NotFound(c, "User not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="account-export.json"`)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// AdminHandler serves /api/v1/admin. Every route is guarded by RequirePermission.
+type AdminHandler struct {
+	rbacService       service.RBACService
+	revocationService service.TokenRevocationService
+	lockoutService    service.LockoutService
+	impersonation     service.ImpersonationService
+	response          *utils.ResponseHelper
+	logger            *zap.Logger
+}
+
+func NewAdminHandler(rbacService service.RBACService, revocationService service.TokenRevocationService, lockoutService service.LockoutService, impersonation service.ImpersonationService, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		rbacService:       rbacService,
+		revocationService: revocationService,
+		lockoutService:    lockoutService,
+		impersonation:     impersonation,
+		response:          utils.NewResponseHelper(logger),
+		logger:            logger,
+	}
+}
+
+func (h *AdminHandler) ListRoles(c echo.Context) error {
+	roles, err := h.rbacService.ListRoles(c.Request().Context())
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, roles)
+}
+
+func (h *AdminHandler) GetUserRoles(c echo.Context) error {
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	roles, err := h.rbacService.GetUserRoles(c.Request().Context(), userID)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, &model.UserRolesResponse{UserID: userID, Roles: roles})
+}
+
+func (h *AdminHandler) AssignRole(c echo.Context) error {
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	var req model.AssignRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.rbacService.AssignRole(c.Request().Context(), userID, req.Role); err != nil {
+		switch err.Error() {
+		case constants.ErrUserNotFound:
+			return h.response.NotFound(c, "User not found", err)
+		case constants.ErrRoleNotFound:
+			return h.response.NotFound(c, "Role not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	h.logger.Info("Admin assigned role",
+		zap.String("admin_id", adminID(c)),
+		zap.String("user_id", userID.String()),
+		zap.String("role", req.Role),
+	)
+
+	return h.response.Success(c, map[string]string{"message": "Role assigned"})
+}
+
+func (h *AdminHandler) RemoveRole(c echo.Context) error {
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	role := c.Param("role")
+	if err := h.rbacService.RemoveRole(c.Request().Context(), userID, role); err != nil {
+		if err.Error() == constants.ErrRoleNotFound {
+			return h.response.NotFound(c, "User does not have this role", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	h.logger.Info("Admin removed role",
+		zap.String("admin_id", adminID(c)),
+		zap.String("user_id", userID.String()),
+		zap.String("role", role),
+	)
+
+	return h.response.Success(c, map[string]string{"message": "Role removed"})
+}
+
+// RevokeUserTokens logs the user out everywhere
+func (h *AdminHandler) RevokeUserTokens(c echo.Context) error {
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	if err := h.revocationService.RevokeAllUserTokens(c.Request().Context(), userID); err != nil {
+		if err.Error() == constants.ErrUserNotFound {
+			return h.response.NotFound(c, "User not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	h.logger.Info("Admin revoked user tokens",
+		zap.String("admin_id", adminID(c)),
+		zap.String("user_id", userID.String()),
+	)
+
+	return h.response.Success(c, map[string]string{"message": "All tokens revoked"})
+}
+
+// UnlockUser clears a login lockout and any pending throttle
+func (h *AdminHandler) UnlockUser(c echo.Context) error {
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	if err := h.lockoutService.Reset(c.Request().Context(), userID); err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	h.logger.Info("Admin unlocked user",
+		zap.String("admin_id", adminID(c)),
+		zap.String("user_id", userID.String()),
+	)
+
+	return h.response.Success(c, map[string]string{"message": "Account unlocked"})
+}
+
+// Impersonate issues a short-lived token for acting as the user
+func (h *AdminHandler) Impersonate(c echo.Context) error {
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	actorID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.ImpersonateRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	authResponse, err := h.impersonation.Impersonate(c.Request().Context(), actorID, userID, req.Reason)
+	if err != nil {
+		if err.Error() == constants.ErrUserNotFound {
+			return h.response.NotFound(c, "User not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, authResponse)
+}
+
+func parseUserIDParam(c echo.Context) (pgtype.UUID, error) {
+	var userID pgtype.UUID
+	err := userID.Scan(c.Param("userID"))
+	return userID, err
+}
+
+// adminID names the caller in audit logs; service principals are logged by client ID
+func adminID(c echo.Context) string {
+	if clientID, ok := c.Get("clientID").(string); ok {
+		return "client:" + clientID
+	}
+	id, _ := c.Get("userID").(pgtype.UUID)
+	return id.String()
+}
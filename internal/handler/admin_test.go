@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"go.uber.org/zap"
+)
+
+type fakeLockedAccounts struct {
+	repository.LockoutRepository
+	failures map[pgtype.UUID]*model.LoginFailures
+}
+
+func (f *fakeLockedAccounts) GetLoginFailures(ctx context.Context, userID pgtype.UUID) (*model.LoginFailures, error) {
+	if failures, ok := f.failures[userID]; ok {
+		return failures, nil
+	}
+	return &model.LoginFailures{UserID: userID}, nil
+}
+
+func (f *fakeLockedAccounts) ResetLoginFailures(ctx context.Context, userID pgtype.UUID) error {
+	delete(f.failures, userID)
+	return nil
+}
+
+func TestUnlockUser(t *testing.T) {
+	userID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	repo := &fakeLockedAccounts{failures: map[pgtype.UUID]*model.LoginFailures{
+		userID: {
+			UserID:         userID,
+			FailedAttempts: 10,
+			LastFailedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			LockedUntil:    pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		},
+	}}
+
+	cfg := &config.Config{}
+	cfg.Auth.ThrottleAfter = 3
+	cfg.Auth.ThrottleBaseDelay = time.Second
+	cfg.Auth.ThrottleMaxDelay = time.Minute
+	lockout := service.NewLockoutService(repo, cfg, zap.NewNop())
+
+	var locked *service.AccountLockedError
+	if err := lockout.Check(context.Background(), userID); !errors.As(err, &locked) {
+		t.Fatalf("Check before unlock = %v, want AccountLockedError", err)
+	}
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+	c.SetParamNames("userID")
+	c.SetParamValues(userID.String())
+
+	h := NewAdminHandler(nil, nil, lockout, nil, zap.NewNop())
+	if err := h.UnlockUser(c); err != nil {
+		t.Fatalf("UnlockUser: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if err := lockout.Check(context.Background(), userID); err != nil {
+		t.Errorf("Check after unlock = %v, want nil", err)
+	}
+}
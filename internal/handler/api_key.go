@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+	response      *utils.ResponseHelper
+	logger        *zap.Logger
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService, logger *zap.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		response:      utils.NewResponseHelper(logger),
+		logger:        logger,
+	}
+}
+
+func (h *APIKeyHandler) Create(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	// A key can never hand out more than it holds itself
+	if scopes, ok := c.Get("scopes").([]string); ok {
+		for _, scope := range req.Scopes {
+			if !slices.Contains(scopes, scope) {
+				return h.response.Forbidden(c, "Cannot grant a scope this API key does not have", nil)
+			}
+		}
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(c.Request().Context(), userID, &req)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Created(c, key)
+}
+
+func (h *APIKeyHandler) List(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request().Context(), userID)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, keys)
+}
+
+func (h *APIKeyHandler) Revoke(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var id pgtype.UUID
+	if err := id.Scan(c.Param("id")); err != nil {
+		return h.response.BadRequest(c, "Invalid API key ID", err)
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request().Context(), userID, id); err != nil {
+		if err.Error() == constants.ErrAPIKeyNotFound {
+			return h.response.NotFound(c, "API key not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "API key revoked"})
+}
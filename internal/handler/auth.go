@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
 	"github.com/manish-npx/go-echo-pg/internal/constants"
 	"github.com/manish-npx/go-echo-pg/internal/model"
 	"github.com/manish-npx/go-echo-pg/internal/service"
@@ -15,13 +18,15 @@ import (
 
 type AuthHandler struct {
 	authService service.AuthService
+	cfg         *config.Config
 	response    *utils.ResponseHelper
 	logger      *zap.Logger
 }
 
-func NewAuthHandler(authService service.AuthService, logger *zap.Logger) *AuthHandler {
+func NewAuthHandler(authService service.AuthService, cfg *config.Config, logger *zap.Logger) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		cfg:         cfg,
 		response:    utils.NewResponseHelper(logger),
 		logger:      logger,
 	}
@@ -45,12 +50,17 @@ func (h *AuthHandler) Register(c echo.Context) error {
 			zap.Error(err),
 		)
 
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return h.response.ValidationError(c, policyErr.Violations, err)
+		}
 		if err.Error() == constants.ErrUserExists {
 			return h.response.Conflict(c, "User with this email already exists", err)
 		}
 		return h.response.BadRequest(c, "Registration failed", err)
 	}
 
+	setSessionCookies(c, h.cfg, authResponse)
 	return h.response.Created(c, authResponse)
 }
 
@@ -64,15 +74,230 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		return h.response.ValidationError(c, err.Error(), err)
 	}
 
+	// Reported to the LoginGuard middleware
+	c.Set("loginEmail", req.Email)
+
 	authResponse, err := h.authService.Login(c.Request().Context(), &req)
 	if err != nil {
+		var mfaErr *service.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return h.response.Success(c, mfaErr.Challenge)
+		}
+
+		var lockedErr *service.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			utils.SetRetryAfter(c, time.Until(lockedErr.Until))
+			return h.response.Locked(c, "Account is temporarily locked after too many failed login attempts", err)
+		}
+
+		var throttledErr *service.LoginThrottledError
+		if errors.As(err, &throttledErr) {
+			utils.SetRetryAfter(c, throttledErr.RetryAfter)
+			return h.response.TooManyRequests(c, "Too many failed login attempts, try again later", err)
+		}
+
 		h.logger.Warn("Login failed",
 			zap.String("email", req.Email),
 			zap.Error(err),
 		)
+		if err.Error() == constants.ErrEmailNotVerified {
+			return h.response.Error(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Email address has not been verified", err)
+		}
+		if err.Error() == constants.ErrPasswordExpired {
+			return h.response.Error(c, http.StatusForbidden, "PASSWORD_EXPIRED", "Password has expired, a reset link has been sent to your email", err)
+		}
 		return h.response.Unauthorized(c, "Invalid email or password", err)
 	}
 
+	setSessionCookies(c, h.cfg, authResponse)
+	return h.response.Success(c, authResponse)
+}
+
+func (h *AuthHandler) RefreshToken(c echo.Context) error {
+	var req model.RefreshTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	authResponse, err := h.authService.RefreshToken(c.Request().Context(), &req)
+	if err != nil {
+		switch err.Error() {
+		case constants.ErrInvalidRefreshToken, constants.ErrRefreshTokenReused:
+			return h.response.Unauthorized(c, "Invalid or expired refresh token", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, authResponse)
+}
+
+func (h *AuthHandler) Logout(c echo.Context) error {
+	if session, ok := c.Get("session").(*model.Session); ok {
+		if err := h.authService.EndSession(c.Request().Context(), session); err != nil {
+			return h.response.InternalServerError(c, err)
+		}
+		utils.ClearSessionCookies(c, &h.cfg.SessionCookie)
+		return h.response.Success(c, map[string]string{"message": "Logged out successfully"})
+	}
+
+	claims, ok := c.Get("claims").(*utils.Claims)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid token", nil)
+	}
+
+	var req model.LogoutRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := h.authService.Logout(c.Request().Context(), claims, &req); err != nil {
+		if err.Error() == constants.ErrInvalidRefreshToken {
+			return h.response.BadRequest(c, "Invalid refresh token", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Logged out successfully"})
+}
+
+func (h *AuthHandler) VerifyEmail(c echo.Context) error {
+	var req model.VerifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.VerifyEmail(c.Request().Context(), &req); err != nil {
+		if err.Error() == constants.ErrInvalidActionToken {
+			return h.response.BadRequest(c, "Invalid or expired verification link", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Email verified successfully"})
+}
+
+func (h *AuthHandler) ResendVerification(c echo.Context) error {
+	var req model.ResendVerificationRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.ResendVerification(c.Request().Context(), &req); err != nil {
+		h.logger.Error("Resending verification email failed", zap.Error(err))
+	}
+
+	return h.response.Success(c, map[string]string{"message": "If the account exists and is unverified, a verification email has been sent"})
+}
+
+func (h *AuthHandler) ForgotPassword(c echo.Context) error {
+	var req model.ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.ForgotPassword(c.Request().Context(), &req); err != nil {
+		h.logger.Error("Password reset request failed", zap.Error(err))
+	}
+
+	return h.response.Success(c, map[string]string{"message": "If an account exists for this email, a password reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c echo.Context) error {
+	var req model.ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.ResetPassword(c.Request().Context(), &req); err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return h.response.ValidationError(c, policyErr.Violations, err)
+		}
+		if err.Error() == constants.ErrInvalidActionToken {
+			return h.response.BadRequest(c, "Invalid or expired reset link", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Password reset successfully"})
+}
+
+func (h *AuthHandler) RequestMagicLink(c echo.Context) error {
+	var req model.MagicLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.RequestMagicLink(c.Request().Context(), &req); err != nil {
+		if err.Error() == constants.ErrMagicLinkRateLimited {
+			return h.response.TooManyRequests(c, "Too many login link requests, try again later", err)
+		}
+		h.logger.Error("Magic link request failed", zap.Error(err))
+	}
+
+	return h.response.Success(c, map[string]string{"message": "If an account exists for this email, a login link has been sent"})
+}
+
+func (h *AuthHandler) MagicLinkLogin(c echo.Context) error {
+	var req model.MagicLinkLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	authResponse, err := h.authService.MagicLinkLogin(c.Request().Context(), &req)
+	if err != nil {
+		var mfaErr *service.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return h.response.Success(c, mfaErr.Challenge)
+		}
+
+		var lockedErr *service.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			utils.SetRetryAfter(c, time.Until(lockedErr.Until))
+			return h.response.Locked(c, "Account is temporarily locked after too many failed login attempts", err)
+		}
+
+		var throttledErr *service.LoginThrottledError
+		if errors.As(err, &throttledErr) {
+			utils.SetRetryAfter(c, throttledErr.RetryAfter)
+			return h.response.TooManyRequests(c, "Too many failed login attempts, try again later", err)
+		}
+
+		if err.Error() == constants.ErrInvalidActionToken {
+			return h.response.Unauthorized(c, "Invalid or expired login link", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	setSessionCookies(c, h.cfg, authResponse)
 	return h.response.Success(c, authResponse)
 }
 
@@ -113,6 +338,81 @@ func (h *AuthHandler) UpdateProfile(c echo.Context) error {
 	return h.response.Success(c, user)
 }
 
+// ChangeEmail starts a change that takes effect once the new address confirms it
+func (h *AuthHandler) ChangeEmail(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.ChangeEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.RequestEmailChange(c.Request().Context(), userID, &req); err != nil {
+		switch err.Error() {
+		case constants.ErrUserExists:
+			return h.response.Conflict(c, "User with this email already exists", err)
+		case constants.ErrEmailUnchanged:
+			return h.response.BadRequest(c, "New email is the same as the current one", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Check your new email address to confirm the change"})
+}
+
+func (h *AuthHandler) ConfirmEmailChange(c echo.Context) error {
+	var req model.EmailChangeTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.ConfirmEmailChange(c.Request().Context(), &req); err != nil {
+		switch err.Error() {
+		case constants.ErrInvalidActionToken:
+			return h.response.BadRequest(c, "Invalid or expired confirmation link", err)
+		case constants.ErrUserExists:
+			return h.response.Conflict(c, "User with this email already exists", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Email changed successfully"})
+}
+
+func (h *AuthHandler) UndoEmailChange(c echo.Context) error {
+	var req model.EmailChangeTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.authService.UndoEmailChange(c.Request().Context(), &req); err != nil {
+		switch err.Error() {
+		case constants.ErrInvalidActionToken:
+			return h.response.BadRequest(c, "Invalid or expired link", err)
+		case constants.ErrUserExists:
+			return h.response.Conflict(c, "User with this email already exists", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Email change undone and all sessions signed out"})
+}
+
 func (h *AuthHandler) ChangePassword(c echo.Context) error {
 	userID, ok := c.Get("userID").(pgtype.UUID)
 	if !ok {
@@ -130,6 +430,10 @@ func (h *AuthHandler) ChangePassword(c echo.Context) error {
 
 	err := h.authService.ChangePassword(c.Request().Context(), userID, &req)
 	if err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return h.response.ValidationError(c, policyErr.Violations, err)
+		}
 		if err.Error() == "invalid current password" {
 			return h.response.BadRequest(c, "Invalid current password", err)
 		}
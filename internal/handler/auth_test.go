@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type fakeLoginService struct {
+	service.AuthService
+	err error
+}
+
+func (f *fakeLoginService) Login(ctx context.Context, req *model.LoginRequest) (*model.AuthResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &model.AuthResponse{User: &model.User{Email: req.Email}, Token: "access-token"}, nil
+}
+
+func TestLoginStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "success", wantStatus: http.StatusOK},
+		{name: "invalid credentials", err: errors.New(constants.ErrInvalidCredentials), wantStatus: http.StatusUnauthorized},
+		{
+			name:           "locked",
+			err:            &service.AccountLockedError{Until: time.Now().Add(90 * time.Second)},
+			wantStatus:     http.StatusLocked,
+			wantRetryAfter: "90",
+		},
+		{
+			name:           "throttled",
+			err:            &service.LoginThrottledError{RetryAfter: 1500 * time.Millisecond},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Validator = utils.NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"user@example.com","password":"secret"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			h := NewAuthHandler(&fakeLoginService{err: tt.err}, &config.Config{}, zap.NewNop())
+			if err := h.Login(e.NewContext(req, rec)); err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	federationStateCookie = "federation_state"
+	federationCookiePath  = "/auth/federated"
+)
+
+type FederationHandler struct {
+	federationService service.FederationService
+	cfg               *config.Config
+	response          *utils.ResponseHelper
+	logger            *zap.Logger
+}
+
+func NewFederationHandler(federationService service.FederationService, cfg *config.Config, logger *zap.Logger) *FederationHandler {
+	return &FederationHandler{
+		federationService: federationService,
+		cfg:               cfg,
+		response:          utils.NewResponseHelper(logger),
+		logger:            logger,
+	}
+}
+
+func (h *FederationHandler) Providers(c echo.Context) error {
+	return h.response.Success(c, h.federationService.Providers())
+}
+
+// Login redirects the browser to the identity provider
+func (h *FederationHandler) Login(c echo.Context) error {
+	authURL, state, err := h.federationService.BeginLogin(c.Request().Context(), c.Param("provider"))
+	if err != nil {
+		if err.Error() == constants.ErrIdentityProviderNotFound {
+			return h.response.NotFound(c, "Identity provider not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	h.setStateCookie(c, state, int(h.cfg.Federation.StateTTL.Seconds()))
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+func (h *FederationHandler) Callback(c echo.Context) error {
+	var req model.FederatedCallbackRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	var state string
+	if cookie, err := c.Cookie(federationStateCookie); err == nil {
+		state = cookie.Value
+	}
+	// The state is single-use whatever the outcome
+	h.setStateCookie(c, "", -1)
+
+	authResponse, err := h.federationService.FinishLogin(c.Request().Context(), c.Param("provider"), &req, state)
+	if err != nil {
+		var mfaErr *service.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return h.response.Success(c, mfaErr.Challenge)
+		}
+
+		switch err.Error() {
+		case constants.ErrIdentityProviderNotFound:
+			return h.response.NotFound(c, "Identity provider not found", err)
+		case constants.ErrInvalidFederationState:
+			return h.response.BadRequest(c, "Login session is invalid or has expired", err)
+		case constants.ErrUserExists:
+			return h.response.Conflict(c, "An account with this email already exists; sign in with your password to use it", err)
+		case constants.ErrEmailNotVerified:
+			return h.response.Error(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "Email address has not been verified", err)
+		case constants.ErrFederatedLoginFailed:
+			return h.response.Unauthorized(c, "Sign-in with the identity provider failed", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	setSessionCookies(c, h.cfg, authResponse)
+	return h.response.Success(c, authResponse)
+}
+
+// setStateCookie uses SameSite=Lax so the cookie survives the top-level redirect
+// back from the provider
+func (h *FederationHandler) setStateCookie(c echo.Context, value string, maxAge int) {
+	c.SetCookie(&http.Cookie{
+		Name:     federationStateCookie,
+		Value:    value,
+		Path:     federationCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.cfg.Env == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type MFAHandler struct {
+	mfaService service.MFAService
+	cfg        *config.Config
+	response   *utils.ResponseHelper
+	logger     *zap.Logger
+}
+
+func NewMFAHandler(mfaService service.MFAService, cfg *config.Config, logger *zap.Logger) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+		cfg:        cfg,
+		response:   utils.NewResponseHelper(logger),
+		logger:     logger,
+	}
+}
+
+func (h *MFAHandler) Enroll(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	enrollment, err := h.mfaService.Enroll(c.Request().Context(), userID)
+	if err != nil {
+		if err.Error() == constants.ErrMFAAlreadyEnabled {
+			return h.response.Conflict(c, "MFA is already enabled", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, enrollment)
+}
+
+func (h *MFAHandler) Confirm(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	codes, err := h.mfaService.Confirm(c.Request().Context(), userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case constants.ErrMFANotEnrolled:
+			return h.response.BadRequest(c, "MFA enrollment has not been started", err)
+		case constants.ErrMFAAlreadyEnabled:
+			return h.response.Conflict(c, "MFA is already enabled", err)
+		case constants.ErrInvalidMFACode:
+			return h.response.BadRequest(c, "Invalid authentication code", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, codes)
+}
+
+func (h *MFAHandler) Disable(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.mfaService.Disable(c.Request().Context(), userID, &req); err != nil {
+		switch err.Error() {
+		case constants.ErrMFANotEnrolled:
+			return h.response.NotFound(c, "MFA is not enabled", err)
+		case constants.ErrInvalidMFACode:
+			return h.response.BadRequest(c, "Invalid authentication code", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "MFA disabled"})
+}
+
+func (h *MFAHandler) Verify(c echo.Context) error {
+	var req model.MFAVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	authResponse, err := h.mfaService.VerifyChallenge(c.Request().Context(), &req)
+	if err != nil {
+		var lockedErr *service.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			utils.SetRetryAfter(c, time.Until(lockedErr.Until))
+			return h.response.Locked(c, "Account is temporarily locked after too many failed login attempts", err)
+		}
+
+		var throttledErr *service.LoginThrottledError
+		if errors.As(err, &throttledErr) {
+			utils.SetRetryAfter(c, throttledErr.RetryAfter)
+			return h.response.TooManyRequests(c, "Too many failed login attempts, try again later", err)
+		}
+
+		switch err.Error() {
+		case constants.ErrInvalidToken, constants.ErrMFANotEnrolled:
+			return h.response.Unauthorized(c, "Invalid or expired MFA challenge", err)
+		case constants.ErrInvalidMFACode:
+			return h.response.Unauthorized(c, "Invalid authentication code", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	setSessionCookies(c, h.cfg, authResponse)
+	return h.response.Success(c, authResponse)
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// OAuthHandler serves the OpenID Connect provider endpoints. Protocol endpoints
+// answer in the OAuth wire format rather than utils.Response; only the consent
+// decision, which our own app calls, uses the usual envelope.
+type OAuthHandler struct {
+	oauthService service.OAuthService
+	cfg          *config.Config
+	response     *utils.ResponseHelper
+	logger       *zap.Logger
+}
+
+func NewOAuthHandler(oauthService service.OAuthService, cfg *config.Config, logger *zap.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		cfg:          cfg,
+		response:     utils.NewResponseHelper(logger),
+		logger:       logger,
+	}
+}
+
+// Authorize validates the request and sends the browser to the app's login and
+// consent page with the original parameters
+func (h *OAuthHandler) Authorize(c echo.Context) error {
+	var req model.AuthorizeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "malformed authorization request"})
+	}
+
+	if _, err := h.oauthService.ValidateAuthorizeRequest(c.Request().Context(), &req); err != nil {
+		return h.authorizeError(c, err)
+	}
+
+	return c.Redirect(http.StatusFound, h.cfg.OIDC.LoginURL+"?"+c.QueryString())
+}
+
+// Decide is called by the consent page with the user's bearer token or session cookie
+func (h *OAuthHandler) Decide(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	// auth_time is when this login session began. API keys have no session and
+	// cannot consent.
+	sessionID := currentSessionID(c)
+	if !sessionID.Valid {
+		return h.response.Unauthorized(c, "Invalid token claims", nil)
+	}
+
+	var req model.AuthorizeDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	decision, err := h.oauthService.Authorize(c.Request().Context(), userID, sessionID, &req)
+	if err != nil {
+		if err.Error() == constants.ErrSessionNotFound {
+			return h.response.Unauthorized(c, "Session has ended", err)
+		}
+
+		var oauthErr *service.OAuthError
+		if errors.As(err, &oauthErr) {
+			if redirect := oauthErr.RedirectURL(); redirect != "" {
+				return h.response.Success(c, &model.AuthorizeDecisionResponse{RedirectTo: redirect})
+			}
+			return h.response.BadRequest(c, oauthErr.Description, err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, decision)
+}
+
+func (h *OAuthHandler) Token(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req model.TokenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "malformed token request"})
+	}
+	basicAuth(c, &req.ClientID, &req.ClientSecret)
+
+	tokens, err := h.oauthService.Token(c.Request().Context(), &req)
+	if err != nil {
+		return h.clientError(c, "Token request failed", err)
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Introspect is called by resource servers to check a token they were handed
+func (h *OAuthHandler) Introspect(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	var req model.TokenIntrospectionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "malformed introspection request"})
+	}
+	basicAuth(c, &req.ClientID, &req.ClientSecret)
+
+	introspection, err := h.oauthService.Introspect(c.Request().Context(), &req)
+	if err != nil {
+		return h.clientError(c, "Introspection request failed", err)
+	}
+
+	return c.JSON(http.StatusOK, introspection)
+}
+
+// Revoke answers 200 with an empty body whether or not the token was valid
+func (h *OAuthHandler) Revoke(c echo.Context) error {
+	var req model.TokenIntrospectionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "malformed revocation request"})
+	}
+	basicAuth(c, &req.ClientID, &req.ClientSecret)
+
+	if err := h.oauthService.Revoke(c.Request().Context(), &req); err != nil {
+		return h.clientError(c, "Revocation request failed", err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// UserInfo runs behind OAuthBearer and answers with the claims the token's scopes cover
+func (h *OAuthHandler) UserInfo(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+	}
+	scopes, _ := c.Get("scopes").([]string)
+
+	info, err := h.oauthService.UserInfo(c.Request().Context(), userID, scopes)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+func (h *OAuthHandler) OpenIDConfiguration(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.oauthService.Discovery())
+}
+
+// basicAuth applies client_secret_basic credentials, which take precedence over
+// credentials in the body
+func basicAuth(c echo.Context, clientID, clientSecret *string) {
+	if id, secret, ok := c.Request().BasicAuth(); ok {
+		*clientID, _ = url.QueryUnescape(id)
+		*clientSecret, _ = url.QueryUnescape(secret)
+	}
+}
+
+// clientError renders an error from an endpoint that authenticates the client
+func (h *OAuthHandler) clientError(c echo.Context, msg string, err error) error {
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		status := http.StatusBadRequest
+		if oauthErr.Code == "invalid_client" {
+			status = http.StatusUnauthorized
+			c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		return c.JSON(status, oauthErr)
+	}
+	h.logger.Error(msg, zap.Error(err))
+	return c.JSON(http.StatusInternalServerError, &service.OAuthError{Code: "server_error"})
+}
+
+// authorizeError redirects back to the client when it is safe to do so and
+// otherwise shows the error to the user
+func (h *OAuthHandler) authorizeError(c echo.Context, err error) error {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		h.logger.Error("Authorization request failed", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, &service.OAuthError{Code: "server_error"})
+	}
+
+	if redirect := oauthErr.RedirectURL(); redirect != "" {
+		return c.Redirect(http.StatusFound, redirect)
+	}
+
+	h.logger.Warn("Rejected authorization request",
+		zap.String("error", oauthErr.Code),
+		zap.String("client_id", c.QueryParam("client_id")),
+	)
+	return c.JSON(http.StatusBadRequest, oauthErr)
+}
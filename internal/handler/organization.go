@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// OrganizationHandler serves /api/v1/orgs. Routes under /orgs/:orgID run behind
+// RequireOrgMember, which puts the caller's membership in the context.
+type OrganizationHandler struct {
+	orgService service.OrganizationService
+	response   *utils.ResponseHelper
+	logger     *zap.Logger
+}
+
+func NewOrganizationHandler(orgService service.OrganizationService, logger *zap.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+		response:   utils.NewResponseHelper(logger),
+		logger:     logger,
+	}
+}
+
+func (h *OrganizationHandler) Create(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.CreateOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	org, err := h.orgService.CreateOrganization(c.Request().Context(), userID, &req)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Created(c, org)
+}
+
+func (h *OrganizationHandler) List(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	orgs, err := h.orgService.ListUserOrganizations(c.Request().Context(), userID)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, orgs)
+}
+
+// Switch changes the caller's active organization. Token sessions get a new
+// access token carrying it; cookie sessions have it stored on the session.
+func (h *OrganizationHandler) Switch(c echo.Context) error {
+	var req model.SwitchOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	var orgID pgtype.UUID
+	if req.OrgID != "" {
+		if err := orgID.Scan(req.OrgID); err != nil {
+			return h.response.BadRequest(c, "Invalid organization ID", err)
+		}
+	}
+
+	if session, ok := c.Get("session").(*model.Session); ok {
+		if err := h.orgService.SwitchSessionOrganization(c.Request().Context(), session, orgID); err != nil {
+			return h.switchError(c, err)
+		}
+		return h.response.Success(c, session)
+	}
+
+	claims, ok := c.Get("claims").(*utils.Claims)
+	if !ok {
+		return h.response.BadRequest(c, "Switching organization requires a session token", nil)
+	}
+
+	authResponse, err := h.orgService.SwitchOrganization(c.Request().Context(), claims, orgID)
+	if err != nil {
+		return h.switchError(c, err)
+	}
+
+	return h.response.Success(c, authResponse)
+}
+
+func (h *OrganizationHandler) switchError(c echo.Context, err error) error {
+	switch err.Error() {
+	case constants.ErrMembershipNotFound:
+		return h.response.NotFound(c, "Organization not found", err)
+	case constants.ErrSessionNotFound:
+		return h.response.BadRequest(c, "Switching organization requires a session token", err)
+	}
+	return h.response.InternalServerError(c, err)
+}
+
+func (h *OrganizationHandler) Get(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	org, err := h.orgService.GetOrganization(c.Request().Context(), membership.OrgID)
+	if err != nil {
+		if err.Error() == constants.ErrOrganizationNotFound {
+			return h.response.NotFound(c, "Organization not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+	org.Role = membership.Role
+
+	return h.response.Success(c, org)
+}
+
+func (h *OrganizationHandler) Update(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	var req model.UpdateOrganizationRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	org, err := h.orgService.UpdateOrganization(c.Request().Context(), membership.OrgID, &req)
+	if err != nil {
+		if err.Error() == constants.ErrOrganizationNotFound {
+			return h.response.NotFound(c, "Organization not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+	org.Role = membership.Role
+
+	return h.response.Success(c, org)
+}
+
+func (h *OrganizationHandler) Delete(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	if err := h.orgService.DeleteOrganization(c.Request().Context(), membership.OrgID); err != nil {
+		if err.Error() == constants.ErrOrganizationNotFound {
+			return h.response.NotFound(c, "Organization not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Organization deleted"})
+}
+
+func (h *OrganizationHandler) ListMembers(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	members, err := h.orgService.ListMembers(c.Request().Context(), membership.OrgID)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, members)
+}
+
+func (h *OrganizationHandler) UpdateMemberRole(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	var req model.UpdateMemberRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.orgService.UpdateMemberRole(c.Request().Context(), membership, userID, req.Role); err != nil {
+		return h.memberError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Member role updated"})
+}
+
+// RemoveMember removes another member, or the caller when they leave
+func (h *OrganizationHandler) RemoveMember(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	userID, err := parseUserIDParam(c)
+	if err != nil {
+		return h.response.BadRequest(c, "Invalid user ID", err)
+	}
+
+	if err := h.orgService.RemoveMember(c.Request().Context(), membership, userID); err != nil {
+		return h.memberError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Member removed"})
+}
+
+func (h *OrganizationHandler) memberError(c echo.Context, err error) error {
+	switch err.Error() {
+	case constants.ErrMembershipNotFound:
+		return h.response.NotFound(c, "Member not found", err)
+	case constants.ErrInsufficientOrgRole:
+		return h.response.Forbidden(c, "Only owners can manage owners", err)
+	case constants.ErrLastOrgOwner:
+		return h.response.Conflict(c, "The organization must keep at least one owner", err)
+	}
+	return h.response.InternalServerError(c, err)
+}
+
+func (h *OrganizationHandler) Invite(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	var req model.InviteMemberRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	invitation, err := h.orgService.Invite(c.Request().Context(), membership, &req)
+	if err != nil {
+		switch err.Error() {
+		case constants.ErrAlreadyMember:
+			return h.response.Conflict(c, "User is already a member", err)
+		case constants.ErrInsufficientOrgRole:
+			return h.response.Forbidden(c, "Only owners can invite owners", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Created(c, invitation)
+}
+
+func (h *OrganizationHandler) ListInvitations(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	invitations, err := h.orgService.ListInvitations(c.Request().Context(), membership.OrgID)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, invitations)
+}
+
+func (h *OrganizationHandler) RevokeInvitation(c echo.Context) error {
+	membership := c.Get("membership").(*model.Membership)
+
+	var id pgtype.UUID
+	if err := id.Scan(c.Param("id")); err != nil {
+		return h.response.BadRequest(c, "Invalid invitation ID", err)
+	}
+
+	if err := h.orgService.RevokeInvitation(c.Request().Context(), membership.OrgID, id); err != nil {
+		if err.Error() == constants.ErrInvitationNotFound {
+			return h.response.NotFound(c, "Invitation not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Invitation revoked"})
+}
+
+func (h *OrganizationHandler) AcceptInvitation(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.InvitationTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	org, err := h.orgService.AcceptInvitation(c.Request().Context(), userID, req.Token)
+	if err != nil {
+		if err.Error() == constants.ErrInvitationNotFound {
+			return h.response.BadRequest(c, "Invalid or expired invitation", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, org)
+}
+
+func (h *OrganizationHandler) DeclineInvitation(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.InvitationTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	if err := h.orgService.DeclineInvitation(c.Request().Context(), userID, req.Token); err != nil {
+		if err.Error() == constants.ErrInvitationNotFound {
+			return h.response.BadRequest(c, "Invalid or expired invitation", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Invitation declined"})
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	customMiddleware "github.com/manish-npx/go-echo-pg/internal/middleware"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// fakeCookieSessions hands out a copy of the stored session for its cookie
+// secret, as reading the row again would
+type fakeCookieSessions struct {
+	service.SessionService
+	secret  string
+	session *model.Session
+}
+
+func (f *fakeCookieSessions) AuthenticateCookie(ctx context.Context, secret string) (*model.Session, error) {
+	if secret != f.secret {
+		return nil, errors.New(constants.ErrSessionNotFound)
+	}
+	copied := *f.session
+	return &copied, nil
+}
+
+type fakeSessionOrgs struct {
+	repository.SessionRepository
+	session *model.Session
+}
+
+func (f *fakeSessionOrgs) SetSessionOrganization(ctx context.Context, id, orgID pgtype.UUID) error {
+	if id != f.session.ID {
+		return errors.New(constants.ErrSessionNotFound)
+	}
+	f.session.OrgID = orgID
+	return nil
+}
+
+type fakeMemberships struct {
+	repository.OrganizationRepository
+	orgID pgtype.UUID
+}
+
+func (f *fakeMemberships) GetMembership(ctx context.Context, orgID, userID pgtype.UUID) (*model.Membership, error) {
+	if orgID != f.orgID {
+		return nil, errors.New(constants.ErrMembershipNotFound)
+	}
+	return &model.Membership{OrgID: orgID, UserID: userID, Role: model.OrgRoleMember}, nil
+}
+
+func TestSwitchWithCookieSession(t *testing.T) {
+	orgID := pgtype.UUID{Bytes: [16]byte{7}, Valid: true}
+	session := &model.Session{
+		ID:            pgtype.UUID{Bytes: [16]byte{2}, Valid: true},
+		UserID:        pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		UserEmail:     "user@example.com",
+		CSRFTokenHash: pgtype.Text{String: utils.HashToken("csrf-token"), Valid: true},
+	}
+
+	cfg := &config.Config{}
+	cfg.SessionCookie.Name = "session"
+	orgService := service.NewOrganizationService(&fakeMemberships{orgID: orgID}, nil, &fakeSessionOrgs{session: session}, nil, nil, cfg, zap.NewNop())
+
+	e := echo.New()
+	e.Validator = utils.NewValidator()
+	auth := customMiddleware.AuthMiddleware(nil, nil, nil, &fakeCookieSessions{secret: "cookie-secret", session: session}, cfg)
+	e.POST("/orgs/switch", NewOrganizationHandler(orgService, zap.NewNop()).Switch, auth)
+	e.GET("/active-org", func(c echo.Context) error {
+		activeOrgID, _ := c.Get("activeOrgID").(pgtype.UUID)
+		return c.String(http.StatusOK, activeOrgID.String())
+	}, auth)
+
+	tests := []struct {
+		name       string
+		orgID      pgtype.UUID
+		csrfToken  string
+		wantStatus int
+		wantOrgID  pgtype.UUID
+	}{
+		{name: "missing csrf token", orgID: orgID, wantStatus: http.StatusForbidden},
+		{name: "wrong csrf token", orgID: orgID, csrfToken: "other-token", wantStatus: http.StatusForbidden},
+		{name: "not a member", orgID: pgtype.UUID{Bytes: [16]byte{8}, Valid: true}, csrfToken: "csrf-token", wantStatus: http.StatusNotFound},
+		{name: "member", orgID: orgID, csrfToken: "csrf-token", wantStatus: http.StatusOK, wantOrgID: orgID},
+		{name: "clear", csrfToken: "csrf-token", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session.OrgID = pgtype.UUID{}
+
+			body := `{}`
+			if tt.orgID.Valid {
+				body = `{"org_id":"` + tt.orgID.String() + `"}`
+			}
+			req := httptest.NewRequest(http.MethodPost, "/orgs/switch", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.AddCookie(&http.Cookie{Name: "session", Value: "cookie-secret"})
+			if tt.csrfToken != "" {
+				req.Header.Set(customMiddleware.CSRFTokenHeader, tt.csrfToken)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if session.OrgID != tt.wantOrgID {
+				t.Fatalf("stored organization = %v, want %v", session.OrgID, tt.wantOrgID)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Data model.Session `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.Data.OrgID != tt.wantOrgID {
+				t.Errorf("response organization = %v, want %v", resp.Data.OrgID, tt.wantOrgID)
+			}
+
+			// The next request on the same cookie carries the organization
+			req = httptest.NewRequest(http.MethodGet, "/active-org", nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: "cookie-secret"})
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if got := rec.Body.String(); got != tt.wantOrgID.String() {
+				t.Errorf("active organization = %q, want %q", got, tt.wantOrgID.String())
+			}
+		})
+	}
+}
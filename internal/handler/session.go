@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+	response       *utils.ResponseHelper
+	logger         *zap.Logger
+}
+
+func NewSessionHandler(sessionService service.SessionService, logger *zap.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		response:       utils.NewResponseHelper(logger),
+		logger:         logger,
+	}
+}
+
+func (h *SessionHandler) List(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	sessions, err := h.sessionService.ListSessions(c.Request().Context(), userID, currentSessionID(c))
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, sessions)
+}
+
+func (h *SessionHandler) Revoke(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var id pgtype.UUID
+	if err := id.Scan(c.Param("id")); err != nil {
+		return h.response.BadRequest(c, "Invalid session ID", err)
+	}
+
+	if err := h.sessionService.RevokeSession(c.Request().Context(), userID, id); err != nil {
+		if err.Error() == constants.ErrSessionNotFound {
+			return h.response.NotFound(c, "Session not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Session revoked"})
+}
+
+// RevokeOthers logs out everywhere except the session making the request
+func (h *SessionHandler) RevokeOthers(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	revoked, err := h.sessionService.RevokeOtherSessions(c.Request().Context(), userID, currentSessionID(c))
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]int{"revoked": revoked})
+}
+
+// currentSessionID is unset for API keys, which have no session
+func currentSessionID(c echo.Context) pgtype.UUID {
+	if claims, ok := c.Get("claims").(*utils.Claims); ok {
+		return claims.SessionID
+	}
+	if session, ok := c.Get("session").(*model.Session); ok {
+		return session.ID
+	}
+	return pgtype.UUID{}
+}
+
+// setSessionCookies hands a cookie session started by a login to the browser.
+// Logins that issued tokens set no cookies.
+func setSessionCookies(c echo.Context, cfg *config.Config, authResponse *model.AuthResponse) {
+	if authResponse.SessionSecret == "" {
+		return
+	}
+	utils.SetSessionCookies(c, &cfg.SessionCookie, authResponse.SessionSecret, authResponse.CSRFToken, time.Unix(authResponse.ExpiresAt, 0))
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type WebAuthnHandler struct {
+	webAuthnService service.WebAuthnService
+	cfg             *config.Config
+	response        *utils.ResponseHelper
+	logger          *zap.Logger
+}
+
+func NewWebAuthnHandler(webAuthnService service.WebAuthnService, cfg *config.Config, logger *zap.Logger) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webAuthnService: webAuthnService,
+		cfg:             cfg,
+		response:        utils.NewResponseHelper(logger),
+		logger:          logger,
+	}
+}
+
+func (h *WebAuthnHandler) BeginRegistration(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(c.Request().Context(), userID)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, options)
+}
+
+func (h *WebAuthnHandler) FinishRegistration(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var req model.WebAuthnFinishRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	credential, err := h.webAuthnService.FinishRegistration(c.Request().Context(), userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case constants.ErrWebAuthnSessionNotFound:
+			return h.response.BadRequest(c, "Registration session not found or expired", err)
+		case constants.ErrWebAuthnVerification:
+			return h.response.BadRequest(c, "Passkey registration could not be verified", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Created(c, credential)
+}
+
+func (h *WebAuthnHandler) BeginLogin(c echo.Context) error {
+	var req model.WebAuthnBeginLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	options, err := h.webAuthnService.BeginLogin(c.Request().Context(), &req)
+	if err != nil {
+		if err.Error() == constants.ErrWebAuthnCredentialNotFound {
+			return h.response.NotFound(c, "No passkey registered for this account", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, options)
+}
+
+func (h *WebAuthnHandler) FinishLogin(c echo.Context) error {
+	var req model.WebAuthnFinishRequest
+	if err := c.Bind(&req); err != nil {
+		return h.response.BadRequest(c, "Invalid request format", err)
+	}
+
+	if err := c.Validate(req); err != nil {
+		return h.response.ValidationError(c, err.Error(), err)
+	}
+
+	authResponse, err := h.webAuthnService.FinishLogin(c.Request().Context(), &req)
+	if err != nil {
+		switch err.Error() {
+		case constants.ErrWebAuthnSessionNotFound:
+			return h.response.Unauthorized(c, "Login session not found or expired", err)
+		case constants.ErrWebAuthnVerification, constants.ErrWebAuthnCredentialNotFound:
+			return h.response.Unauthorized(c, "Passkey could not be verified", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	setSessionCookies(c, h.cfg, authResponse)
+	return h.response.Success(c, authResponse)
+}
+
+func (h *WebAuthnHandler) ListCredentials(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	credentials, err := h.webAuthnService.ListCredentials(c.Request().Context(), userID)
+	if err != nil {
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, credentials)
+}
+
+func (h *WebAuthnHandler) DeleteCredential(c echo.Context) error {
+	userID, ok := c.Get("userID").(pgtype.UUID)
+	if !ok {
+		return h.response.Unauthorized(c, "Invalid user ID", nil)
+	}
+
+	var id pgtype.UUID
+	if err := id.Scan(c.Param("id")); err != nil {
+		return h.response.BadRequest(c, "Invalid credential ID", err)
+	}
+
+	if err := h.webAuthnService.DeleteCredential(c.Request().Context(), userID, id); err != nil {
+		if err.Error() == constants.ErrWebAuthnCredentialNotFound {
+			return h.response.NotFound(c, "Credential not found", err)
+		}
+		return h.response.InternalServerError(c, err)
+	}
+
+	return h.response.Success(c, map[string]string{"message": "Credential deleted"})
+}
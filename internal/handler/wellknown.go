@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// WellKnownHandler serves standard discovery documents. They are returned as-is
+// rather than wrapped in utils.Response so off-the-shelf clients can consume them.
+type WellKnownHandler struct {
+	keys   *utils.KeyRing
+	logger *zap.Logger
+}
+
+func NewWellKnownHandler(keys *utils.KeyRing, logger *zap.Logger) *WellKnownHandler {
+	return &WellKnownHandler{
+		keys:   keys,
+		logger: logger,
+	}
+}
+
+func (h *WellKnownHandler) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"go.uber.org/zap"
+)
+
+// FileMailer writes each message as an .eml file, for local development and tests
+type FileMailer struct {
+	cfg    *config.MailConfig
+	logger *zap.Logger
+}
+
+func NewFileMailer(cfg *config.MailConfig, logger *zap.Logger) (*FileMailer, error) {
+	if err := os.MkdirAll(cfg.FileDir, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating mail directory: %w", err)
+	}
+
+	return &FileMailer{
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	path := filepath.Join(m.cfg.FileDir, name)
+
+	if err := os.WriteFile(path, buildMessage(m.cfg.From, msg), 0o600); err != nil {
+		return fmt.Errorf("error writing mail file: %w", err)
+	}
+
+	m.logger.Info("Mail written to file", zap.String("to", msg.To), zap.String("path", path))
+	return nil
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"go.uber.org/zap"
+)
+
+// LogMailer only logs messages. It is the default so development needs no mail server.
+// Bodies carry live tokens and are left out unless mail.log_body is set.
+type LogMailer struct {
+	cfg    *config.MailConfig
+	logger *zap.Logger
+}
+
+func NewLogMailer(cfg *config.MailConfig, logger *zap.Logger) *LogMailer {
+	return &LogMailer{
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	fields := []zap.Field{
+		zap.String("from", m.cfg.From),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+	}
+	if m.cfg.LogBody {
+		fields = append(fields, zap.String("body", msg.Body))
+	}
+
+	m.logger.Info("Mail (log driver)", fields...)
+	return nil
+}
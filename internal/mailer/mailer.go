@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"go.uber.org/zap"
+)
+
+// Message is a plain-text transactional email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New builds the mailer selected by mail.driver
+func New(cfg *config.Config, logger *zap.Logger) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return NewSMTPMailer(&cfg.Mail, logger), nil
+	case "file":
+		return NewFileMailer(&cfg.Mail, logger)
+	case "log", "":
+		return NewLogMailer(&cfg.Mail, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
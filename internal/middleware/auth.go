@@ -1,19 +1,33 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
 	"github.com/manish-npx/go-echo-pg/internal/utils"
 )
 
-func AuthMiddleware(config *config.Config) echo.MiddlewareFunc {
+// AuthMiddleware accepts either a JWT access token or an API key as the bearer
+// credential. API keys are recognised by their prefix and put their scopes in the
+// context; JWT sessions leave "scopes" unset, which RequireScope treats as full access.
+// Service tokens from the client_credentials grant set "clientID" and "scopes"
+// but no "userID"; "principal" tells the two kinds of caller apart.
+// Without an Authorization header the session cookie is tried; cookie sessions
+// set "session" instead of "claims". Either way the organization the caller
+// switched to, if any, is put in "activeOrgID".
+func AuthMiddleware(keys *utils.KeyRing, revocationService service.TokenRevocationService, apiKeyService service.APIKeyService, sessionService service.SessionService, cfg *config.Config) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
+				if cookie, err := c.Cookie(cfg.SessionCookie.Name); err == nil && cookie.Value != "" {
+					return cookieSession(c, next, sessionService, cookie.Value)
+				}
 				return echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
 			}
 
@@ -23,15 +37,79 @@ func AuthMiddleware(config *config.Config) echo.MiddlewareFunc {
 			}
 
 			tokenString := parts[1]
-			claims, err := utils.ValidateToken(tokenString, config)
+			if strings.HasPrefix(tokenString, service.APIKeyPrefix) {
+				key, err := apiKeyService.Authenticate(c.Request().Context(), tokenString)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid api key").SetInternal(err)
+				}
+
+				c.Set("principal", model.PrincipalUser)
+				c.Set("userID", key.UserID)
+				c.Set("userEmail", key.UserEmail)
+				c.Set("apiKeyID", key.ID)
+				c.Set("scopes", key.Scopes)
+
+				return next(c)
+			}
+
+			claims, err := utils.ValidateToken(tokenString, keys)
 			if err != nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
 			}
 
+			revoked, err := revocationService.IsRevoked(c.Request().Context(), claims)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token").SetInternal(err)
+			}
+			if revoked {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token has been revoked")
+			}
+
+			c.Set("claims", claims)
+			if claims.IsService() {
+				c.Set("principal", model.PrincipalService)
+				c.Set("clientID", claims.ClientID)
+				c.Set("scopes", strings.Fields(claims.Scope))
+				return next(c)
+			}
+
+			c.Set("principal", model.PrincipalUser)
 			c.Set("userID", claims.UserID)
 			c.Set("userEmail", claims.Email)
+			if claims.OrgID.Valid {
+				c.Set("activeOrgID", claims.OrgID)
+			}
 
 			return next(c)
 		}
 	}
 }
+
+// cookieSession authenticates a request by its session cookie. Browsers attach
+// cookies to cross-site requests too, so unsafe methods must also echo the
+// session's CSRF token, which other sites cannot read.
+func cookieSession(c echo.Context, next echo.HandlerFunc, sessionService service.SessionService, secret string) error {
+	session, err := sessionService.AuthenticateCookie(c.Request().Context(), secret)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid session").SetInternal(err)
+	}
+
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		token := c.Request().Header.Get(CSRFTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(token)), []byte(session.CSRFTokenHash.String)) != 1 {
+			return echo.NewHTTPError(http.StatusForbidden, "missing or invalid csrf token")
+		}
+	}
+
+	c.Set("principal", model.PrincipalUser)
+	c.Set("userID", session.UserID)
+	c.Set("userEmail", session.UserEmail)
+	c.Set("session", session)
+	if session.OrgID.Valid {
+		c.Set("activeOrgID", session.OrgID)
+	}
+
+	return next(c)
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+)
+
+// ClientInfo records the caller's user agent and IP in the request context
+func ClientInfo() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := utils.WithClientInfo(req.Context(), utils.ClientInfo{
+				UserAgent: req.UserAgent(),
+				IPAddress: c.RealIP(),
+			})
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
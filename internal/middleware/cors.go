@@ -19,6 +19,10 @@ func CORS(config *config.Config) echo.MiddlewareFunc {
 			echo.HeaderContentType,
 			echo.HeaderAccept,
 			echo.HeaderAuthorization,
+			LoginChallengeHeader,
+			LoginChallengeSolutionHeader,
+			SessionModeHeader,
+			CSRFTokenHeader,
 		},
 		AllowCredentials: true,
 		MaxAge:           300,
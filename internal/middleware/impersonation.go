@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+)
+
+// DenyImpersonation keeps admins acting as a user away from routes that change
+// the user's credentials or grant lasting access. It must run after AuthMiddleware.
+func DenyImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if claims, ok := c.Get("claims").(*utils.Claims); ok && claims.Impersonated() {
+				return echo.NewHTTPError(http.StatusForbidden, "not allowed while impersonating a user")
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
 	"go.uber.org/zap"
 )
 
@@ -17,23 +18,27 @@ func Logger(logger *zap.Logger) echo.MiddlewareFunc {
 			c.Set("logger", logger)
 		},
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
-			if v.Error == nil {
-				logger.Info("request",
-					zap.String("method", v.Method),
-					zap.String("uri", v.URI),
-					zap.Int("status", v.Status),
-					zap.String("host", v.Host),
-					zap.Duration("latency", v.Latency),
+			fields := []zap.Field{
+				zap.String("method", v.Method),
+				zap.String("uri", v.URI),
+				zap.Int("status", v.Status),
+				zap.String("host", v.Host),
+				zap.Duration("latency", v.Latency),
+			}
+
+			// Requests made while impersonating name both the user and the admin
+			if claims, ok := c.Get("claims").(*utils.Claims); ok && claims.Impersonated() {
+				fields = append(fields,
+					zap.String("user_id", claims.UserID.String()),
+					zap.String("impersonator_id", claims.Actor.UserID.String()),
+					zap.String("impersonator_email", claims.Actor.Email),
 				)
+			}
+
+			if v.Error == nil {
+				logger.Info("request", fields...)
 			} else {
-				logger.Error("request error",
-					zap.String("method", v.Method),
-					zap.String("uri", v.URI),
-					zap.Int("status", v.Status),
-					zap.String("host", v.Host),
-					zap.Duration("latency", v.Latency),
-					zap.Error(v.Error),
-				)
+				logger.Error("request error", append(fields, zap.Error(v.Error))...)
 			}
 			return nil
 		},
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	LoginChallengeHeader         = "X-Login-Challenge"
+	LoginChallengeSolutionHeader = "X-Login-Challenge-Solution"
+)
+
+// LoginGuard puts the credential stuffing detector in front of a login handler.
+// The handler reports the attempted email in the "loginEmail" context key; every
+// 401 or 423 it answers with counts as a failure of the client's IP.
+func LoginGuard(loginGuardService service.LoginGuardService, logger *zap.Logger) echo.MiddlewareFunc {
+	response := utils.NewResponseHelper(logger)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ip := c.RealIP()
+
+			verdict := loginGuardService.Check(ip)
+			switch verdict.Action {
+			case service.LoginGuardBlock:
+				utils.SetRetryAfter(c, verdict.RetryAfter)
+				return response.TooManyRequests(c, "Too many failed login attempts from this network", nil)
+			case service.LoginGuardChallenge:
+				header := c.Request().Header
+				if err := loginGuardService.VerifyChallenge(ip, header.Get(LoginChallengeHeader), header.Get(LoginChallengeSolutionHeader)); err != nil {
+					challenge, err := loginGuardService.IssueChallenge(ip)
+					if err != nil {
+						return response.InternalServerError(c, err)
+					}
+					return response.ErrorWithDetails(c, http.StatusPreconditionRequired, "CHALLENGE_REQUIRED", "Solve the login challenge and retry", challenge, nil)
+				}
+			}
+
+			err := next(c)
+
+			switch c.Response().Status {
+			case http.StatusUnauthorized, http.StatusLocked:
+				email, _ := c.Get("loginEmail").(string)
+				loginGuardService.RecordFailure(ip, email)
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+)
+
+// OAuthBearer authenticates OAuth clients calling on a user's behalf. Only access
+// tokens from the token endpoint are accepted; first-party tokens, API keys and
+// session cookies are not. It sets "userID", "clientID", "scopes" and "claims".
+func OAuthBearer(keys *utils.KeyRing, revocationService service.TokenRevocationService, cfg *config.Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			parts := strings.Split(c.Request().Header.Get("Authorization"), " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header format")
+			}
+
+			claims, err := utils.ValidateOAuthToken(parts[1], cfg.OIDC.Issuer, keys)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+			}
+
+			revoked, err := revocationService.IsRevoked(c.Request().Context(), claims)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token").SetInternal(err)
+			}
+			if revoked {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token has been revoked")
+			}
+
+			c.Set("claims", claims)
+			c.Set("principal", model.PrincipalUser)
+			c.Set("userID", claims.UserID)
+			c.Set("clientID", claims.ClientID)
+			c.Set("scopes", strings.Fields(claims.Scope))
+
+			return next(c)
+		}
+	}
+}
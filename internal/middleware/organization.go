@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+)
+
+// RequireOrgMember guards routes under /orgs/:orgID. It must run after
+// AuthMiddleware and puts the caller's membership in the context. Membership is
+// read per request, so removing a member takes effect immediately; non-members
+// get 404 so they cannot probe which organizations exist.
+func RequireOrgMember(orgService service.OrganizationService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, ok := c.Get("userID").(pgtype.UUID)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+			}
+
+			var orgID pgtype.UUID
+			if err := orgID.Scan(c.Param("orgID")); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid organization id")
+			}
+
+			membership, err := orgService.GetMembership(c.Request().Context(), orgID, userID)
+			if err != nil {
+				if err.Error() == constants.ErrMembershipNotFound {
+					return echo.NewHTTPError(http.StatusNotFound, "organization not found")
+				}
+				return err
+			}
+
+			c.Set("orgID", orgID)
+			c.Set("membership", membership)
+			return next(c)
+		}
+	}
+}
+
+// RequireOrgRole lets the request through when the caller's organization role is
+// at least role. It must run after RequireOrgMember.
+func RequireOrgRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			membership, ok := c.Get("membership").(*model.Membership)
+			if !ok || !service.OrgRoleAtLeast(membership.Role, role) {
+				return echo.NewHTTPError(http.StatusForbidden, "requires organization role: "+role)
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+)
+
+// RequirePermission lets the request through only when every listed permission
+// is granted by at least one of the caller's roles; different roles may grant
+// different permissions. It must run after AuthMiddleware. Roles come
+// from the access token; API keys carry none, so they are looked up, and only
+// keys granted the admin scope may use them at all. Service principals have no
+// roles: their scopes name the permissions they hold.
+func RequirePermission(rbacService service.RBACService, permissions ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+
+			if _, ok := c.Get("apiKeyID").(pgtype.UUID); ok {
+				scopes, _ := c.Get("scopes").([]string)
+				if !slices.Contains(scopes, model.ScopeAdmin) {
+					return echo.NewHTTPError(http.StatusForbidden, "insufficient scope: "+model.ScopeAdmin)
+				}
+			}
+
+			if c.Get("principal") == model.PrincipalService {
+				scopes, _ := c.Get("scopes").([]string)
+				for _, permission := range permissions {
+					if !slices.Contains(scopes, permission) {
+						return echo.NewHTTPError(http.StatusForbidden, "missing permission: "+permission)
+					}
+				}
+				return next(c)
+			}
+
+			var roles []string
+			if claims, ok := c.Get("claims").(*utils.Claims); ok {
+				roles = claims.Roles
+			} else {
+				userID, ok := c.Get("userID").(pgtype.UUID)
+				if !ok {
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+				}
+				var err error
+				if roles, err = rbacService.GetUserRoles(ctx, userID); err != nil {
+					return err
+				}
+			}
+
+			for _, permission := range permissions {
+				allowed, err := rbacService.HasPermission(ctx, roles, permission)
+				if err != nil {
+					return err
+				}
+				if !allowed {
+					return echo.NewHTTPError(http.StatusForbidden, "missing permission: "+permission)
+				}
+			}
+
+			c.Set("roles", roles)
+			return next(c)
+		}
+	}
+}
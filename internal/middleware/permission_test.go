@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+)
+
+// fakeRBAC gives the admin role every permission and looks every user up as an admin
+type fakeRBAC struct {
+	service.RBACService
+}
+
+func (f *fakeRBAC) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	return slices.Contains(roles, model.RoleAdmin), nil
+}
+
+func (f *fakeRBAC) GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error) {
+	return []string{model.RoleAdmin}, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	userID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	apiKey := func(scopes ...string) map[string]any {
+		return map[string]any{
+			"principal": model.PrincipalUser,
+			"userID":    userID,
+			"apiKeyID":  pgtype.UUID{Bytes: [16]byte{2}, Valid: true},
+			"scopes":    scopes,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		context    map[string]any
+		wantStatus int
+	}{
+		{
+			name:       "admin token",
+			context:    map[string]any{"principal": model.PrincipalUser, "claims": &utils.Claims{UserID: userID, Roles: []string{model.RoleAdmin}}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "token without roles",
+			context:    map[string]any{"principal": model.PrincipalUser, "claims": &utils.Claims{UserID: userID}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "admin's api key without the admin scope",
+			context:    apiKey(model.ScopeProfileRead, model.ScopeAccountSecurity),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "admin's api key with the admin scope",
+			context:    apiKey(model.ScopeAdmin),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "service client with the permission",
+			context:    map[string]any{"principal": model.PrincipalService, "scopes": []string{model.PermissionUsersRead}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "service client without the permission",
+			context:    map[string]any{"principal": model.PrincipalService, "scopes": []string{model.PermissionRolesManage}},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil), httptest.NewRecorder())
+			for key, value := range tt.context {
+				c.Set(key, value)
+			}
+
+			handler := RequirePermission(&fakeRBAC{}, model.PermissionUsersRead)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			status := http.StatusOK
+			if err := handler(c); err != nil {
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) {
+					t.Fatalf("handler error = %v, want an HTTP error", err)
+				}
+				status = httpErr.Code
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// fakeRolePermissions grants each role the permissions listed for it
+type fakeRolePermissions struct {
+	service.RBACService
+	permissions map[string][]string
+	userRoles   []string
+}
+
+func (f *fakeRolePermissions) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	for _, role := range roles {
+		if slices.Contains(f.permissions[role], permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeRolePermissions) GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error) {
+	return f.userRoles, nil
+}
+
+func TestRequirePermissionAcrossRoles(t *testing.T) {
+	userID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	token := func(roles ...string) map[string]any {
+		return map[string]any{"principal": model.PrincipalUser, "claims": &utils.Claims{UserID: userID, Roles: roles}}
+	}
+
+	tests := []struct {
+		name       string
+		context    map[string]any
+		userRoles  []string
+		wantStatus int
+	}{
+		{name: "both roles", context: token("auditor", "role-admin"), wantStatus: http.StatusOK},
+		{name: "only the role granting users:read", context: token("auditor"), wantStatus: http.StatusForbidden},
+		{name: "only the role granting roles:manage", context: token("role-admin"), wantStatus: http.StatusForbidden},
+		{
+			name:       "both roles looked up for a cookie session",
+			context:    map[string]any{"principal": model.PrincipalUser, "userID": userID, "session": &model.Session{UserID: userID}},
+			userRoles:  []string{"auditor", "role-admin"},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rbac := &fakeRolePermissions{
+				permissions: map[string][]string{
+					"auditor":    {model.PermissionUsersRead},
+					"role-admin": {model.PermissionRolesManage},
+				},
+				userRoles: tt.userRoles,
+			}
+
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/admin/roles", nil), httptest.NewRecorder())
+			for key, value := range tt.context {
+				c.Set(key, value)
+			}
+
+			handler := RequirePermission(rbac, model.PermissionUsersRead, model.PermissionRolesManage)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			status := http.StatusOK
+			if err := handler(c); err != nil {
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) {
+					t.Fatalf("handler error = %v, want an HTTP error", err)
+				}
+				status = httpErr.Code
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
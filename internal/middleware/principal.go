@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+)
+
+// RequireUser keeps service principals off routes that act on the caller's own
+// account. It must run after AuthMiddleware.
+func RequireUser() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Get("principal") != model.PrincipalUser {
+				return echo.NewHTTPError(http.StatusForbidden, "this endpoint requires a user")
+			}
+
+			return next(c)
+		}
+	}
+}
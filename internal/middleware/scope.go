@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireScope rejects API keys that were not granted scope. It must run after
+// AuthMiddleware; interactive sessions are not scoped and always pass.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scopes, ok := c.Get("scopes").([]string)
+			if ok && !slices.Contains(scopes, scope) {
+				return echo.NewHTTPError(http.StatusForbidden, "insufficient scope: "+scope)
+			}
+
+			return next(c)
+		}
+	}
+}
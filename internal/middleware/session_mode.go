@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+)
+
+const (
+	// SessionModeHeader set to "cookie" on a login request asks for a cookie
+	// session instead of bearer tokens
+	SessionModeHeader = "X-Session-Mode"
+	// CSRFTokenHeader must echo the session's CSRF token on unsafe requests
+	// authenticated by cookie
+	CSRFTokenHeader = "X-CSRF-Token"
+)
+
+// SessionMode records in the request context whether the client asked for a
+// cookie session, for TokenService to honour when a login succeeds
+func SessionMode() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if strings.EqualFold(req.Header.Get(SessionModeHeader), "cookie") {
+				c.SetRequest(req.WithContext(utils.WithCookieSession(req.Context())))
+			}
+			return next(c)
+		}
+	}
+}
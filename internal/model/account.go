@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AccountDeletionResponse tells the user until when logging in restores the account
+type AccountDeletionResponse struct {
+	Message string    `json:"message"`
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// AccountExport is everything stored about a user, minus secrets such as
+// password hashes, MFA seeds, token hashes and key material. A pending email
+// change is part of User.
+type AccountExport struct {
+	ExportedAt         time.Time             `json:"exported_at"`
+	User               *User                 `json:"user"`
+	Roles              []string              `json:"roles"`
+	MFA                *UserMFA              `json:"mfa"`
+	RecoveryCodes      []*RecoveryCode       `json:"recovery_codes"`
+	Passkeys           []*WebAuthnCredential `json:"passkeys"`
+	WebAuthnCeremonies []*WebAuthnCeremony   `json:"webauthn_ceremonies"`
+	APIKeys            []*APIKey             `json:"api_keys"`
+	Sessions           []*Session            `json:"sessions"`
+	RefreshTokens      []*RefreshToken       `json:"refresh_tokens"`
+	RevokedTokens      []*RevokedToken       `json:"revoked_tokens"`
+	EmailTokens        []*UserToken          `json:"email_tokens"`
+	Identities         []*UserIdentity       `json:"identities"`
+	OAuthGrants        []*OAuthGrant         `json:"oauth_grants"`
+	Organizations      []*Organization       `json:"organizations"`
+	Invitations        []*Invitation         `json:"invitations"`
+	LoginFailures      *LoginFailures        `json:"login_failures"`
+	PasswordChanges    []pgtype.Timestamptz  `json:"password_changes"`
+	MagicLinkRequests  []pgtype.Timestamptz  `json:"magic_link_requests"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// Scopes an API key can be granted. Session tokens from Login carry all of them.
+const (
+	ScopeProfileRead     = "profile:read"
+	ScopeProfileWrite    = "profile:write"
+	ScopeTokensRead      = "tokens:read"
+	ScopeTokensWrite     = "tokens:write"
+	ScopeAccountSecurity = "account:security"
+	ScopeOrgsRead        = "orgs:read"
+	ScopeOrgsWrite       = "orgs:write"
+	// ScopeAdmin lets a key use its user's RBAC permissions on the admin routes
+	ScopeAdmin = "admin"
+)
+
+// APIKey is a long-lived personal access token. Prefix is the start of the key,
+// kept so users can recognise their keys; the key itself is only stored hashed.
+type APIKey struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	UserEmail  string             `json:"-"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	KeyHash    string             `json:"-"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=255"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=profile:read profile:write tokens:read tokens:write account:security orgs:read orgs:write admin"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+// CreateAPIKeyResponse is the only time the plaintext key is returned
+type CreateAPIKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
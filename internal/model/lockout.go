@@ -0,0 +1,11 @@
+package model
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// LoginFailures tracks consecutive failed password logins of one account
+type LoginFailures struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	FailedAttempts int                `json:"failed_attempts"`
+	LastFailedAt   pgtype.Timestamptz `json:"last_failed_at"`
+	LockedUntil    pgtype.Timestamptz `json:"locked_until"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// LoginChallenge is a proof-of-work puzzle handed to sources that look like
+// credential stuffing. The client must find a Solution such that
+// SHA-256(Challenge + ":" + Solution) starts with Difficulty zero bits and send
+// both back in the X-Login-Challenge and X-Login-Challenge-Solution headers.
+type LoginChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
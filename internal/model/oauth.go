@@ -0,0 +1,170 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// OAuth 2.0 grant types
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthClient is an application registered to use this service as its identity
+// provider. Public clients (SPAs, native apps) have no secret and rely on PKCE.
+// Machine clients use the client_credentials grant and act on their own behalf;
+// their scopes are RBAC permissions rather than OpenID Connect scopes. Resource
+// servers are the APIs that accept our tokens; they use no grant and may only
+// introspect.
+type OAuthClient struct {
+	ID             pgtype.UUID        `json:"id"`
+	ClientID       string             `json:"client_id"`
+	SecretHash     string             `json:"-"`
+	Name           string             `json:"name"`
+	RedirectURIs   []string           `json:"redirect_uris"`
+	Scopes         []string           `json:"scopes"`
+	GrantTypes     []string           `json:"grant_types"`
+	ResourceServer bool               `json:"resource_server"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (c *OAuthClient) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+type AuthorizationCode struct {
+	ID                  pgtype.UUID
+	CodeHash            string
+	ClientID            string
+	UserID              pgtype.UUID
+	RedirectURI         string
+	Scopes              []string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	AuthTime            pgtype.Timestamptz
+	ExpiresAt           pgtype.Timestamptz
+}
+
+// OAuthGrant is an authorization code a user issued to a client, minus the
+// code and its PKCE challenge
+type OAuthGrant struct {
+	ClientID    string             `json:"client_id"`
+	RedirectURI string             `json:"redirect_uri"`
+	Scopes      []string           `json:"scopes"`
+	AuthTime    pgtype.Timestamptz `json:"auth_time"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	UsedAt      pgtype.Timestamptz `json:"used_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+// AuthorizeRequest carries the parameters of an OAuth 2.0 authorization request.
+// The browser sends them as a query string; the consent page posts them back as JSON.
+type AuthorizeRequest struct {
+	ResponseType        string `query:"response_type" json:"response_type"`
+	ClientID            string `query:"client_id" json:"client_id"`
+	RedirectURI         string `query:"redirect_uri" json:"redirect_uri"`
+	Scope               string `query:"scope" json:"scope"`
+	State               string `query:"state" json:"state"`
+	Nonce               string `query:"nonce" json:"nonce"`
+	CodeChallenge       string `query:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// AuthorizeDecisionRequest is posted by the consent page once the user is logged in
+type AuthorizeDecisionRequest struct {
+	AuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+type AuthorizeDecisionResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// TokenRequest is the form-encoded body of the token endpoint (RFC 6749 section 4.1.3)
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Scope        string `form:"scope"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// TokenIntrospectionRequest is the form-encoded body of the introspection (RFC 7662)
+// and revocation (RFC 7009) endpoints
+type TokenIntrospectionRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// IntrospectionResponse describes a token as defined by RFC 7662 section 2.2.
+// Inactive tokens are reported with Active alone.
+type IntrospectionResponse struct {
+	Active    bool                `json:"active"`
+	Scope     string              `json:"scope,omitempty"`
+	ClientID  string              `json:"client_id,omitempty"`
+	Username  string              `json:"username,omitempty"`
+	TokenType string              `json:"token_type,omitempty"`
+	ExpiresAt int64               `json:"exp,omitempty"`
+	IssuedAt  int64               `json:"iat,omitempty"`
+	Subject   string              `json:"sub,omitempty"`
+	Issuer    string              `json:"iss,omitempty"`
+	TokenID   string              `json:"jti,omitempty"`
+	Actor     *IntrospectionActor `json:"act,omitempty"`
+}
+
+// IntrospectionActor is the admin behind an impersonation token
+type IntrospectionActor struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+type UserInfoResponse struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+}
+
+// OpenIDConfiguration is the OpenID Connect discovery document
+type OpenIDConfiguration struct {
+	Issuer                                    string   `json:"issuer"`
+	AuthorizationEndpoint                     string   `json:"authorization_endpoint"`
+	TokenEndpoint                             string   `json:"token_endpoint"`
+	UserInfoEndpoint                          string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint                     string   `json:"introspection_endpoint"`
+	RevocationEndpoint                        string   `json:"revocation_endpoint"`
+	JWKSURI                                   string   `json:"jwks_uri"`
+	ResponseTypesSupported                    []string `json:"response_types_supported"`
+	GrantTypesSupported                       []string `json:"grant_types_supported"`
+	SubjectTypesSupported                     []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported          []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                           []string `json:"scopes_supported"`
+	ClaimsSupported                           []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported         []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported             []string `json:"code_challenge_methods_supported"`
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported"`
+	RevocationEndpointAuthMethodsSupported    []string `json:"revocation_endpoint_auth_methods_supported"`
+}
@@ -0,0 +1,70 @@
+package model
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// Roles a user can hold within an organization, from most to least privileged
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Organization is a tenant. Role is the caller's role when listing their organizations.
+type Organization struct {
+	ID        pgtype.UUID        `json:"id"`
+	Name      string             `json:"name"`
+	Role      string             `json:"role,omitempty"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Membership struct {
+	OrgID     pgtype.UUID        `json:"org_id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Email     string             `json:"email"`
+	Name      string             `json:"name"`
+	Role      string             `json:"role"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// Invitation is an emailed offer to join an organization. Only the hash of its
+// token is stored.
+type Invitation struct {
+	ID         pgtype.UUID        `json:"id"`
+	OrgID      pgtype.UUID        `json:"org_id"`
+	Email      string             `json:"email"`
+	Role       string             `json:"role"`
+	InvitedBy  pgtype.UUID        `json:"invited_by"`
+	TokenHash  string             `json:"-"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	AcceptedAt pgtype.Timestamptz `json:"accepted_at"`
+	DeclinedAt pgtype.Timestamptz `json:"declined_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type InvitationTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// SwitchOrganizationRequest selects the organization carried in new access
+// tokens. An empty OrgID clears it.
+type SwitchOrganizationRequest struct {
+	OrgID string `json:"org_id" validate:"omitempty,uuid"`
+}
@@ -0,0 +1,21 @@
+package model
+
+// Rules a new password is checked against
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleMaxLength = "max_length"
+	PasswordRuleUppercase = "uppercase"
+	PasswordRuleLowercase = "lowercase"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleUserInfo  = "user_info"
+	PasswordRuleBreached  = "breached"
+	PasswordRuleHistory   = "history"
+)
+
+// PasswordPolicyViolation is returned as validation error details, one per failed rule
+type PasswordPolicyViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
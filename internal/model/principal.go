@@ -0,0 +1,9 @@
+package model
+
+// Kinds of authenticated caller. AuthMiddleware stores one under "principal".
+// Users come with a "userID"; service principals (machine clients using the
+// client_credentials grant) with a "clientID" and their granted "scopes".
+const (
+	PrincipalUser    = "user"
+	PrincipalService = "service"
+)
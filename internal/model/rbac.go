@@ -0,0 +1,35 @@
+package model
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// Built-in role and permissions seeded by the rbac migration
+const (
+	RoleAdmin = "admin"
+
+	PermissionUsersRead   = "users:read"
+	PermissionUsersManage = "users:manage"
+	PermissionRolesManage = "roles:manage"
+
+	PermissionUsersImpersonate = "users:impersonate"
+)
+
+type Role struct {
+	ID          pgtype.UUID        `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Permissions []string           `json:"permissions"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type UserRolesResponse struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Roles  []string    `json:"roles"`
+}
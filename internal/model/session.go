@@ -0,0 +1,27 @@
+package model
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// Session is one login on one device. Its ID is also the family ID of the
+// refresh tokens it rotates through and the "sid" claim of its access tokens.
+// Cookie sessions have no tokens; the browser holds a secret whose hash is
+// CookieHash, and the session ends at ExpiresAt. Sessions started for an OAuth
+// client carry its ClientID and the Scopes the user granted it.
+type Session struct {
+	ID            pgtype.UUID        `json:"id"`
+	UserID        pgtype.UUID        `json:"-"`
+	UserEmail     string             `json:"-"`
+	TokenID       pgtype.Text        `json:"-"`
+	OrgID         pgtype.UUID        `json:"org_id"`
+	UserAgent     string             `json:"user_agent"`
+	IPAddress     string             `json:"ip_address"`
+	CookieHash    pgtype.Text        `json:"-"`
+	CSRFTokenHash pgtype.Text        `json:"-"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	LastSeenAt    pgtype.Timestamptz `json:"last_seen_at"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt     pgtype.Timestamptz `json:"-"`
+	ClientID      pgtype.Text        `json:"client_id"`
+	Scopes        []string           `json:"scopes,omitempty"`
+	Current       bool               `json:"current"`
+}
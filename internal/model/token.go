@@ -0,0 +1,47 @@
+package model
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// Purposes of single-use tokens stored in user_tokens
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeMagicLink         = "magic_link"
+	TokenPurposeEmailChange       = "email_change"
+	TokenPurposeEmailChangeUndo   = "email_change_undo"
+	TokenPurposeMFAChallenge      = "mfa_challenge"
+)
+
+type RefreshToken struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	FamilyID  pgtype.UUID        `json:"family_id"`
+	TokenHash string             `json:"-"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// UserToken describes a single-use token sent by email without revealing it
+type UserToken struct {
+	Purpose    string             `json:"purpose"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	ConsumedAt pgtype.Timestamptz `json:"consumed_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+// RevokedToken is an access token that was revoked before it expired
+type RevokedToken struct {
+	TokenID   string             `json:"jti"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -3,17 +3,25 @@ package model
 import "github.com/jackc/pgx/v5/pgtype"
 
 type User struct {
-	ID        pgtype.UUID        `json:"id"`
-	Email     string             `json:"email"`
-	Password  string             `json:"-"`
-	Name      string             `json:"name"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID                pgtype.UUID        `json:"id"`
+	Email             string             `json:"email"`
+	Password          string             `json:"-"`
+	Name              string             `json:"name"`
+	TokenVersion      int                `json:"-"`
+	EmailVerifiedAt   pgtype.Timestamptz `json:"email_verified_at"`
+	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+	// Set while a deleted account waits out its grace period
+	DeletedAt pgtype.Timestamptz `json:"-"`
+	PurgeAt   pgtype.Timestamptz `json:"-"`
+	// PendingEmail awaits confirmation from its owner before replacing Email
+	PendingEmail pgtype.Text `json:"pending_email"`
 }
 
 type CreateUserRequest struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required,max=256"`
 	Name     string `json:"name" validate:"required"`
 }
 
@@ -22,20 +30,63 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// UpdateUserRequest changes the profile. The email address is changed with
+// ChangeEmailRequest, which has to be confirmed.
 type UpdateUserRequest struct {
-	Name  string `json:"name" validate:"required"`
-	Email string `json:"email" validate:"required,email"`
+	Name string `json:"name" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
 }
 
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required,min=6"`
+	NewPassword string `json:"new_password" validate:"required,max=256"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,max=256"`
 }
 
 type AuthResponse struct {
-	User      *User  `json:"user"`
-	Token     string `json:"token"`
-	ExpiresAt int64  `json:"expires_at"`
+	User                      *User  `json:"user"`
+	Token                     string `json:"token,omitempty"`
+	ExpiresAt                 int64  `json:"expires_at,omitempty"`
+	RefreshToken              string `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt     int64  `json:"refresh_token_expires_at,omitempty"`
+	EmailVerificationRequired bool   `json:"email_verification_required,omitempty"`
+	// Set instead of the tokens when a cookie session was requested. The
+	// secret only ever travels in the HttpOnly cookie.
+	SessionSecret string `json:"-"`
+	CSRFToken     string `json:"csrf_token,omitempty"`
+	// Scopes granted to the OAuth client the session was started for
+	Scopes []string `json:"-"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
 }
 
 type HealthResponse struct {
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type WebAuthnCredential struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"-"`
+	CredentialID    []byte             `json:"-"`
+	PublicKey       []byte             `json:"-"`
+	AttestationType string             `json:"attestation_type"`
+	Transports      []string           `json:"transports"`
+	AAGUID          []byte             `json:"-"`
+	SignCount       int64              `json:"-"`
+	CloneWarning    bool               `json:"clone_warning"`
+	UserVerified    bool               `json:"user_verified"`
+	BackupEligible  bool               `json:"backup_eligible"`
+	BackupState     bool               `json:"backup_state"`
+	Name            string             `json:"name"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	LastUsedAt      pgtype.Timestamptz `json:"last_used_at"`
+}
+
+// WebAuthnCeremony is a registration or login started with a begin call
+type WebAuthnCeremony struct {
+	ID        pgtype.UUID        `json:"id"`
+	Ceremony  string             `json:"ceremony"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// WebAuthnBeginResponse carries the options for navigator.credentials.create/get
+// and the session the finish call must refer to
+type WebAuthnBeginResponse struct {
+	SessionID string `json:"session_id"`
+	Options   any    `json:"options"`
+}
+
+type WebAuthnBeginLoginRequest struct {
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+type WebAuthnFinishRequest struct {
+	SessionID  string          `json:"session_id" validate:"required,uuid"`
+	Name       string          `json:"name" validate:"max=255"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
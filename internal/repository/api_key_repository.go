@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]*model.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	TouchAPIKey(ctx context.Context, id pgtype.UUID) error
+	DeleteAPIKey(ctx context.Context, userID, id pgtype.UUID) error
+}
+
+const apiKeyColumns = `k.id, k.user_id, u.email, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at, k.last_used_at, k.created_at`
+
+// scanAPIKey reads a row selected with apiKeyColumns from api_keys k JOIN users u
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var key model.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.UserEmail,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// APIKeyRepositoryImpl implements APIKeyRepository
+type APIKeyRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewAPIKeyRepository(db *database.DB, logger *zap.Logger) *APIKeyRepositoryImpl {
+	return &APIKeyRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *APIKeyRepositoryImpl) CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.APIKey, error) {
+	query := `
+		WITH k AS (
+			INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *
+		)
+		SELECT ` + apiKeyColumns + ` FROM k JOIN users u ON u.id = k.user_id`
+
+	created, err := scanAPIKey(r.db.Pool.QueryRow(ctx, query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.Scopes,
+		key.ExpiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating api key: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *APIKeyRepositoryImpl) ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]*model.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.user_id = $1
+		ORDER BY k.created_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByHash only returns keys that have not expired
+func (r *APIKeyRepositoryImpl) GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.expires_at > NOW() AND u.deleted_at IS NULL
+	`
+
+	key, err := scanAPIKey(r.db.Pool.QueryRow(ctx, query, keyHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrInvalidAPIKey)
+		}
+		return nil, fmt.Errorf("error getting api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// TouchAPIKey records usage, at most once a minute per key to spare busy clients a write per request
+func (r *APIKeyRepositoryImpl) TouchAPIKey(ctx context.Context, id pgtype.UUID) error {
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+	if _, err := r.db.Pool.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("error updating api key usage: %w", err)
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepositoryImpl) DeleteAPIKey(ctx context.Context, userID, id pgtype.UUID) error {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.New(constants.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
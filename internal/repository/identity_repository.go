@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+// IdentityRepository stores links between users and external identity providers
+type IdentityRepository interface {
+	GetIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	ListIdentities(ctx context.Context, userID pgtype.UUID) ([]*model.UserIdentity, error)
+	CreateIdentity(ctx context.Context, userID pgtype.UUID, provider, subject, email string) (*model.UserIdentity, error)
+	TouchIdentity(ctx context.Context, id pgtype.UUID, email string) error
+}
+
+const identityColumns = `id, user_id, provider, subject, email, created_at, last_login_at`
+
+// scanIdentity reads a row selected with identityColumns
+func scanIdentity(row pgx.Row) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := row.Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// IdentityRepositoryImpl implements IdentityRepository
+type IdentityRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewIdentityRepository(db *database.DB, logger *zap.Logger) *IdentityRepositoryImpl {
+	return &IdentityRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *IdentityRepositoryImpl) GetIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	identity, err := scanIdentity(r.db.Pool.QueryRow(ctx, query, provider, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrIdentityNotFound)
+		}
+		return nil, fmt.Errorf("error getting identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+func (r *IdentityRepositoryImpl) ListIdentities(ctx context.Context, userID pgtype.UUID) ([]*model.UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []*model.UserIdentity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+func (r *IdentityRepositoryImpl) CreateIdentity(ctx context.Context, userID pgtype.UUID, provider, subject, email string) (*model.UserIdentity, error) {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NOW())
+		RETURNING ` + identityColumns
+
+	identity, err := scanIdentity(r.db.Pool.QueryRow(ctx, query, userID, provider, subject, email))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, errors.New(constants.ErrIdentityExists)
+		}
+		return nil, fmt.Errorf("error creating identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// TouchIdentity records a login and refreshes the email the provider reported
+func (r *IdentityRepositoryImpl) TouchIdentity(ctx context.Context, id pgtype.UUID, email string) error {
+	query := `UPDATE user_identities SET last_login_at = NOW(), email = COALESCE(NULLIF($2, ''), email) WHERE id = $1`
+	if _, err := r.db.Pool.Exec(ctx, query, id, email); err != nil {
+		return fmt.Errorf("error updating identity: %w", err)
+	}
+
+	return nil
+}
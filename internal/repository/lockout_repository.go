@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type LockoutRepository interface {
+	GetLoginFailures(ctx context.Context, userID pgtype.UUID) (*model.LoginFailures, error)
+	RecordLoginFailure(ctx context.Context, userID pgtype.UUID, window time.Duration) (*model.LoginFailures, error)
+	LockAccount(ctx context.Context, userID pgtype.UUID, until time.Time) error
+	ResetLoginFailures(ctx context.Context, userID pgtype.UUID) error
+}
+
+// LockoutRepositoryImpl implements LockoutRepository
+type LockoutRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewLockoutRepository(db *database.DB, logger *zap.Logger) *LockoutRepositoryImpl {
+	return &LockoutRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetLoginFailures returns a zero record for accounts without failures
+func (r *LockoutRepositoryImpl) GetLoginFailures(ctx context.Context, userID pgtype.UUID) (*model.LoginFailures, error) {
+	query := `SELECT user_id, failed_attempts, last_failed_at, locked_until FROM login_failures WHERE user_id = $1`
+
+	failures := model.LoginFailures{UserID: userID}
+	err := r.db.Pool.QueryRow(ctx, query, userID).Scan(
+		&failures.UserID,
+		&failures.FailedAttempts,
+		&failures.LastFailedAt,
+		&failures.LockedUntil,
+	)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("error getting login failures: %w", err)
+	}
+
+	return &failures, nil
+}
+
+// RecordLoginFailure increments the counter in one statement so concurrent
+// attempts on different replicas are all counted. The count starts over once a
+// lock has expired or the previous failure is older than window.
+func (r *LockoutRepositoryImpl) RecordLoginFailure(ctx context.Context, userID pgtype.UUID, window time.Duration) (*model.LoginFailures, error) {
+	query := `
+		INSERT INTO login_failures (user_id, failed_attempts, last_failed_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			failed_attempts = CASE
+				WHEN login_failures.locked_until <= NOW() OR login_failures.last_failed_at < NOW() - $2::interval THEN 1
+				ELSE login_failures.failed_attempts + 1
+			END,
+			locked_until = CASE
+				WHEN login_failures.locked_until <= NOW() THEN NULL
+				ELSE login_failures.locked_until
+			END,
+			last_failed_at = NOW()
+		RETURNING user_id, failed_attempts, last_failed_at, locked_until
+	`
+
+	var failures model.LoginFailures
+	err := r.db.Pool.QueryRow(ctx, query, userID, window).Scan(
+		&failures.UserID,
+		&failures.FailedAttempts,
+		&failures.LastFailedAt,
+		&failures.LockedUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error recording login failure: %w", err)
+	}
+
+	return &failures, nil
+}
+
+func (r *LockoutRepositoryImpl) LockAccount(ctx context.Context, userID pgtype.UUID, until time.Time) error {
+	query := `UPDATE login_failures SET locked_until = $2 WHERE user_id = $1`
+	if _, err := r.db.Pool.Exec(ctx, query, userID, until); err != nil {
+		return fmt.Errorf("error locking account: %w", err)
+	}
+
+	return nil
+}
+
+// ResetLoginFailures clears the counter and any lock
+func (r *LockoutRepositoryImpl) ResetLoginFailures(ctx context.Context, userID pgtype.UUID) error {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM login_failures WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error resetting login failures: %w", err)
+	}
+
+	return nil
+}
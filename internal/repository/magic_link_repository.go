@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"go.uber.org/zap"
+)
+
+type MagicLinkRepository interface {
+	RecordMagicLinkRequest(ctx context.Context, email string, window time.Duration) (int, error)
+	ListMagicLinkRequests(ctx context.Context, email string) ([]pgtype.Timestamptz, error)
+	DeleteMagicLinkRequests(ctx context.Context, olderThan time.Duration) (int, error)
+}
+
+// MagicLinkRepositoryImpl implements MagicLinkRepository
+type MagicLinkRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewMagicLinkRepository(db *database.DB, logger *zap.Logger) *MagicLinkRepositoryImpl {
+	return &MagicLinkRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// RecordMagicLinkRequest stores a request and returns how many requests the email
+// has made within window, this one included. Older rows for the email are dropped.
+func (r *MagicLinkRepositoryImpl) RecordMagicLinkRequest(ctx context.Context, email string, window time.Duration) (int, error) {
+	query := `
+		WITH pruned AS (
+			DELETE FROM magic_link_requests WHERE email = $1 AND requested_at <= NOW() - $2::interval
+		), inserted AS (
+			INSERT INTO magic_link_requests (email) VALUES ($1)
+		)
+		SELECT COUNT(*) + 1 FROM magic_link_requests WHERE email = $1 AND requested_at > NOW() - $2::interval
+	`
+
+	var count int
+	if err := r.db.Pool.QueryRow(ctx, query, email, window).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error recording magic link request: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *MagicLinkRepositoryImpl) ListMagicLinkRequests(ctx context.Context, email string) ([]pgtype.Timestamptz, error) {
+	query := `SELECT requested_at FROM magic_link_requests WHERE email = $1 ORDER BY requested_at DESC`
+
+	rows, err := r.db.Pool.Query(ctx, query, email)
+	if err != nil {
+		return nil, fmt.Errorf("error listing magic link requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []pgtype.Timestamptz{}
+	for rows.Next() {
+		var requestedAt pgtype.Timestamptz
+		if err := rows.Scan(&requestedAt); err != nil {
+			return nil, fmt.Errorf("error scanning magic link request: %w", err)
+		}
+		requests = append(requests, requestedAt)
+	}
+
+	return requests, rows.Err()
+}
+
+// DeleteMagicLinkRequests drops requests older than olderThan for every email,
+// including ones that never ask again and so are not pruned on record
+func (r *MagicLinkRepositoryImpl) DeleteMagicLinkRequests(ctx context.Context, olderThan time.Duration) (int, error) {
+	query := `DELETE FROM magic_link_requests WHERE requested_at <= NOW() - $1::interval`
+	result, err := r.db.Pool.Exec(ctx, query, olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting magic link requests: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
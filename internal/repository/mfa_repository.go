@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type MFARepository interface {
+	UpsertMFASecret(ctx context.Context, userID pgtype.UUID, secretEncrypted string) error
+	GetMFA(ctx context.Context, userID pgtype.UUID) (*model.UserMFA, error)
+	EnableMFA(ctx context.Context, userID pgtype.UUID, recoveryCodeHashes []string) error
+	DeleteMFA(ctx context.Context, userID pgtype.UUID) error
+	UseTOTPStep(ctx context.Context, userID pgtype.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID pgtype.UUID, codeHash string) (bool, error)
+	ListRecoveryCodes(ctx context.Context, userID pgtype.UUID) ([]*model.RecoveryCode, error)
+}
+
+// MFARepositoryImpl implements MFARepository
+type MFARepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewMFARepository(db *database.DB, logger *zap.Logger) *MFARepositoryImpl {
+	return &MFARepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// UpsertMFASecret stores a pending (not yet enabled) secret, replacing any earlier pending one
+func (r *MFARepositoryImpl) UpsertMFASecret(ctx context.Context, userID pgtype.UUID, secretEncrypted string) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret_encrypted)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0
+		WHERE user_mfa.enabled_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, userID, secretEncrypted)
+	if err != nil {
+		return fmt.Errorf("error storing mfa secret: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrMFAAlreadyEnabled)
+	}
+
+	return nil
+}
+
+func (r *MFARepositoryImpl) GetMFA(ctx context.Context, userID pgtype.UUID) (*model.UserMFA, error) {
+	query := `
+		SELECT user_id, secret_encrypted, enabled_at, last_used_step, created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	var mfa model.UserMFA
+	err := r.db.Pool.QueryRow(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.SecretEncrypted,
+		&mfa.EnabledAt,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+		&mfa.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrMFANotEnrolled)
+		}
+		return nil, fmt.Errorf("error getting mfa: %w", err)
+	}
+
+	return &mfa, nil
+}
+
+// EnableMFA activates the pending secret and replaces the recovery codes in one transaction
+func (r *MFARepositoryImpl) EnableMFA(ctx context.Context, userID pgtype.UUID, recoveryCodeHashes []string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE user_mfa SET enabled_at = NOW() WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error enabling mfa: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("error storing recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	r.logger.Info("MFA enabled", zap.String("user_id", userID.String()))
+	return nil
+}
+
+func (r *MFARepositoryImpl) DeleteMFA(ctx context.Context, userID pgtype.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error deleting mfa: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	r.logger.Info("MFA disabled", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code. It reports false when the
+// step (or a later one) was already used, so every code works at most once.
+func (r *MFARepositoryImpl) UseTOTPStep(ctx context.Context, userID pgtype.UUID, step int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	result, err := r.db.Pool.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("error recording totp step: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *MFARepositoryImpl) UseRecoveryCode(ctx context.Context, userID pgtype.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("error using recovery code: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *MFARepositoryImpl) ListRecoveryCodes(ctx context.Context, userID pgtype.UUID) ([]*model.RecoveryCode, error) {
+	query := `SELECT used_at, created_at FROM mfa_recovery_codes WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing recovery codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []*model.RecoveryCode{}
+	for rows.Next() {
+		var code model.RecoveryCode
+		if err := rows.Scan(&code.UsedAt, &code.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning recovery code: %w", err)
+		}
+		codes = append(codes, &code)
+	}
+
+	return codes, rows.Err()
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+// OAuthRepository stores registered OAuth clients and pending authorization codes
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *model.OAuthClient) (*model.OAuthClient, error)
+	GetClientByClientID(ctx context.Context, clientID string) (*model.OAuthClient, error)
+	GetClientTokenCutoff(ctx context.Context, clientID string) (pgtype.Timestamptz, error)
+	DisableClient(ctx context.Context, clientID string) error
+	RotateClientSecret(ctx context.Context, clientID, secretHash string) error
+	CreateAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error)
+	ListAuthorizationGrants(ctx context.Context, userID pgtype.UUID) ([]*model.OAuthGrant, error)
+}
+
+const oauthClientColumns = "id, client_id, COALESCE(client_secret_hash, ''), name, redirect_uris, scopes, grant_types, resource_server, created_at, updated_at"
+
+// scanOAuthClient reads a row selected with oauthClientColumns
+func scanOAuthClient(row pgx.Row) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := row.Scan(
+		&client.ID,
+		&client.ClientID,
+		&client.SecretHash,
+		&client.Name,
+		&client.RedirectURIs,
+		&client.Scopes,
+		&client.GrantTypes,
+		&client.ResourceServer,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// OAuthRepositoryImpl implements OAuthRepository
+type OAuthRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewOAuthRepository(db *database.DB, logger *zap.Logger) *OAuthRepositoryImpl {
+	return &OAuthRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *OAuthRepositoryImpl) CreateClient(ctx context.Context, client *model.OAuthClient) (*model.OAuthClient, error) {
+	query := `
+		INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, scopes, grant_types, resource_server)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)
+		RETURNING ` + oauthClientColumns
+
+	created, err := scanOAuthClient(r.db.Pool.QueryRow(ctx, query,
+		client.ClientID,
+		client.SecretHash,
+		client.Name,
+		client.RedirectURIs,
+		client.Scopes,
+		client.GrantTypes,
+		client.ResourceServer,
+	))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, errors.New(constants.ErrOAuthClientExists)
+		}
+		return nil, fmt.Errorf("error creating oauth client: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetClientByClientID returns the client unless it was disabled
+func (r *OAuthRepositoryImpl) GetClientByClientID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE client_id = $1 AND disabled_at IS NULL`
+
+	client, err := scanOAuthClient(r.db.Pool.QueryRow(ctx, query, clientID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrOAuthClientNotFound)
+		}
+		return nil, fmt.Errorf("error getting oauth client: %w", err)
+	}
+
+	return client, nil
+}
+
+// GetClientTokenCutoff returns when the client's tokens were last invalidated,
+// unset if they never were. Disabled clients report the time they were disabled.
+func (r *OAuthRepositoryImpl) GetClientTokenCutoff(ctx context.Context, clientID string) (pgtype.Timestamptz, error) {
+	query := `SELECT GREATEST(disabled_at, tokens_revoked_at) FROM oauth_clients WHERE client_id = $1`
+
+	var cutoff pgtype.Timestamptz
+	if err := r.db.Pool.QueryRow(ctx, query, clientID).Scan(&cutoff); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return cutoff, errors.New(constants.ErrOAuthClientNotFound)
+		}
+		return cutoff, fmt.Errorf("error getting oauth client: %w", err)
+	}
+
+	return cutoff, nil
+}
+
+// DisableClient stops the client from authenticating and invalidates its tokens
+func (r *OAuthRepositoryImpl) DisableClient(ctx context.Context, clientID string) error {
+	query := `
+		UPDATE oauth_clients
+		SET disabled_at = NOW(), tokens_revoked_at = NOW(), updated_at = NOW()
+		WHERE client_id = $1 AND disabled_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, clientID)
+	if err != nil {
+		return fmt.Errorf("error disabling oauth client: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrOAuthClientNotFound)
+	}
+
+	return nil
+}
+
+// RotateClientSecret replaces the secret of an enabled confidential client and
+// invalidates the tokens issued under the old one
+func (r *OAuthRepositoryImpl) RotateClientSecret(ctx context.Context, clientID, secretHash string) error {
+	query := `
+		UPDATE oauth_clients
+		SET client_secret_hash = $2, tokens_revoked_at = NOW(), updated_at = NOW()
+		WHERE client_id = $1 AND disabled_at IS NULL AND client_secret_hash IS NOT NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, clientID, secretHash)
+	if err != nil {
+		return fmt.Errorf("error rotating oauth client secret: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrOAuthClientNotFound)
+	}
+
+	return nil
+}
+
+// CreateAuthorizationCode stores a new code and clears out expired ones
+func (r *OAuthRepositoryImpl) CreateAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM oauth_authorization_codes WHERE expires_at < NOW()`); err != nil {
+		r.logger.Warn("Failed to delete expired authorization codes", zap.Error(err))
+	}
+
+	query := `
+		INSERT INTO oauth_authorization_codes
+			(code_hash, client_id, user_id, redirect_uri, scopes, nonce, code_challenge, code_challenge_method, auth_time, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.Scopes,
+		code.Nonce,
+		code.CodeChallenge,
+		code.CodeChallengeMethod,
+		code.AuthTime,
+		code.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating authorization code: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode atomically marks an unexpired code as used and returns it
+func (r *OAuthRepositoryImpl) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*model.AuthorizationCode, error) {
+	query := `
+		UPDATE oauth_authorization_codes
+		SET used_at = NOW()
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, code_hash, client_id, user_id, redirect_uri, scopes, nonce,
+			code_challenge, code_challenge_method, auth_time, expires_at
+	`
+
+	var code model.AuthorizationCode
+	err := r.db.Pool.QueryRow(ctx, query, codeHash).Scan(
+		&code.ID,
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.Scopes,
+		&code.Nonce,
+		&code.CodeChallenge,
+		&code.CodeChallengeMethod,
+		&code.AuthTime,
+		&code.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrInvalidAuthorizationCode)
+		}
+		return nil, fmt.Errorf("error consuming authorization code: %w", err)
+	}
+
+	return &code, nil
+}
+
+func (r *OAuthRepositoryImpl) ListAuthorizationGrants(ctx context.Context, userID pgtype.UUID) ([]*model.OAuthGrant, error) {
+	query := `
+		SELECT client_id, redirect_uri, scopes, auth_time, expires_at, used_at, created_at
+		FROM oauth_authorization_codes
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing authorization grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := []*model.OAuthGrant{}
+	for rows.Next() {
+		var grant model.OAuthGrant
+		err := rows.Scan(
+			&grant.ClientID,
+			&grant.RedirectURI,
+			&grant.Scopes,
+			&grant.AuthTime,
+			&grant.ExpiresAt,
+			&grant.UsedAt,
+			&grant.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning authorization grant: %w", err)
+		}
+		grants = append(grants, &grant)
+	}
+
+	return grants, rows.Err()
+}
@@ -0,0 +1,461 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, name string, ownerID pgtype.UUID) (*model.Organization, error)
+	GetOrganization(ctx context.Context, id pgtype.UUID) (*model.Organization, error)
+	ListUserOrganizations(ctx context.Context, userID pgtype.UUID) ([]*model.Organization, error)
+	UpdateOrganization(ctx context.Context, id pgtype.UUID, name string) (*model.Organization, error)
+	DeleteOrganization(ctx context.Context, id pgtype.UUID) error
+
+	GetMembership(ctx context.Context, orgID, userID pgtype.UUID) (*model.Membership, error)
+	ListMembers(ctx context.Context, orgID pgtype.UUID) ([]*model.Membership, error)
+	UpdateMemberRole(ctx context.Context, orgID, userID pgtype.UUID, role string) (bool, error)
+	RemoveMember(ctx context.Context, orgID, userID pgtype.UUID) (bool, error)
+
+	CreateInvitation(ctx context.Context, invitation *model.Invitation) (*model.Invitation, error)
+	GetInvitationByHash(ctx context.Context, tokenHash string) (*model.Invitation, error)
+	ListPendingInvitations(ctx context.Context, orgID pgtype.UUID) ([]*model.Invitation, error)
+	ListUserInvitations(ctx context.Context, userID pgtype.UUID, email string) ([]*model.Invitation, error)
+	DeleteInvitation(ctx context.Context, orgID, id pgtype.UUID) error
+	AcceptInvitation(ctx context.Context, invitation *model.Invitation, userID pgtype.UUID) error
+	DeclineInvitation(ctx context.Context, id pgtype.UUID) error
+}
+
+const organizationColumns = `id, name, created_at, updated_at`
+
+// scanOrganization reads a row selected with organizationColumns
+func scanOrganization(row pgx.Row) (*model.Organization, error) {
+	var org model.Organization
+	err := row.Scan(
+		&org.ID,
+		&org.Name,
+		&org.CreatedAt,
+		&org.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+const membershipColumns = `m.org_id, m.user_id, u.email, u.name, m.role, m.created_at`
+
+// scanMembership reads a row selected with membershipColumns from organization_members m JOIN users u
+func scanMembership(row pgx.Row) (*model.Membership, error) {
+	var membership model.Membership
+	err := row.Scan(
+		&membership.OrgID,
+		&membership.UserID,
+		&membership.Email,
+		&membership.Name,
+		&membership.Role,
+		&membership.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+const invitationColumns = `id, org_id, email, role, invited_by, token_hash, expires_at, accepted_at, declined_at, created_at`
+
+// scanInvitation reads a row selected with invitationColumns
+func scanInvitation(row pgx.Row) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.OrgID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.TokenHash,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+		&invitation.DeclinedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// OrganizationRepositoryImpl implements OrganizationRepository
+type OrganizationRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewOrganizationRepository(db *database.DB, logger *zap.Logger) *OrganizationRepositoryImpl {
+	return &OrganizationRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateOrganization creates the organization with ownerID as its first owner
+func (r *OrganizationRepositoryImpl) CreateOrganization(ctx context.Context, name string, ownerID pgtype.UUID) (*model.Organization, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO organizations (name) VALUES ($1) RETURNING ` + organizationColumns
+	org, err := scanOrganization(tx.QueryRow(ctx, query, name))
+	if err != nil {
+		return nil, fmt.Errorf("error creating organization: %w", err)
+	}
+
+	query = `INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, org.ID, ownerID, model.OrgRoleOwner); err != nil {
+		return nil, fmt.Errorf("error adding organization owner: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	org.Role = model.OrgRoleOwner
+	return org, nil
+}
+
+func (r *OrganizationRepositoryImpl) GetOrganization(ctx context.Context, id pgtype.UUID) (*model.Organization, error) {
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE id = $1`
+
+	org, err := scanOrganization(r.db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrOrganizationNotFound)
+		}
+		return nil, fmt.Errorf("error getting organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// ListUserOrganizations returns the organizations the user belongs to, with their role in each
+func (r *OrganizationRepositoryImpl) ListUserOrganizations(ctx context.Context, userID pgtype.UUID) ([]*model.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_at, o.updated_at, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.org_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []*model.Organization{}
+	for rows.Next() {
+		var org model.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt, &org.Role); err != nil {
+			return nil, fmt.Errorf("error scanning organization: %w", err)
+		}
+		orgs = append(orgs, &org)
+	}
+
+	return orgs, rows.Err()
+}
+
+func (r *OrganizationRepositoryImpl) UpdateOrganization(ctx context.Context, id pgtype.UUID, name string) (*model.Organization, error) {
+	query := `UPDATE organizations SET name = $2, updated_at = NOW() WHERE id = $1 RETURNING ` + organizationColumns
+
+	org, err := scanOrganization(r.db.Pool.QueryRow(ctx, query, id, name))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrOrganizationNotFound)
+		}
+		return nil, fmt.Errorf("error updating organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// DeleteOrganization also drops its memberships and invitations
+func (r *OrganizationRepositoryImpl) DeleteOrganization(ctx context.Context, id pgtype.UUID) error {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting organization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrOrganizationNotFound)
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepositoryImpl) GetMembership(ctx context.Context, orgID, userID pgtype.UUID) (*model.Membership, error) {
+	query := `
+		SELECT ` + membershipColumns + `
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND m.user_id = $2
+	`
+
+	membership, err := scanMembership(r.db.Pool.QueryRow(ctx, query, orgID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrMembershipNotFound)
+		}
+		return nil, fmt.Errorf("error getting membership: %w", err)
+	}
+
+	return membership, nil
+}
+
+func (r *OrganizationRepositoryImpl) ListMembers(ctx context.Context, orgID pgtype.UUID) ([]*model.Membership, error) {
+	query := `
+		SELECT ` + membershipColumns + `
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*model.Membership{}
+	for rows.Next() {
+		membership, err := scanMembership(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning membership: %w", err)
+		}
+		members = append(members, membership)
+	}
+
+	return members, rows.Err()
+}
+
+// UpdateMemberRole reports false when the change would leave the organization
+// without an owner
+func (r *OrganizationRepositoryImpl) UpdateMemberRole(ctx context.Context, orgID, userID pgtype.UUID, role string) (bool, error) {
+	query := `
+		UPDATE organization_members SET role = $3
+		WHERE org_id = $1 AND user_id = $2
+			AND (role <> 'owner' OR $3 = 'owner'
+				OR (SELECT COUNT(*) FROM organization_members WHERE org_id = $1 AND role = 'owner') > 1)
+	`
+	result, err := r.db.Pool.Exec(ctx, query, orgID, userID, role)
+	if err != nil {
+		return false, fmt.Errorf("error updating member role: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// RemoveMember reports false when the member is the organization's last owner
+func (r *OrganizationRepositoryImpl) RemoveMember(ctx context.Context, orgID, userID pgtype.UUID) (bool, error) {
+	query := `
+		DELETE FROM organization_members
+		WHERE org_id = $1 AND user_id = $2
+			AND (role <> 'owner'
+				OR (SELECT COUNT(*) FROM organization_members WHERE org_id = $1 AND role = 'owner') > 1)
+	`
+	result, err := r.db.Pool.Exec(ctx, query, orgID, userID)
+	if err != nil {
+		return false, fmt.Errorf("error removing member: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// CreateInvitation replaces any pending invitation for the same address
+func (r *OrganizationRepositoryImpl) CreateInvitation(ctx context.Context, invitation *model.Invitation) (*model.Invitation, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		DELETE FROM organization_invitations
+		WHERE org_id = $1 AND email = $2 AND accepted_at IS NULL AND declined_at IS NULL
+	`
+	if _, err := tx.Exec(ctx, query, invitation.OrgID, invitation.Email); err != nil {
+		return nil, fmt.Errorf("error replacing invitation: %w", err)
+	}
+
+	query = `
+		INSERT INTO organization_invitations (org_id, email, role, invited_by, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + invitationColumns
+	created, err := scanInvitation(tx.QueryRow(ctx, query,
+		invitation.OrgID,
+		invitation.Email,
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.TokenHash,
+		invitation.ExpiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating invitation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetInvitationByHash only finds invitations that are still pending and unexpired
+func (r *OrganizationRepositoryImpl) GetInvitationByHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE token_hash = $1 AND accepted_at IS NULL AND declined_at IS NULL AND expires_at > NOW()
+	`
+
+	invitation, err := scanInvitation(r.db.Pool.QueryRow(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrInvitationNotFound)
+		}
+		return nil, fmt.Errorf("error getting invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+func (r *OrganizationRepositoryImpl) ListPendingInvitations(ctx context.Context, orgID pgtype.UUID) ([]*model.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE org_id = $1 AND accepted_at IS NULL AND declined_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []*model.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// ListUserInvitations returns invitations sent to email or by the user, in any state
+func (r *OrganizationRepositoryImpl) ListUserInvitations(ctx context.Context, userID pgtype.UUID, email string) ([]*model.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE email = $1 OR invited_by = $2
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, email, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []*model.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+func (r *OrganizationRepositoryImpl) DeleteInvitation(ctx context.Context, orgID, id pgtype.UUID) error {
+	query := `DELETE FROM organization_invitations WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, id, orgID)
+	if err != nil {
+		return fmt.Errorf("error deleting invitation: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrInvitationNotFound)
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds the user with the invited role, unless they are already
+// a member, and marks the invitation used in the same transaction
+func (r *OrganizationRepositoryImpl) AcceptInvitation(ctx context.Context, invitation *model.Invitation, userID pgtype.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE organization_invitations SET accepted_at = NOW()
+		WHERE id = $1 AND accepted_at IS NULL AND declined_at IS NULL AND expires_at > NOW()
+	`
+	result, err := tx.Exec(ctx, query, invitation.ID)
+	if err != nil {
+		return fmt.Errorf("error accepting invitation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrInvitationNotFound)
+	}
+
+	query = `
+		INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, user_id) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, query, invitation.OrgID, userID, invitation.Role); err != nil {
+		return fmt.Errorf("error adding member: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepositoryImpl) DeclineInvitation(ctx context.Context, id pgtype.UUID) error {
+	query := `
+		UPDATE organization_invitations SET declined_at = NOW()
+		WHERE id = $1 AND accepted_at IS NULL AND declined_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error declining invitation: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrInvitationNotFound)
+	}
+
+	return nil
+}
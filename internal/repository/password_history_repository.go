@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"go.uber.org/zap"
+)
+
+type PasswordHistoryRepository interface {
+	AddPasswordHistory(ctx context.Context, userID pgtype.UUID, passwordHash string, keep int) error
+	GetRecentPasswordHashes(ctx context.Context, userID pgtype.UUID, limit int) ([]string, error)
+	ListPasswordChanges(ctx context.Context, userID pgtype.UUID) ([]pgtype.Timestamptz, error)
+}
+
+// PasswordHistoryRepositoryImpl implements PasswordHistoryRepository
+type PasswordHistoryRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewPasswordHistoryRepository(db *database.DB, logger *zap.Logger) *PasswordHistoryRepositoryImpl {
+	return &PasswordHistoryRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AddPasswordHistory records a hash and drops all but the newest keep entries
+func (r *PasswordHistoryRepositoryImpl) AddPasswordHistory(ctx context.Context, userID pgtype.UUID, passwordHash string, keep int) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	insert := `INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)`
+	if _, err := tx.Exec(ctx, insert, userID, passwordHash); err != nil {
+		return fmt.Errorf("error adding password history: %w", err)
+	}
+
+	prune := `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		)
+	`
+	if _, err := tx.Exec(ctx, prune, userID, keep); err != nil {
+		return fmt.Errorf("error pruning password history: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PasswordHistoryRepositoryImpl) GetRecentPasswordHashes(ctx context.Context, userID pgtype.UUID, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting password history: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("error scanning password history: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// ListPasswordChanges returns when each remembered password was set, newest first
+func (r *PasswordHistoryRepositoryImpl) ListPasswordChanges(ctx context.Context, userID pgtype.UUID) ([]pgtype.Timestamptz, error) {
+	query := `SELECT created_at FROM password_history WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing password changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []pgtype.Timestamptz{}
+	for rows.Next() {
+		var changedAt pgtype.Timestamptz
+		if err := rows.Scan(&changedAt); err != nil {
+			return nil, fmt.Errorf("error scanning password change: %w", err)
+		}
+		changes = append(changes, changedAt)
+	}
+
+	return changes, rows.Err()
+}
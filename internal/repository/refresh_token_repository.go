@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, userID, familyID pgtype.UUID, tokenHash string, expiresAt time.Time) (*model.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id pgtype.UUID) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) error
+	RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error
+	ListRefreshTokens(ctx context.Context, userID pgtype.UUID) ([]*model.RefreshToken, error)
+}
+
+// RefreshTokenRepositoryImpl implements RefreshTokenRepository
+type RefreshTokenRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewRefreshTokenRepository(db *database.DB, logger *zap.Logger) *RefreshTokenRepositoryImpl {
+	return &RefreshTokenRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateRefreshToken stores a new refresh token. An invalid familyID starts a new family.
+func (r *RefreshTokenRepositoryImpl) CreateRefreshToken(ctx context.Context, userID, familyID pgtype.UUID, tokenHash string, expiresAt time.Time) (*model.RefreshToken, error) {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, COALESCE($2, uuid_generate_v4()), $3, $4)
+		RETURNING id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+	`
+
+	var token model.RefreshToken
+	err := r.db.Pool.QueryRow(ctx, query, userID, familyID, tokenHash, expiresAt).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *RefreshTokenRepositoryImpl) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var token model.RefreshToken
+	err := r.db.Pool.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrInvalidRefreshToken)
+		}
+		return nil, fmt.Errorf("error getting refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed flags a token as consumed. It reports false when the token
+// was already used or revoked, which lets concurrent refreshes detect each other.
+func (r *RefreshTokenRepositoryImpl) MarkRefreshTokenUsed(ctx context.Context, id pgtype.UUID) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+	`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("error marking refresh token used: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *RefreshTokenRepositoryImpl) RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, familyID)
+	if err != nil {
+		return fmt.Errorf("error revoking refresh token family: %w", err)
+	}
+
+	r.logger.Info("Refresh token family revoked",
+		zap.String("family_id", familyID.String()),
+		zap.Int64("tokens", result.RowsAffected()),
+	)
+	return nil
+}
+
+func (r *RefreshTokenRepositoryImpl) RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking user refresh tokens: %w", err)
+	}
+
+	r.logger.Info("User refresh tokens revoked",
+		zap.String("user_id", userID.String()),
+		zap.Int64("tokens", result.RowsAffected()),
+	)
+	return nil
+}
+
+func (r *RefreshTokenRepositoryImpl) ListRefreshTokens(ctx context.Context, userID pgtype.UUID) ([]*model.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*model.RefreshToken{}
+	for rows.Next() {
+		var token model.RefreshToken
+		err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.FamilyID,
+			&token.ExpiresAt,
+			&token.UsedAt,
+			&token.RevokedAt,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning refresh token: %w", err)
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, rows.Err()
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type RevocationRepository interface {
+	RevokeToken(ctx context.Context, jti string, userID pgtype.UUID, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	GetTokenVersion(ctx context.Context, userID pgtype.UUID) (int, error)
+	IncrementTokenVersion(ctx context.Context, userID pgtype.UUID) (int, error)
+	ListRevokedTokens(ctx context.Context, userID pgtype.UUID) ([]*model.RevokedToken, error)
+	DeleteExpiredRevocations(ctx context.Context) (int, error)
+}
+
+// RevocationRepositoryImpl implements RevocationRepository
+type RevocationRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewRevocationRepository(db *database.DB, logger *zap.Logger) *RevocationRepositoryImpl {
+	return &RevocationRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *RevocationRepositoryImpl) RevokeToken(ctx context.Context, jti string, userID pgtype.UUID, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	if _, err := r.db.Pool.Exec(ctx, query, jti, userID, expiresAt); err != nil {
+		return fmt.Errorf("error revoking token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RevocationRepositoryImpl) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	var revoked bool
+	if err := r.db.Pool.QueryRow(ctx, query, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("error checking token revocation: %w", err)
+	}
+
+	return revoked, nil
+}
+
+func (r *RevocationRepositoryImpl) GetTokenVersion(ctx context.Context, userID pgtype.UUID) (int, error) {
+	query := `SELECT token_version FROM users WHERE id = $1`
+
+	var version int
+	if err := r.db.Pool.QueryRow(ctx, query, userID).Scan(&version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, errors.New(constants.ErrUserNotFound)
+		}
+		return 0, fmt.Errorf("error getting token version: %w", err)
+	}
+
+	return version, nil
+}
+
+// IncrementTokenVersion invalidates every access token issued to the user so far
+func (r *RevocationRepositoryImpl) IncrementTokenVersion(ctx context.Context, userID pgtype.UUID) (int, error) {
+	query := `
+		UPDATE users
+		SET token_version = token_version + 1
+		WHERE id = $1
+		RETURNING token_version
+	`
+
+	var version int
+	if err := r.db.Pool.QueryRow(ctx, query, userID).Scan(&version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, errors.New(constants.ErrUserNotFound)
+		}
+		return 0, fmt.Errorf("error incrementing token version: %w", err)
+	}
+
+	r.logger.Info("User token version incremented",
+		zap.String("user_id", userID.String()),
+		zap.Int("token_version", version),
+	)
+	return version, nil
+}
+
+func (r *RevocationRepositoryImpl) ListRevokedTokens(ctx context.Context, userID pgtype.UUID) ([]*model.RevokedToken, error) {
+	query := `SELECT jti, expires_at, revoked_at FROM revoked_tokens WHERE user_id = $1 ORDER BY revoked_at DESC`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing revoked tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*model.RevokedToken{}
+	for rows.Next() {
+		var token model.RevokedToken
+		if err := rows.Scan(&token.TokenID, &token.ExpiresAt, &token.RevokedAt); err != nil {
+			return nil, fmt.Errorf("error scanning revoked token: %w", err)
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// DeleteExpiredRevocations drops revocations of tokens that have expired anyway
+func (r *RevocationRepositoryImpl) DeleteExpiredRevocations(ctx context.Context) (int, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired revocations: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
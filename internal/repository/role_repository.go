@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type RoleRepository interface {
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+	GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error)
+	AssignRole(ctx context.Context, userID pgtype.UUID, role string) error
+	RemoveRole(ctx context.Context, userID pgtype.UUID, role string) (bool, error)
+	RoleHasMembers(ctx context.Context, role string) (bool, error)
+}
+
+// RoleRepositoryImpl implements RoleRepository
+type RoleRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewRoleRepository(db *database.DB, logger *zap.Logger) *RoleRepositoryImpl {
+	return &RoleRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListRoles returns every role with the names of its permissions
+func (r *RoleRepositoryImpl) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description,
+			COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}'),
+			r.created_at
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		GROUP BY r.id
+		ORDER BY r.name
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error listing roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []*model.Role{}
+	for rows.Next() {
+		var role model.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.Permissions, &role.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning role: %w", err)
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *RoleRepositoryImpl) GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error) {
+	query := `
+		SELECT r.name
+		FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY r.name
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("error scanning user role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// AssignRole is idempotent: assigning a role the user already has is not an error
+func (r *RoleRepositoryImpl) AssignRole(ctx context.Context, userID pgtype.UUID, role string) error {
+	query := `
+		WITH role AS (SELECT id FROM roles WHERE name = $2),
+		inserted AS (
+			INSERT INTO user_roles (user_id, role_id)
+			SELECT $1, id FROM role
+			ON CONFLICT DO NOTHING
+		)
+		SELECT EXISTS (SELECT 1 FROM role)
+	`
+
+	var roleExists bool
+	if err := r.db.Pool.QueryRow(ctx, query, userID, role).Scan(&roleExists); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return errors.New(constants.ErrUserNotFound)
+		}
+		return fmt.Errorf("error assigning role: %w", err)
+	}
+	if !roleExists {
+		return errors.New(constants.ErrRoleNotFound)
+	}
+
+	return nil
+}
+
+// RemoveRole reports whether the user actually had the role
+func (r *RoleRepositoryImpl) RemoveRole(ctx context.Context, userID pgtype.UUID, role string) (bool, error) {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)
+	`
+
+	tag, err := r.db.Pool.Exec(ctx, query, userID, role)
+	if err != nil {
+		return false, fmt.Errorf("error removing role: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RoleHasMembers reports whether any user holds the role
+func (r *RoleRepositoryImpl) RoleHasMembers(ctx context.Context, role string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE r.name = $1
+		)
+	`
+
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, query, role).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking role members: %w", err)
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type SessionRepository interface {
+	SaveSession(ctx context.Context, session *model.Session) error
+	GetSession(ctx context.Context, id pgtype.UUID) (*model.Session, error)
+	GetSessionByCookie(ctx context.Context, cookieHash string) (*model.Session, error)
+	ListActiveSessions(ctx context.Context, userID pgtype.UUID, seenSince time.Time) ([]*model.Session, error)
+	ListSessions(ctx context.Context, userID pgtype.UUID) ([]*model.Session, error)
+	TouchSession(ctx context.Context, id pgtype.UUID) (bool, error)
+	RevokeSession(ctx context.Context, id pgtype.UUID) error
+	RevokeUserSessions(ctx context.Context, userID pgtype.UUID) error
+	SetSessionOrganization(ctx context.Context, id, orgID pgtype.UUID) error
+	ClearSessionOrganization(ctx context.Context, userID, orgID pgtype.UUID) error
+}
+
+const sessionColumns = `id, user_id, token_id, org_id, user_agent, ip_address, cookie_hash, csrf_token_hash, created_at, last_seen_at, expires_at, revoked_at, client_id, scopes`
+
+// scanSession reads a row selected with sessionColumns. Columns selected after
+// them are scanned into extra.
+func scanSession(row pgx.Row, extra ...any) (*model.Session, error) {
+	var session model.Session
+	dest := []any{
+		&session.ID,
+		&session.UserID,
+		&session.TokenID,
+		&session.OrgID,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CookieHash,
+		&session.CSRFTokenHash,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.ClientID,
+		&session.Scopes,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SessionRepositoryImpl implements SessionRepository
+type SessionRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewSessionRepository(db *database.DB, logger *zap.Logger) *SessionRepositoryImpl {
+	return &SessionRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveSession creates the session when its ID is unset or unknown, otherwise it
+// records the latest access token and client address. The cookie, expiry and
+// OAuth client are only set on creation. The row is read back into session.
+func (r *SessionRepositoryImpl) SaveSession(ctx context.Context, session *model.Session) error {
+	query := `
+		INSERT INTO user_sessions (id, user_id, token_id, org_id, user_agent, ip_address, cookie_hash, csrf_token_hash, expires_at, client_id, scopes)
+		VALUES (COALESCE($1, uuid_generate_v4()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			token_id = EXCLUDED.token_id,
+			ip_address = COALESCE(NULLIF(EXCLUDED.ip_address, ''), user_sessions.ip_address),
+			last_seen_at = NOW()
+		RETURNING ` + sessionColumns
+
+	saved, err := scanSession(r.db.Pool.QueryRow(ctx, query,
+		session.ID,
+		session.UserID,
+		session.TokenID,
+		session.OrgID,
+		session.UserAgent,
+		session.IPAddress,
+		session.CookieHash,
+		session.CSRFTokenHash,
+		session.ExpiresAt,
+		session.ClientID,
+		session.Scopes,
+	))
+	if err != nil {
+		return fmt.Errorf("error saving session: %w", err)
+	}
+
+	*session = *saved
+	return nil
+}
+
+func (r *SessionRepositoryImpl) GetSession(ctx context.Context, id pgtype.UUID) (*model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE id = $1`
+
+	session, err := scanSession(r.db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrSessionNotFound)
+		}
+		return nil, fmt.Errorf("error getting session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionByCookie returns the unrevoked, unexpired cookie session along with
+// its user's email
+func (r *SessionRepositoryImpl) GetSessionByCookie(ctx context.Context, cookieHash string) (*model.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `, (SELECT email FROM users WHERE users.id = user_sessions.user_id)
+		FROM user_sessions
+		WHERE cookie_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+
+	var email string
+	session, err := scanSession(r.db.Pool.QueryRow(ctx, query, cookieHash), &email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrSessionNotFound)
+		}
+		return nil, fmt.Errorf("error getting session: %w", err)
+	}
+
+	session.UserEmail = email
+	return session, nil
+}
+
+// ListActiveSessions returns the user's unrevoked sessions used since seenSince, most recent first
+func (r *SessionRepositoryImpl) ListActiveSessions(ctx context.Context, userID pgtype.UUID, seenSince time.Time) ([]*model.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND last_seen_at > $2
+			AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, seenSince)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*model.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// ListSessions returns every session of the user, ended ones included, newest first
+func (r *SessionRepositoryImpl) ListSessions(ctx context.Context, userID pgtype.UUID) ([]*model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*model.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// TouchSession bumps last_seen_at and reports whether the session is still active
+func (r *SessionRepositoryImpl) TouchSession(ctx context.Context, id pgtype.UUID) (bool, error) {
+	query := `UPDATE user_sessions SET last_seen_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("error touching session: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *SessionRepositoryImpl) RevokeSession(ctx context.Context, id pgtype.UUID) error {
+	query := `UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	if _, err := r.db.Pool.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepositoryImpl) RevokeUserSessions(ctx context.Context, userID pgtype.UUID) error {
+	query := `UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking user sessions: %w", err)
+	}
+
+	r.logger.Info("User sessions revoked",
+		zap.String("user_id", userID.String()),
+		zap.Int64("sessions", result.RowsAffected()),
+	)
+	return nil
+}
+
+// SetSessionOrganization changes the organization carried by the session's next
+// access tokens. An unset orgID clears it.
+func (r *SessionRepositoryImpl) SetSessionOrganization(ctx context.Context, id, orgID pgtype.UUID) error {
+	query := `UPDATE user_sessions SET org_id = $2 WHERE id = $1 AND revoked_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, id, orgID)
+	if err != nil {
+		return fmt.Errorf("error setting session organization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+// ClearSessionOrganization drops orgID from every session of the user, e.g. once
+// they leave the organization
+func (r *SessionRepositoryImpl) ClearSessionOrganization(ctx context.Context, userID, orgID pgtype.UUID) error {
+	query := `UPDATE user_sessions SET org_id = NULL WHERE user_id = $1 AND org_id = $2`
+	if _, err := r.db.Pool.Exec(ctx, query, userID, orgID); err != nil {
+		return fmt.Errorf("error clearing session organization: %w", err)
+	}
+
+	return nil
+}
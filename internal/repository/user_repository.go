@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -12,13 +13,47 @@ import (
 	"github.com/manish-npx/go-echo-pg/internal/database"
 	"github.com/manish-npx/go-echo-pg/internal/model"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepository interface {
-	CreateUser(ctx context.Context, req *model.CreateUserRequest) (*model.User, error)
+	CreateUser(ctx context.Context, email, passwordHash, name string) (*model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
 	GetUserByID(ctx context.Context, id pgtype.UUID) (*model.User, error)
+	UpdateUser(ctx context.Context, id pgtype.UUID, req *model.UpdateUserRequest) (*model.User, error)
+	SetPendingEmail(ctx context.Context, id pgtype.UUID, email pgtype.Text) error
+	ChangeEmail(ctx context.Context, id pgtype.UUID, email string) (*model.User, error)
+	UpdatePassword(ctx context.Context, userID pgtype.UUID, newPassword string) error
+	RehashPassword(ctx context.Context, userID pgtype.UUID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id pgtype.UUID) error
+	SoftDeleteUser(ctx context.Context, id pgtype.UUID, purgeAt time.Time) error
+	RestoreUser(ctx context.Context, id pgtype.UUID) error
+	ListUsersDueForPurge(ctx context.Context, limit int) ([]pgtype.UUID, error)
+	DeleteUser(ctx context.Context, id pgtype.UUID) error
+}
+
+const userColumns = `id, email, password, name, token_version, email_verified_at, password_changed_at, created_at, updated_at, deleted_at, purge_at, pending_email`
+
+// scanUser reads a row selected with userColumns
+func scanUser(row pgx.Row) (*model.User, error) {
+	var user model.User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Password,
+		&user.Name,
+		&user.TokenVersion,
+		&user.EmailVerifiedAt,
+		&user.PasswordChangedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletedAt,
+		&user.PurgeAt,
+		&user.PendingEmail,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 // UserRepositoryImpl implements UserRepository
@@ -34,27 +69,14 @@ func NewUserRepository(db *database.DB, logger *zap.Logger) *UserRepositoryImpl
 	}
 }
 
-func (r *UserRepositoryImpl) CreateUser(ctx context.Context, req *model.CreateUserRequest) (*model.User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, fmt.Errorf("error hashing password: %w", err)
-	}
-
+// CreateUser stores an already hashed password
+func (r *UserRepositoryImpl) CreateUser(ctx context.Context, email, passwordHash, name string) (*model.User, error) {
 	query := `
 		INSERT INTO users (email, password, name)
 		VALUES ($1, $2, $3)
-		RETURNING id, email, password, name, created_at, updated_at
-	`
+		RETURNING ` + userColumns
 
-	var user model.User
-	err = r.db.Pool.QueryRow(ctx, query, req.Email, string(hashedPassword), req.Name).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.db.Pool.QueryRow(ctx, query, email, passwordHash, name))
 
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -65,25 +87,22 @@ func (r *UserRepositoryImpl) CreateUser(ctx context.Context, req *model.CreateUs
 	}
 
 	r.logger.Info("User created successfully", zap.String("email", user.Email))
-	return &user, nil
+	return user, nil
 }
 
+// GetUserByEmail matches case-insensitively, so accounts stored before emails
+// were normalized are still found. Should two of them differ only in case, the
+// oldest wins.
 func (r *UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	query := `
-		SELECT id, email, password, name, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE email = $1
+		WHERE LOWER(email) = LOWER($1)
+		ORDER BY created_at
+		LIMIT 1
 	`
 
-	var user model.User
-	err := r.db.Pool.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.db.Pool.QueryRow(ctx, query, email))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -92,25 +111,17 @@ func (r *UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 func (r *UserRepositoryImpl) GetUserByID(ctx context.Context, id pgtype.UUID) (*model.User, error) {
 	query := `
-		SELECT id, email, password, name, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = $1
 	`
 
-	var user model.User
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.db.Pool.QueryRow(ctx, query, id))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -119,38 +130,72 @@ func (r *UserRepositoryImpl) GetUserByID(ctx context.Context, id pgtype.UUID) (*
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // Additional methods for update operations
 func (r *UserRepositoryImpl) UpdateUser(ctx context.Context, id pgtype.UUID, req *model.UpdateUserRequest) (*model.User, error) {
 	query := `
 		UPDATE users
-		SET name = $2, email = $3, updated_at = NOW()
+		SET name = $2, updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, email, password, name, created_at, updated_at
-	`
+		RETURNING ` + userColumns
 
-	var user model.User
-	err := r.db.Pool.QueryRow(ctx, query, id, req.Name, req.Email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.db.Pool.QueryRow(ctx, query, id, req.Name))
 
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrUserNotFound)
+		}
 		return nil, fmt.Errorf("error updating user: %w", err)
 	}
 
 	r.logger.Info("User updated successfully", zap.String("email", user.Email))
-	return &user, nil
+	return user, nil
+}
+
+// SetPendingEmail records an unconfirmed email change; a null email cancels it
+func (r *UserRepositoryImpl) SetPendingEmail(ctx context.Context, id pgtype.UUID, email pgtype.Text) error {
+	query := `UPDATE users SET pending_email = $2, updated_at = NOW() WHERE id = $1`
+	result, err := r.db.Pool.Exec(ctx, query, id, email)
+	if err != nil {
+		return fmt.Errorf("error setting pending email: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// ChangeEmail replaces the address with one its owner has proven control of,
+// which also verifies it, and drops any pending change
+func (r *UserRepositoryImpl) ChangeEmail(ctx context.Context, id pgtype.UUID, email string) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET email = $2, pending_email = NULL, email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + userColumns
+
+	user, err := scanUser(r.db.Pool.QueryRow(ctx, query, id, email))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrUserNotFound)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, errors.New(constants.ErrUserExists)
+		}
+		return nil, fmt.Errorf("error changing email: %w", err)
+	}
+
+	return user, nil
 }
 
 func (r *UserRepositoryImpl) UpdatePassword(ctx context.Context, userID pgtype.UUID, newPassword string) error {
-	query := `UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`
+	query := `UPDATE users SET password = $1, password_changed_at = NOW(), updated_at = NOW() WHERE id = $2`
 	result, err := r.db.Pool.Exec(ctx, query, newPassword, userID)
 	if err != nil {
 		return fmt.Errorf("error updating password: %w", err)
@@ -162,3 +207,155 @@ func (r *UserRepositoryImpl) UpdatePassword(ctx context.Context, userID pgtype.U
 
 	return nil
 }
+
+// RehashPassword replaces the stored hash of an unchanged password, so the
+// password age is kept
+func (r *UserRepositoryImpl) RehashPassword(ctx context.Context, userID pgtype.UUID, passwordHash string) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+	result, err := r.db.Pool.Exec(ctx, query, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("error rehashing password: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+func (r *UserRepositoryImpl) MarkEmailVerified(ctx context.Context, id pgtype.UUID) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+	result, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error marking email verified: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// SoftDeleteUser marks the account deleted and schedules its purge
+func (r *UserRepositoryImpl) SoftDeleteUser(ctx context.Context, id pgtype.UUID, purgeAt time.Time) error {
+	query := `UPDATE users SET deleted_at = NOW(), purge_at = $2 WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.db.Pool.Exec(ctx, query, id, purgeAt)
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// RestoreUser cancels a pending purge
+func (r *UserRepositoryImpl) RestoreUser(ctx context.Context, id pgtype.UUID) error {
+	query := `UPDATE users SET deleted_at = NULL, purge_at = NULL WHERE id = $1`
+	if _, err := r.db.Pool.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("error restoring user: %w", err)
+	}
+
+	return nil
+}
+
+// ListUsersDueForPurge returns deleted accounts whose grace period is over, oldest first
+func (r *UserRepositoryImpl) ListUsersDueForPurge(ctx context.Context, limit int) ([]pgtype.UUID, error) {
+	query := `
+		SELECT id FROM users
+		WHERE purge_at <= NOW()
+		ORDER BY purge_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing users due for purge: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []pgtype.UUID{}
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// DeleteUser purges a deleted account whose grace period is over: the user and,
+// through cascading foreign keys, everything stored about them. Organizations the
+// user was the only member of are deleted too, and ones that would be left
+// without an owner pass to their longest standing admin, or member if there is
+// none. Accounts that were restored, are not due yet or are being purged by
+// another replica are left alone and reported as not found.
+func (r *UserRepositoryImpl) DeleteUser(ctx context.Context, id pgtype.UUID) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The row lock also makes a concurrent restore wait for the purge to finish
+	query := `
+		SELECT id FROM users
+		WHERE id = $1 AND deleted_at IS NOT NULL AND purge_at <= NOW()
+		FOR UPDATE SKIP LOCKED
+	`
+	var lockedID pgtype.UUID
+	if err := tx.QueryRow(ctx, query, id).Scan(&lockedID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New(constants.ErrUserNotFound)
+		}
+		return fmt.Errorf("error locking user: %w", err)
+	}
+
+	query = `
+		DELETE FROM organizations o
+		WHERE o.id IN (SELECT org_id FROM organization_members WHERE user_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.org_id = o.id AND m.user_id <> $1)
+	`
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("error deleting organizations: %w", err)
+	}
+
+	query = `
+		UPDATE organization_members SET role = 'owner'
+		WHERE (org_id, user_id) IN (
+			SELECT DISTINCT ON (m.org_id) m.org_id, m.user_id
+			FROM organization_members m
+			WHERE m.user_id <> $1
+				AND m.org_id IN (SELECT org_id FROM organization_members WHERE user_id = $1 AND role = 'owner')
+				AND NOT EXISTS (
+					SELECT 1 FROM organization_members o
+					WHERE o.org_id = m.org_id AND o.role = 'owner' AND o.user_id <> $1
+				)
+			ORDER BY m.org_id, m.role = 'admin' DESC, m.created_at
+		)
+	`
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("error transferring organization ownership: %w", err)
+	}
+
+	query = `DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL AND purge_at <= NOW()`
+	result, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrUserNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
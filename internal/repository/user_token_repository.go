@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+// UserTokenRepository stores hashes of single-use tokens sent to users by email
+type UserTokenRepository interface {
+	CreateUserToken(ctx context.Context, userID pgtype.UUID, purpose, tokenHash string, expiresAt time.Time) error
+	ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (pgtype.UUID, error)
+	AttemptUserToken(ctx context.Context, purpose, tokenHash string, maxAttempts int) (pgtype.UUID, error)
+	DeleteUserTokens(ctx context.Context, userID pgtype.UUID, purpose string) error
+	HasRecentUserToken(ctx context.Context, userID pgtype.UUID, purpose string, within time.Duration) (bool, error)
+	ListUserTokens(ctx context.Context, userID pgtype.UUID) ([]*model.UserToken, error)
+}
+
+// UserTokenRepositoryImpl implements UserTokenRepository
+type UserTokenRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewUserTokenRepository(db *database.DB, logger *zap.Logger) *UserTokenRepositoryImpl {
+	return &UserTokenRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *UserTokenRepositoryImpl) CreateUserToken(ctx context.Context, userID pgtype.UUID, purpose, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := r.db.Pool.Exec(ctx, query, userID, purpose, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("error creating user token: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeUserToken atomically marks an unexpired token as used and returns its owner
+func (r *UserTokenRepositoryImpl) ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (pgtype.UUID, error) {
+	query := `
+		UPDATE user_tokens
+		SET consumed_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+
+	var userID pgtype.UUID
+	if err := r.db.Pool.QueryRow(ctx, query, tokenHash, purpose).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgtype.UUID{}, errors.New(constants.ErrInvalidActionToken)
+		}
+		return pgtype.UUID{}, fmt.Errorf("error consuming user token: %w", err)
+	}
+
+	return userID, nil
+}
+
+// AttemptUserToken counts a guess made with an unexpired, unused token and returns
+// its owner. Once maxAttempts guesses were made the token is treated as invalid.
+func (r *UserTokenRepositoryImpl) AttemptUserToken(ctx context.Context, purpose, tokenHash string, maxAttempts int) (pgtype.UUID, error) {
+	query := `
+		UPDATE user_tokens
+		SET attempts = attempts + 1
+		WHERE token_hash = $1 AND purpose = $2 AND consumed_at IS NULL AND expires_at > NOW() AND attempts < $3
+		RETURNING user_id
+	`
+
+	var userID pgtype.UUID
+	if err := r.db.Pool.QueryRow(ctx, query, tokenHash, purpose, maxAttempts).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgtype.UUID{}, errors.New(constants.ErrInvalidActionToken)
+		}
+		return pgtype.UUID{}, fmt.Errorf("error attempting user token: %w", err)
+	}
+
+	return userID, nil
+}
+
+// DeleteUserTokens drops every outstanding token of a purpose, e.g. before issuing a new one
+func (r *UserTokenRepositoryImpl) DeleteUserTokens(ctx context.Context, userID pgtype.UUID, purpose string) error {
+	query := `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL`
+	if _, err := r.db.Pool.Exec(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("error deleting user tokens: %w", err)
+	}
+
+	return nil
+}
+
+// HasRecentUserToken reports whether a token of the purpose was created within
+// the given duration, used or not
+func (r *UserTokenRepositoryImpl) HasRecentUserToken(ctx context.Context, userID pgtype.UUID, purpose string, within time.Duration) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_tokens
+			WHERE user_id = $1 AND purpose = $2 AND created_at > NOW() - $3::interval
+		)
+	`
+
+	var recent bool
+	if err := r.db.Pool.QueryRow(ctx, query, userID, purpose, within).Scan(&recent); err != nil {
+		return false, fmt.Errorf("error checking recent user tokens: %w", err)
+	}
+
+	return recent, nil
+}
+
+func (r *UserTokenRepositoryImpl) ListUserTokens(ctx context.Context, userID pgtype.UUID) ([]*model.UserToken, error) {
+	query := `
+		SELECT purpose, expires_at, consumed_at, created_at
+		FROM user_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing user tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*model.UserToken{}
+	for rows.Next() {
+		var token model.UserToken
+		if err := rows.Scan(&token.Purpose, &token.ExpiresAt, &token.ConsumedAt, &token.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning user token: %w", err)
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, rows.Err()
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/database"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+type WebAuthnRepository interface {
+	CreateCredential(ctx context.Context, cred *model.WebAuthnCredential) (*model.WebAuthnCredential, error)
+	ListCredentials(ctx context.Context, userID pgtype.UUID) ([]*model.WebAuthnCredential, error)
+	GetCredentialByCredentialID(ctx context.Context, credentialID []byte) (*model.WebAuthnCredential, error)
+	UpdateCredentialUsage(ctx context.Context, id pgtype.UUID, signCount int64, cloneWarning, backupState bool) error
+	DeleteCredential(ctx context.Context, userID, id pgtype.UUID) error
+	CreateSession(ctx context.Context, userID pgtype.UUID, ceremony string, data []byte, expiresAt time.Time) (pgtype.UUID, error)
+	ConsumeSession(ctx context.Context, id pgtype.UUID, ceremony string) (pgtype.UUID, []byte, error)
+	ListSessions(ctx context.Context, userID pgtype.UUID) ([]*model.WebAuthnCeremony, error)
+}
+
+const webAuthnCredentialColumns = `id, user_id, credential_id, public_key, attestation_type, transports, aaguid,
+	sign_count, clone_warning, user_verified, backup_eligible, backup_state, name, created_at, last_used_at`
+
+// scanWebAuthnCredential reads a row selected with webAuthnCredentialColumns
+func scanWebAuthnCredential(row pgx.Row) (*model.WebAuthnCredential, error) {
+	var cred model.WebAuthnCredential
+	err := row.Scan(
+		&cred.ID,
+		&cred.UserID,
+		&cred.CredentialID,
+		&cred.PublicKey,
+		&cred.AttestationType,
+		&cred.Transports,
+		&cred.AAGUID,
+		&cred.SignCount,
+		&cred.CloneWarning,
+		&cred.UserVerified,
+		&cred.BackupEligible,
+		&cred.BackupState,
+		&cred.Name,
+		&cred.CreatedAt,
+		&cred.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// WebAuthnRepositoryImpl implements WebAuthnRepository
+type WebAuthnRepositoryImpl struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewWebAuthnRepository(db *database.DB, logger *zap.Logger) *WebAuthnRepositoryImpl {
+	return &WebAuthnRepositoryImpl{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *WebAuthnRepositoryImpl) CreateCredential(ctx context.Context, cred *model.WebAuthnCredential) (*model.WebAuthnCredential, error) {
+	query := `
+		INSERT INTO webauthn_credentials (user_id, credential_id, public_key, attestation_type, transports, aaguid,
+			sign_count, clone_warning, user_verified, backup_eligible, backup_state, name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ` + webAuthnCredentialColumns
+
+	created, err := scanWebAuthnCredential(r.db.Pool.QueryRow(ctx, query,
+		cred.UserID,
+		cred.CredentialID,
+		cred.PublicKey,
+		cred.AttestationType,
+		cred.Transports,
+		cred.AAGUID,
+		cred.SignCount,
+		cred.CloneWarning,
+		cred.UserVerified,
+		cred.BackupEligible,
+		cred.BackupState,
+		cred.Name,
+	))
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, errors.New(constants.ErrWebAuthnVerification)
+		}
+		return nil, fmt.Errorf("error creating webauthn credential: %w", err)
+	}
+
+	r.logger.Info("WebAuthn credential registered", zap.String("user_id", created.UserID.String()))
+	return created, nil
+}
+
+func (r *WebAuthnRepositoryImpl) ListCredentials(ctx context.Context, userID pgtype.UUID) ([]*model.WebAuthnCredential, error) {
+	query := `
+		SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webauthn credentials: %w", err)
+	}
+	defer rows.Close()
+
+	credentials := []*model.WebAuthnCredential{}
+	for rows.Next() {
+		cred, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webauthn credential: %w", err)
+		}
+		credentials = append(credentials, cred)
+	}
+
+	return credentials, rows.Err()
+}
+
+func (r *WebAuthnRepositoryImpl) GetCredentialByCredentialID(ctx context.Context, credentialID []byte) (*model.WebAuthnCredential, error) {
+	query := `
+		SELECT ` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials
+		WHERE credential_id = $1
+	`
+
+	cred, err := scanWebAuthnCredential(r.db.Pool.QueryRow(ctx, query, credentialID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(constants.ErrWebAuthnCredentialNotFound)
+		}
+		return nil, fmt.Errorf("error getting webauthn credential: %w", err)
+	}
+
+	return cred, nil
+}
+
+func (r *WebAuthnRepositoryImpl) UpdateCredentialUsage(ctx context.Context, id pgtype.UUID, signCount int64, cloneWarning, backupState bool) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, clone_warning = $3, backup_state = $4, last_used_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := r.db.Pool.Exec(ctx, query, id, signCount, cloneWarning, backupState); err != nil {
+		return fmt.Errorf("error updating webauthn credential: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebAuthnRepositoryImpl) DeleteCredential(ctx context.Context, userID, id pgtype.UUID) error {
+	query := `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`
+	result, err := r.db.Pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting webauthn credential: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New(constants.ErrWebAuthnCredentialNotFound)
+	}
+
+	return nil
+}
+
+// CreateSession stores the server side of a pending ceremony. userID is invalid for discoverable logins.
+func (r *WebAuthnRepositoryImpl) CreateSession(ctx context.Context, userID pgtype.UUID, ceremony string, data []byte, expiresAt time.Time) (pgtype.UUID, error) {
+	query := `
+		INSERT INTO webauthn_sessions (user_id, ceremony, session_data, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	var id pgtype.UUID
+	if err := r.db.Pool.QueryRow(ctx, query, userID, ceremony, data, expiresAt).Scan(&id); err != nil {
+		return pgtype.UUID{}, fmt.Errorf("error creating webauthn session: %w", err)
+	}
+
+	// Opportunistically clear abandoned ceremonies
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM webauthn_sessions WHERE expires_at < NOW()`); err != nil {
+		r.logger.Warn("Failed to delete expired webauthn sessions", zap.Error(err))
+	}
+
+	return id, nil
+}
+
+// ConsumeSession deletes and returns a pending ceremony so its challenge can be answered only once
+func (r *WebAuthnRepositoryImpl) ConsumeSession(ctx context.Context, id pgtype.UUID, ceremony string) (pgtype.UUID, []byte, error) {
+	query := `
+		DELETE FROM webauthn_sessions
+		WHERE id = $1 AND ceremony = $2 AND expires_at > NOW()
+		RETURNING user_id, session_data
+	`
+
+	var userID pgtype.UUID
+	var data []byte
+	if err := r.db.Pool.QueryRow(ctx, query, id, ceremony).Scan(&userID, &data); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgtype.UUID{}, nil, errors.New(constants.ErrWebAuthnSessionNotFound)
+		}
+		return pgtype.UUID{}, nil, fmt.Errorf("error consuming webauthn session: %w", err)
+	}
+
+	return userID, data, nil
+}
+
+// ListSessions returns the user's ceremonies without their session data
+func (r *WebAuthnRepositoryImpl) ListSessions(ctx context.Context, userID pgtype.UUID) ([]*model.WebAuthnCeremony, error) {
+	query := `
+		SELECT id, ceremony, expires_at, created_at
+		FROM webauthn_sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webauthn sessions: %w", err)
+	}
+	defer rows.Close()
+
+	ceremonies := []*model.WebAuthnCeremony{}
+	for rows.Next() {
+		var ceremony model.WebAuthnCeremony
+		if err := rows.Scan(&ceremony.ID, &ceremony.Ceremony, &ceremony.ExpiresAt, &ceremony.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning webauthn session: %w", err)
+		}
+		ceremonies = append(ceremonies, &ceremony)
+	}
+
+	return ceremonies, rows.Err()
+}
@@ -6,20 +6,57 @@ import (
 	"github.com/manish-npx/go-echo-pg/internal/config"
 	"github.com/manish-npx/go-echo-pg/internal/handler"
 	customMiddleware "github.com/manish-npx/go-echo-pg/internal/middleware"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/service"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
 	"go.uber.org/zap"
 )
 
 type Routes struct {
-	cfg         *config.Config
-	authHandler *handler.AuthHandler
-	logger      *zap.Logger
+	cfg               *config.Config
+	keys              *utils.KeyRing
+	authHandler       *handler.AuthHandler
+	mfaHandler        *handler.MFAHandler
+	webAuthnHandler   *handler.WebAuthnHandler
+	oauthHandler      *handler.OAuthHandler
+	federationHandler *handler.FederationHandler
+	apiKeyHandler     *handler.APIKeyHandler
+	sessionHandler    *handler.SessionHandler
+	orgHandler        *handler.OrganizationHandler
+	accountHandler    *handler.AccountHandler
+	adminHandler      *handler.AdminHandler
+	wellKnownHandler  *handler.WellKnownHandler
+	revocationService service.TokenRevocationService
+	apiKeyService     service.APIKeyService
+	rbacService       service.RBACService
+	loginGuardService service.LoginGuardService
+	orgService        service.OrganizationService
+	sessionService    service.SessionService
+	logger            *zap.Logger
 }
 
-func NewRoutes(cfg *config.Config, authHandler *handler.AuthHandler, logger *zap.Logger) *Routes {
+func NewRoutes(cfg *config.Config, keys *utils.KeyRing, authHandler *handler.AuthHandler, mfaHandler *handler.MFAHandler, webAuthnHandler *handler.WebAuthnHandler, oauthHandler *handler.OAuthHandler, federationHandler *handler.FederationHandler, apiKeyHandler *handler.APIKeyHandler, sessionHandler *handler.SessionHandler, orgHandler *handler.OrganizationHandler, accountHandler *handler.AccountHandler, adminHandler *handler.AdminHandler, wellKnownHandler *handler.WellKnownHandler, revocationService service.TokenRevocationService, apiKeyService service.APIKeyService, rbacService service.RBACService, loginGuardService service.LoginGuardService, orgService service.OrganizationService, sessionService service.SessionService, logger *zap.Logger) *Routes {
 	return &Routes{
-		cfg:         cfg,
-		authHandler: authHandler,
-		logger:      logger,
+		cfg:               cfg,
+		keys:              keys,
+		authHandler:       authHandler,
+		mfaHandler:        mfaHandler,
+		webAuthnHandler:   webAuthnHandler,
+		oauthHandler:      oauthHandler,
+		federationHandler: federationHandler,
+		apiKeyHandler:     apiKeyHandler,
+		sessionHandler:    sessionHandler,
+		orgHandler:        orgHandler,
+		accountHandler:    accountHandler,
+		adminHandler:      adminHandler,
+		wellKnownHandler:  wellKnownHandler,
+		revocationService: revocationService,
+		apiKeyService:     apiKeyService,
+		rbacService:       rbacService,
+		loginGuardService: loginGuardService,
+		orgService:        orgService,
+		sessionService:    sessionService,
+		logger:            logger,
 	}
 }
 
@@ -27,6 +64,7 @@ func (r *Routes) RegisterRoutes(e *echo.Echo) {
 	// Global middleware
 	e.Use(middleware.RequestID())
 	e.Use(customMiddleware.Logger(r.logger))
+	e.Use(customMiddleware.ClientInfo())
 	e.Use(customMiddleware.CORS(r.cfg))
 	e.Use(middleware.Secure())
 	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(100)))
@@ -35,23 +73,134 @@ func (r *Routes) RegisterRoutes(e *echo.Echo) {
 	e.GET("/health", r.authHandler.Health)
 	e.GET("/ready", r.authHandler.Ready)
 
-	// Auth routes (public)
-	auth := e.Group("/auth")
+	// Discovery documents (public)
+	wellKnown := e.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", r.wellKnownHandler.JWKS)
+		wellKnown.GET("/openid-configuration", r.oauthHandler.OpenIDConfiguration)
+	}
+
+	authMiddleware := customMiddleware.AuthMiddleware(r.keys, r.revocationService, r.apiKeyService, r.sessionService, r.cfg)
+	oauthBearer := customMiddleware.OAuthBearer(r.keys, r.revocationService, r.cfg)
+	requireScope := customMiddleware.RequireScope
+	denyImpersonation := customMiddleware.DenyImpersonation()
+	requireUser := customMiddleware.RequireUser()
+	requirePermission := func(permissions ...string) echo.MiddlewareFunc {
+		return customMiddleware.RequirePermission(r.rbacService, permissions...)
+	}
+
+	// OpenID Connect provider
+	oauth := e.Group("/oauth")
+	{
+		oauth.GET("/authorize", r.oauthHandler.Authorize)
+		oauth.POST("/authorize", r.oauthHandler.Decide, authMiddleware, requireUser, denyImpersonation)
+		oauth.POST("/token", r.oauthHandler.Token)
+		oauth.POST("/introspect", r.oauthHandler.Introspect)
+		oauth.POST("/revoke", r.oauthHandler.Revoke)
+		oauth.GET("/userinfo", r.oauthHandler.UserInfo, oauthBearer)
+		oauth.POST("/userinfo", r.oauthHandler.UserInfo, oauthBearer)
+	}
+
+	// Auth routes (public). Logins honour the X-Session-Mode header.
+	auth := e.Group("/auth", customMiddleware.SessionMode())
 	{
 		auth.POST("/register", r.authHandler.Register)
-		auth.POST("/login", r.authHandler.Login)
+		auth.POST("/login", r.authHandler.Login, customMiddleware.LoginGuard(r.loginGuardService, r.logger))
+		auth.POST("/refresh", r.authHandler.RefreshToken)
+		auth.POST("/logout", r.authHandler.Logout, authMiddleware)
+		auth.POST("/verify-email", r.authHandler.VerifyEmail)
+		auth.POST("/resend-verification", r.authHandler.ResendVerification)
+		auth.POST("/forgot-password", r.authHandler.ForgotPassword)
+		auth.POST("/reset-password", r.authHandler.ResetPassword)
+		auth.POST("/email-change/confirm", r.authHandler.ConfirmEmailChange)
+		auth.POST("/email-change/undo", r.authHandler.UndoEmailChange)
+		auth.POST("/magic-link", r.authHandler.RequestMagicLink)
+		auth.POST("/magic-link/verify", r.authHandler.MagicLinkLogin)
+		auth.POST("/mfa/verify", r.mfaHandler.Verify)
+
+		// Passkeys
+		auth.POST("/webauthn/register/begin", r.webAuthnHandler.BeginRegistration, authMiddleware, requireUser, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+		auth.POST("/webauthn/register/finish", r.webAuthnHandler.FinishRegistration, authMiddleware, requireUser, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+		auth.POST("/webauthn/login/begin", r.webAuthnHandler.BeginLogin)
+		auth.POST("/webauthn/login/finish", r.webAuthnHandler.FinishLogin)
+
+		// Sign-in through external identity providers
+		auth.GET("/federated", r.federationHandler.Providers)
+		auth.GET("/federated/:provider/login", r.federationHandler.Login)
+		auth.GET("/federated/:provider/callback", r.federationHandler.Callback)
 	}
 
 	// API v1 routes (protected)
 	apiV1 := e.Group("/api/v1")
-	apiV1.Use(customMiddleware.AuthMiddleware(r.cfg))
+	apiV1.Use(authMiddleware)
 	{
 		// User routes
-		users := apiV1.Group("/users")
+		users := apiV1.Group("/users", requireUser)
+		{
+			users.GET("/profile", r.authHandler.GetProfile, requireScope(model.ScopeProfileRead))
+			users.PUT("/profile", r.authHandler.UpdateProfile, requireScope(model.ScopeProfileWrite))
+			users.POST("/change-password", r.authHandler.ChangePassword, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+			users.POST("/change-email", r.authHandler.ChangeEmail, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+
+			// MFA enrollment
+			users.POST("/mfa/enroll", r.mfaHandler.Enroll, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+			users.POST("/mfa/confirm", r.mfaHandler.Confirm, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+			users.DELETE("/mfa", r.mfaHandler.Disable, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+
+			// Passkey management
+			users.GET("/webauthn/credentials", r.webAuthnHandler.ListCredentials, requireScope(model.ScopeAccountSecurity))
+			users.DELETE("/webauthn/credentials/:id", r.webAuthnHandler.DeleteCredential, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+
+			// Personal access tokens
+			users.GET("/tokens", r.apiKeyHandler.List, requireScope(model.ScopeTokensRead))
+			users.POST("/tokens", r.apiKeyHandler.Create, requireScope(model.ScopeTokensWrite), denyImpersonation)
+			users.DELETE("/tokens/:id", r.apiKeyHandler.Revoke, requireScope(model.ScopeTokensWrite), denyImpersonation)
+
+			// Active sessions
+			users.GET("/sessions", r.sessionHandler.List, requireScope(model.ScopeAccountSecurity))
+			users.DELETE("/sessions/:id", r.sessionHandler.Revoke, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+			users.POST("/sessions/revoke-others", r.sessionHandler.RevokeOthers, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+
+			// Account deletion and data export
+			users.DELETE("/me", r.accountHandler.Delete, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+			users.GET("/me/export", r.accountHandler.Export, requireScope(model.ScopeAccountSecurity), denyImpersonation)
+		}
+
+		// Organizations
+		orgs := apiV1.Group("/orgs", requireUser)
+		{
+			orgs.GET("", r.orgHandler.List, requireScope(model.ScopeOrgsRead))
+			orgs.POST("", r.orgHandler.Create, requireScope(model.ScopeOrgsWrite))
+			orgs.POST("/switch", r.orgHandler.Switch, requireScope(model.ScopeOrgsWrite))
+			orgs.POST("/invitations/accept", r.orgHandler.AcceptInvitation, requireScope(model.ScopeOrgsWrite), denyImpersonation)
+			orgs.POST("/invitations/decline", r.orgHandler.DeclineInvitation, requireScope(model.ScopeOrgsWrite), denyImpersonation)
+
+			// Tenant-scoped routes
+			requireOrgRole := customMiddleware.RequireOrgRole
+			org := orgs.Group("/:orgID", customMiddleware.RequireOrgMember(r.orgService))
+			{
+				org.GET("", r.orgHandler.Get, requireScope(model.ScopeOrgsRead))
+				org.PUT("", r.orgHandler.Update, requireScope(model.ScopeOrgsWrite), requireOrgRole(model.OrgRoleAdmin))
+				org.DELETE("", r.orgHandler.Delete, requireScope(model.ScopeOrgsWrite), requireOrgRole(model.OrgRoleOwner), denyImpersonation)
+				org.GET("/members", r.orgHandler.ListMembers, requireScope(model.ScopeOrgsRead))
+				org.PUT("/members/:userID", r.orgHandler.UpdateMemberRole, requireScope(model.ScopeOrgsWrite), requireOrgRole(model.OrgRoleAdmin))
+				org.DELETE("/members/:userID", r.orgHandler.RemoveMember, requireScope(model.ScopeOrgsWrite))
+				org.GET("/invitations", r.orgHandler.ListInvitations, requireScope(model.ScopeOrgsRead), requireOrgRole(model.OrgRoleAdmin))
+				org.POST("/invitations", r.orgHandler.Invite, requireScope(model.ScopeOrgsWrite), requireOrgRole(model.OrgRoleAdmin))
+				org.DELETE("/invitations/:id", r.orgHandler.RevokeInvitation, requireScope(model.ScopeOrgsWrite), requireOrgRole(model.OrgRoleAdmin))
+			}
+		}
+
+		// Admin routes
+		admin := apiV1.Group("/admin", denyImpersonation)
 		{
-			users.GET("/profile", r.authHandler.GetProfile)
-			users.PUT("/profile", r.authHandler.UpdateProfile)
-			users.POST("/change-password", r.authHandler.ChangePassword)
+			admin.GET("/roles", r.adminHandler.ListRoles, requirePermission(model.PermissionRolesManage))
+			admin.GET("/users/:userID/roles", r.adminHandler.GetUserRoles, requirePermission(model.PermissionUsersRead))
+			admin.POST("/users/:userID/roles", r.adminHandler.AssignRole, requirePermission(model.PermissionRolesManage))
+			admin.DELETE("/users/:userID/roles/:role", r.adminHandler.RemoveRole, requirePermission(model.PermissionRolesManage))
+			admin.POST("/users/:userID/revoke-tokens", r.adminHandler.RevokeUserTokens, requirePermission(model.PermissionUsersManage))
+			admin.POST("/users/:userID/unlock", r.adminHandler.UnlockUser, requirePermission(model.PermissionUsersManage))
+			admin.POST("/users/:userID/impersonate", r.adminHandler.Impersonate, requireUser, requirePermission(model.PermissionUsersImpersonate))
 		}
 	}
 
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// purgeBatchSize caps how many accounts one purge run deletes
+const purgeBatchSize = 100
+
+// AccountService lets users delete their account and take their data with them.
+// Deletion is soft at first: the account is purged once the grace period is over,
+// and logging in before then restores it (see TokenService.IssueTokens).
+type AccountService interface {
+	DeleteAccount(ctx context.Context, userID pgtype.UUID) (*model.AccountDeletionResponse, error)
+	ExportAccount(ctx context.Context, userID pgtype.UUID) (*model.AccountExport, error)
+	PurgeDeletedAccounts(ctx context.Context) (int, error)
+}
+
+type accountService struct {
+	userRepo            repository.UserRepository
+	roleRepo            repository.RoleRepository
+	mfaRepo             repository.MFARepository
+	webAuthnRepo        repository.WebAuthnRepository
+	apiKeyRepo          repository.APIKeyRepository
+	sessionRepo         repository.SessionRepository
+	refreshRepo         repository.RefreshTokenRepository
+	revocationRepo      repository.RevocationRepository
+	userTokenRepo       repository.UserTokenRepository
+	identityRepo        repository.IdentityRepository
+	oauthRepo           repository.OAuthRepository
+	orgRepo             repository.OrganizationRepository
+	lockoutRepo         repository.LockoutRepository
+	passwordHistoryRepo repository.PasswordHistoryRepository
+	magicLinkRepo       repository.MagicLinkRepository
+	revocationService   TokenRevocationService
+	config              *config.Config
+	logger              *zap.Logger
+}
+
+func NewAccountService(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	mfaRepo repository.MFARepository,
+	webAuthnRepo repository.WebAuthnRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	sessionRepo repository.SessionRepository,
+	refreshRepo repository.RefreshTokenRepository,
+	revocationRepo repository.RevocationRepository,
+	userTokenRepo repository.UserTokenRepository,
+	identityRepo repository.IdentityRepository,
+	oauthRepo repository.OAuthRepository,
+	orgRepo repository.OrganizationRepository,
+	lockoutRepo repository.LockoutRepository,
+	passwordHistoryRepo repository.PasswordHistoryRepository,
+	magicLinkRepo repository.MagicLinkRepository,
+	revocationService TokenRevocationService,
+	config *config.Config,
+	logger *zap.Logger,
+) AccountService {
+	return &accountService{
+		userRepo:            userRepo,
+		roleRepo:            roleRepo,
+		mfaRepo:             mfaRepo,
+		webAuthnRepo:        webAuthnRepo,
+		apiKeyRepo:          apiKeyRepo,
+		sessionRepo:         sessionRepo,
+		refreshRepo:         refreshRepo,
+		revocationRepo:      revocationRepo,
+		userTokenRepo:       userTokenRepo,
+		identityRepo:        identityRepo,
+		oauthRepo:           oauthRepo,
+		orgRepo:             orgRepo,
+		lockoutRepo:         lockoutRepo,
+		passwordHistoryRepo: passwordHistoryRepo,
+		magicLinkRepo:       magicLinkRepo,
+		revocationService:   revocationService,
+		config:              config,
+		logger:              logger,
+	}
+}
+
+// DeleteAccount schedules the purge and logs the user out everywhere. API keys
+// stop working because their lookup skips deleted users.
+func (s *accountService) DeleteAccount(ctx context.Context, userID pgtype.UUID) (*model.AccountDeletionResponse, error) {
+	purgeAt := time.Now().Add(s.config.Auth.AccountDeletionGracePeriod)
+	if err := s.userRepo.SoftDeleteUser(ctx, userID, purgeAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.revocationService.RevokeAllUserTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account scheduled for deletion",
+		zap.String("user_id", userID.String()),
+		zap.Time("purge_at", purgeAt),
+	)
+	return &model.AccountDeletionResponse{
+		Message: "Account deleted. Log in before the purge date to restore it.",
+		PurgeAt: purgeAt,
+	}, nil
+}
+
+// ExportAccount collects the rows of every table keyed by the user, or by their
+// email address for invitations and magic link requests
+func (s *accountService) ExportAccount(ctx context.Context, userID pgtype.UUID) (*model.AccountExport, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &model.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		User:       user,
+	}
+
+	if export.Roles, err = s.roleRepo.GetUserRoles(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	export.MFA, err = s.mfaRepo.GetMFA(ctx, userID)
+	if err != nil && err.Error() != constants.ErrMFANotEnrolled {
+		return nil, err
+	}
+
+	if export.RecoveryCodes, err = s.mfaRepo.ListRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Passkeys, err = s.webAuthnRepo.ListCredentials(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.WebAuthnCeremonies, err = s.webAuthnRepo.ListSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.APIKeys, err = s.apiKeyRepo.ListAPIKeys(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = s.sessionRepo.ListSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.RefreshTokens, err = s.refreshRepo.ListRefreshTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.RevokedTokens, err = s.revocationRepo.ListRevokedTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.EmailTokens, err = s.userTokenRepo.ListUserTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Identities, err = s.identityRepo.ListIdentities(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.OAuthGrants, err = s.oauthRepo.ListAuthorizationGrants(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Organizations, err = s.orgRepo.ListUserOrganizations(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Invitations, err = s.orgRepo.ListUserInvitations(ctx, userID, utils.NormalizeEmail(user.Email)); err != nil {
+		return nil, err
+	}
+	if export.LoginFailures, err = s.lockoutRepo.GetLoginFailures(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.PasswordChanges, err = s.passwordHistoryRepo.ListPasswordChanges(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.MagicLinkRequests, err = s.magicLinkRepo.ListMagicLinkRequests(ctx, utils.NormalizeEmail(user.Email)); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account data exported", zap.String("user_id", userID.String()))
+	return export, nil
+}
+
+// PurgeDeletedAccounts hard-deletes accounts whose grace period is over. Every
+// replica runs it; an account restored meanwhile or taken by another replica is
+// skipped. A user that fails to purge is logged and retried on the next run.
+func (s *accountService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	ids, err := s.userRepo.ListUsersDueForPurge(ctx, purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := s.userRepo.DeleteUser(ctx, id); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return purged, err
+			}
+			if err.Error() == constants.ErrUserNotFound {
+				continue
+			}
+			s.logger.Error("Failed to purge deleted account",
+				zap.String("user_id", id.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		purged++
+		s.logger.Info("Deleted account purged", zap.String("user_id", id.String()))
+	}
+
+	return purged, nil
+}
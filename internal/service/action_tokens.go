@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+)
+
+// actionTokens issues signed links that can be redeemed once. The signature binds
+// the token to a user and purpose; the hashed jti in user_tokens makes it single-use.
+type actionTokens struct {
+	userTokenRepo repository.UserTokenRepository
+	keys          *utils.KeyRing
+}
+
+// issue replaces any outstanding token of the same purpose with a fresh one
+func (a *actionTokens) issue(ctx context.Context, user *model.User, purpose string, ttl time.Duration) (string, error) {
+	if err := a.userTokenRepo.DeleteUserTokens(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+
+	return a.add(ctx, user, purpose, ttl)
+}
+
+// add issues a token and leaves earlier ones of the same purpose valid
+func (a *actionTokens) add(ctx context.Context, user *model.User, purpose string, ttl time.Duration) (string, error) {
+	token, tokenID, expiresAt, err := utils.GenerateActionToken(user, purpose, ttl, a.keys)
+	if err != nil {
+		return "", err
+	}
+
+	if err := a.userTokenRepo.CreateUserToken(ctx, user.ID, purpose, utils.HashToken(tokenID), expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// issuedWithin reports whether a token of the purpose was issued to the user in
+// the last d, to keep the same email from being sent over and over
+func (a *actionTokens) issuedWithin(ctx context.Context, userID pgtype.UUID, purpose string, d time.Duration) (bool, error) {
+	if d <= 0 {
+		return false, nil
+	}
+	return a.userTokenRepo.HasRecentUserToken(ctx, userID, purpose, d)
+}
+
+// peek checks the token's signature and purpose without using it up
+func (a *actionTokens) peek(token, purpose string) (*utils.ActionClaims, error) {
+	claims, err := utils.ValidateActionToken(token, purpose, a.keys)
+	if err != nil {
+		return nil, errors.New(constants.ErrInvalidActionToken)
+	}
+	return claims, nil
+}
+
+// attempt counts a use of a token that guards a guess, such as an MFA code, and
+// fails once maxAttempts were made. The token stays valid until consumed.
+func (a *actionTokens) attempt(ctx context.Context, token, purpose string, maxAttempts int) (*utils.ActionClaims, error) {
+	claims, err := a.peek(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := a.userTokenRepo.AttemptUserToken(ctx, purpose, utils.HashToken(claims.ID), maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != claims.UserID {
+		return nil, errors.New(constants.ErrInvalidActionToken)
+	}
+
+	return claims, nil
+}
+
+func (a *actionTokens) consume(ctx context.Context, token, purpose string) (*utils.ActionClaims, error) {
+	claims, err := a.peek(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := a.userTokenRepo.ConsumeUserToken(ctx, purpose, utils.HashToken(claims.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != claims.UserID {
+		return nil, errors.New(constants.ErrInvalidActionToken)
+	}
+
+	return claims, nil
+}
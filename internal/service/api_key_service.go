@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// APIKeyPrefix starts every API key so AuthMiddleware can tell keys from JWTs
+// and secret scanners can spot leaked keys
+const APIKeyPrefix = "gep_"
+
+const (
+	apiKeyBytes          = 32
+	apiKeyDisplayLen     = 8
+	apiKeyDefaultTTLDays = 90
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID pgtype.UUID, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id pgtype.UUID) error
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	config     *config.Config
+	logger     *zap.Logger
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, config *config.Config, logger *zap.Logger) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID pgtype.UUID, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error) {
+	secret, err := utils.GenerateRandomToken(apiKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	rawKey := APIKeyPrefix + secret
+
+	ttlDays := req.ExpiresInDays
+	if ttlDays == 0 {
+		ttlDays = apiKeyDefaultTTLDays
+	}
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+
+	key, err := s.apiKeyRepo.CreateAPIKey(ctx, &model.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    rawKey[:len(APIKeyPrefix)+apiKeyDisplayLen],
+		KeyHash:   utils.HashToken(rawKey),
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().AddDate(0, 0, ttlDays), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("API key created",
+		zap.String("user_id", userID.String()),
+		zap.String("api_key_id", key.ID.String()),
+		zap.Strings("scopes", key.Scopes),
+	)
+
+	return &model.CreateAPIKeyResponse{APIKey: key, Key: rawKey}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID pgtype.UUID) ([]*model.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys(ctx, userID)
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, id pgtype.UUID) error {
+	if err := s.apiKeyRepo.DeleteAPIKey(ctx, userID, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("API key revoked",
+		zap.String("user_id", userID.String()),
+		zap.String("api_key_id", id.String()),
+	)
+	return nil
+}
+
+// Authenticate resolves a presented key and records its use
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return nil, errors.New(constants.ErrInvalidAPIKey)
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, utils.HashToken(rawKey))
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to record usage must not fail the request
+	if err := s.apiKeyRepo.TouchAPIKey(ctx, key.ID); err != nil {
+		s.logger.Warn("Failed to record api key usage", zap.String("api_key_id", key.ID.String()), zap.Error(err))
+	}
+
+	return key, nil
+}
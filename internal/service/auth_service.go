@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/manish-npx/go-echo-pg/internal/config"
@@ -12,48 +14,104 @@ import (
 	"github.com/manish-npx/go-echo-pg/internal/repository"
 	"github.com/manish-npx/go-echo-pg/internal/utils"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// backgroundEmailTimeout bounds an email sent after the response went out
+const backgroundEmailTimeout = 30 * time.Second
+
 type AuthService interface {
 	Register(ctx context.Context, req *model.CreateUserRequest) (*model.AuthResponse, error)
 	Login(ctx context.Context, req *model.LoginRequest) (*model.AuthResponse, error)
 	GetUserProfile(ctx context.Context, userID pgtype.UUID) (*model.User, error)
 	UpdateUserProfile(ctx context.Context, userID pgtype.UUID, req *model.UpdateUserRequest) (*model.User, error)
+	RequestEmailChange(ctx context.Context, userID pgtype.UUID, req *model.ChangeEmailRequest) error
+	ConfirmEmailChange(ctx context.Context, req *model.EmailChangeTokenRequest) error
+	UndoEmailChange(ctx context.Context, req *model.EmailChangeTokenRequest) error
 	ChangePassword(ctx context.Context, userID pgtype.UUID, req *model.ChangePasswordRequest) error
+	RefreshToken(ctx context.Context, req *model.RefreshTokenRequest) (*model.AuthResponse, error)
+	Logout(ctx context.Context, claims *utils.Claims, req *model.LogoutRequest) error
+	EndSession(ctx context.Context, session *model.Session) error
+	VerifyEmail(ctx context.Context, req *model.VerifyEmailRequest) error
+	ResendVerification(ctx context.Context, req *model.ResendVerificationRequest) error
+	ForgotPassword(ctx context.Context, req *model.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req *model.ResetPasswordRequest) error
+	RequestMagicLink(ctx context.Context, req *model.MagicLinkRequest) error
+	MagicLinkLogin(ctx context.Context, req *model.MagicLinkLoginRequest) (*model.AuthResponse, error)
+	PruneMagicLinkRequests(ctx context.Context) (int, error)
 }
 
 type authService struct {
-	userRepo repository.UserRepository
-	config   *config.Config
-	logger   *zap.Logger
+	userRepo          repository.UserRepository
+	magicLinkRepo     repository.MagicLinkRepository
+	tokenService      TokenService
+	revocationService TokenRevocationService
+	emailService      EmailService
+	mfaService        MFAService
+	lockoutService    LockoutService
+	passwordHasher    *utils.PasswordHasher
+	passwordPolicy    PasswordPolicyService
+	actionTokens      *actionTokens
+	config            *config.Config
+	logger            *zap.Logger
 }
 
-func NewAuthService(userRepo repository.UserRepository, config *config.Config, logger *zap.Logger) AuthService {
+func NewAuthService(
+	userRepo repository.UserRepository,
+	userTokenRepo repository.UserTokenRepository,
+	magicLinkRepo repository.MagicLinkRepository,
+	tokenService TokenService,
+	revocationService TokenRevocationService,
+	emailService EmailService,
+	mfaService MFAService,
+	lockoutService LockoutService,
+	passwordHasher *utils.PasswordHasher,
+	passwordPolicy PasswordPolicyService,
+	keys *utils.KeyRing,
+	config *config.Config,
+	logger *zap.Logger,
+) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		config:   config,
-		logger:   logger,
+		userRepo:          userRepo,
+		magicLinkRepo:     magicLinkRepo,
+		tokenService:      tokenService,
+		revocationService: revocationService,
+		emailService:      emailService,
+		mfaService:        mfaService,
+		lockoutService:    lockoutService,
+		passwordHasher:    passwordHasher,
+		passwordPolicy:    passwordPolicy,
+		actionTokens:      &actionTokens{userTokenRepo: userTokenRepo, keys: keys},
+		config:            config,
+		logger:            logger,
 	}
 }
 
 func (s *authService) Register(ctx context.Context, req *model.CreateUserRequest) (*model.AuthResponse, error) {
+	req.Email = utils.NormalizeEmail(req.Email)
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if err == nil && existingUser != nil {
 		return nil, errors.New(constants.ErrUserExists)
 	}
 
+	if err := s.passwordPolicy.Validate(ctx, "password", req.Password, &model.User{Email: req.Email, Name: req.Name}); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing password: %w", err)
+	}
+
 	// Create user
-	user, err := s.userRepo.CreateUser(ctx, req)
+	user, err := s.userRepo.CreateUser(ctx, req.Email, passwordHash, req.Name)
 	if err != nil {
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
 
-	// Generate token
-	token, expiresAt, err := utils.GenerateToken(user, s.config)
-	if err != nil {
-		return nil, fmt.Errorf("error generating token: %w", err)
+	if err := s.passwordPolicy.Remember(ctx, user.ID, passwordHash); err != nil {
+		return nil, fmt.Errorf("error recording password history: %w", err)
 	}
 
 	s.logger.Info("User registered successfully",
@@ -61,29 +119,93 @@ func (s *authService) Register(ctx context.Context, req *model.CreateUserRequest
 		zap.String("user_id", user.ID.String()),
 	)
 
-	return &model.AuthResponse{
-		User:      user,
-		Token:     token,
-		ExpiresAt: expiresAt,
-	}, nil
+	// A failed email must not fail the registration; the user can ask for a resend
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		s.logger.Error("Failed to send verification email",
+			zap.String("user_id", user.ID.String()),
+			zap.Error(err),
+		)
+	}
+
+	if s.config.Auth.RequireEmailVerification {
+		return &model.AuthResponse{
+			User:                      user,
+			EmailVerificationRequired: true,
+		}, nil
+	}
+
+	// Generate tokens
+	return s.tokenService.IssueTokens(ctx, user)
 }
 
 func (s *authService) Login(ctx context.Context, req *model.LoginRequest) (*model.AuthResponse, error) {
+	req.Email = utils.NormalizeEmail(req.Email)
 	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		s.logger.Warn("Login attempt with non-existent email", zap.String("email", req.Email))
 		return nil, errors.New(constants.ErrInvalidCredentials)
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	// Checked before the password so a locked account answers the same whatever
+	// password is tried. The distinct error tells the owner why they cannot log in.
+	if err := s.lockoutService.Check(ctx, user.ID); err != nil {
+		s.logger.Warn("Login attempt on locked or throttled account",
+			zap.String("email", req.Email),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	valid, err := s.passwordHasher.Verify(user.Password, req.Password)
 	if err != nil {
+		return nil, fmt.Errorf("error verifying password: %w", err)
+	}
+	if !valid {
 		s.logger.Warn("Invalid password attempt", zap.String("email", req.Email))
+		if err := s.lockoutService.RecordFailure(ctx, user.ID); err != nil {
+			s.logger.Error("Failed to record login failure", zap.Error(err))
+		}
 		return nil, errors.New(constants.ErrInvalidCredentials)
 	}
 
-	token, expiresAt, err := utils.GenerateToken(user, s.config)
+	s.rehashPassword(ctx, user, req.Password)
+
+	// The correct but expired password only earns a reset link
+	if s.passwordPolicy.IsExpired(user) {
+		s.logger.Info("Login with expired password, sending reset link", zap.String("user_id", user.ID.String()))
+		if err := s.sendPasswordResetEmail(ctx, user); err != nil {
+			s.logger.Error("Failed to send password reset email", zap.Error(err))
+		}
+		return nil, errors.New(constants.ErrPasswordExpired)
+	}
+
+	if s.config.Auth.RequireEmailVerification && !user.EmailVerifiedAt.Valid {
+		s.logger.Warn("Login attempt with unverified email", zap.String("email", req.Email))
+		return nil, errors.New(constants.ErrEmailNotVerified)
+	}
+
+	mfaEnabled, err := s.mfaService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking mfa: %w", err)
+	}
+	if mfaEnabled {
+		challenge, err := s.mfaService.CreateChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		s.logger.Info("Password accepted, MFA challenge issued", zap.String("user_id", user.ID.String()))
+		return nil, &MFARequiredError{Challenge: challenge}
+	}
+
+	// Failures are only forgiven once the login is complete; with MFA that is
+	// after the second factor (see MFAService.VerifyChallenge)
+	if err := s.lockoutService.Reset(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("error resetting login failures: %w", err)
+	}
+
+	authResponse, err := s.tokenService.IssueTokens(ctx, user)
 	if err != nil {
-		return nil, fmt.Errorf("error generating token: %w", err)
+		return nil, err
 	}
 
 	s.logger.Info("User logged in successfully",
@@ -91,11 +213,317 @@ func (s *authService) Login(ctx context.Context, req *model.LoginRequest) (*mode
 		zap.String("user_id", user.ID.String()),
 	)
 
-	return &model.AuthResponse{
-		User:      user,
-		Token:     token,
-		ExpiresAt: expiresAt,
-	}, nil
+	return authResponse, nil
+}
+
+// rehashPassword upgrades a stored hash made with an older algorithm or weaker
+// parameters. The login goes ahead even if the upgrade fails.
+func (s *authService) rehashPassword(ctx context.Context, user *model.User, password string) {
+	if !s.passwordHasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err == nil {
+		err = s.userRepo.RehashPassword(ctx, user.ID, passwordHash)
+	}
+	if err != nil {
+		s.logger.Error("Failed to upgrade password hash",
+			zap.String("user_id", user.ID.String()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	user.Password = passwordHash
+	s.logger.Info("Password hash upgraded", zap.String("user_id", user.ID.String()))
+}
+
+func (s *authService) RefreshToken(ctx context.Context, req *model.RefreshTokenRequest) (*model.AuthResponse, error) {
+	authResponse, err := s.tokenService.RefreshTokens(ctx, req.RefreshToken, "")
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Tokens refreshed",
+		zap.String("user_id", authResponse.User.ID.String()),
+	)
+
+	return authResponse, nil
+}
+
+func (s *authService) Logout(ctx context.Context, claims *utils.Claims, req *model.LogoutRequest) error {
+	if err := s.revocationService.RevokeToken(ctx, claims); err != nil {
+		return fmt.Errorf("error revoking token: %w", err)
+	}
+
+	if claims.SessionID.Valid {
+		if err := s.revocationService.RevokeSession(ctx, claims.SessionID); err != nil {
+			return fmt.Errorf("error revoking session: %w", err)
+		}
+	}
+
+	if req.RefreshToken != "" {
+		if err := s.tokenService.RevokeRefreshToken(ctx, claims.UserID, req.RefreshToken); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("User logged out", zap.String("user_id", claims.UserID.String()))
+	return nil
+}
+
+// EndSession logs out a cookie session
+func (s *authService) EndSession(ctx context.Context, session *model.Session) error {
+	if err := s.revocationService.RevokeSession(ctx, session.ID); err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+
+	s.logger.Info("User logged out", zap.String("user_id", session.UserID.String()))
+	return nil
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, req *model.VerifyEmailRequest) error {
+	claims, err := s.actionTokens.consume(ctx, req.Token, model.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	// The link only proves ownership of the address it was sent to
+	if user.Email != claims.Email {
+		return errors.New(constants.ErrInvalidActionToken)
+	}
+
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Email verified", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+// ResendVerification never reveals whether the address is registered or already verified
+func (s *authService) ResendVerification(ctx context.Context, req *model.ResendVerificationRequest) error {
+	req.Email = utils.NormalizeEmail(req.Email)
+	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err.Error() == constants.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *authService) sendVerificationEmail(ctx context.Context, user *model.User) error {
+	token, err := s.actionTokens.issue(ctx, user, model.TokenPurposeEmailVerification, s.config.Auth.EmailVerificationTTL)
+	if err != nil {
+		return fmt.Errorf("error issuing verification token: %w", err)
+	}
+
+	return s.emailService.SendVerificationEmail(ctx, user, token)
+}
+
+// ForgotPassword behaves identically whether or not the email is registered. The
+// email is sent in the background so response timing does not leak it either.
+func (s *authService) ForgotPassword(ctx context.Context, req *model.ForgotPasswordRequest) error {
+	req.Email = utils.NormalizeEmail(req.Email)
+	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err.Error() == constants.ErrUserNotFound {
+			s.logger.Info("Password reset requested for unknown email", zap.String("email", req.Email))
+			return nil
+		}
+		return err
+	}
+
+	s.sendInBackground(ctx, user, "password reset email", func(ctx context.Context) error {
+		return s.sendPasswordResetEmail(ctx, user)
+	})
+
+	return nil
+}
+
+func (s *authService) ResetPassword(ctx context.Context, req *model.ResetPasswordRequest) error {
+	claims, err := s.actionTokens.peek(req.Token, model.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	if user.Email != claims.Email {
+		return errors.New(constants.ErrInvalidActionToken)
+	}
+
+	// Checked before the link is used up, so a rejected password can be retried
+	if err := s.passwordPolicy.Validate(ctx, "new_password", req.NewPassword, user); err != nil {
+		return err
+	}
+
+	if _, err := s.actionTokens.consume(ctx, req.Token, model.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return fmt.Errorf("error updating password: %w", err)
+	}
+
+	if err := s.passwordPolicy.Remember(ctx, user.ID, passwordHash); err != nil {
+		return fmt.Errorf("error recording password history: %w", err)
+	}
+
+	// Whoever held the old password must lose access
+	if err := s.revocationService.RevokeAllUserTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("error revoking tokens: %w", err)
+	}
+
+	// A successful reset is the self-service way out of a lockout
+	if err := s.lockoutService.Reset(ctx, user.ID); err != nil {
+		return fmt.Errorf("error resetting login failures: %w", err)
+	}
+
+	// Redeeming the emailed link also proves ownership of the address
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Password reset successfully", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+// sendPasswordResetEmail sends at most one link per auth.password_reset_cooldown,
+// whether it was asked for through ForgotPassword or by logging in with an
+// expired password. The earlier link stays valid in the meantime.
+func (s *authService) sendPasswordResetEmail(ctx context.Context, user *model.User) error {
+	recent, err := s.actionTokens.issuedWithin(ctx, user.ID, model.TokenPurposePasswordReset, s.config.Auth.PasswordResetCooldown)
+	if err != nil {
+		return err
+	}
+	if recent {
+		s.logger.Info("Password reset email throttled", zap.String("user_id", user.ID.String()))
+		return nil
+	}
+
+	token, err := s.actionTokens.issue(ctx, user, model.TokenPurposePasswordReset, s.config.Auth.PasswordResetTTL)
+	if err != nil {
+		return fmt.Errorf("error issuing password reset token: %w", err)
+	}
+
+	return s.emailService.SendPasswordResetEmail(ctx, user, token)
+}
+
+// RequestMagicLink emails a one-time login link. The per-email rate limit applies
+// whether or not the address is registered, so neither the response nor its
+// timing reveals which addresses have accounts.
+func (s *authService) RequestMagicLink(ctx context.Context, req *model.MagicLinkRequest) error {
+	// Keyed by the normalized email so the limit cannot be sidestepped by changing case
+	req.Email = utils.NormalizeEmail(req.Email)
+	requests, err := s.magicLinkRepo.RecordMagicLinkRequest(ctx, req.Email, s.config.Auth.MagicLinkRateWindow)
+	if err != nil {
+		return err
+	}
+	if requests > s.config.Auth.MagicLinkRateLimit {
+		s.logger.Warn("Magic link rate limit exceeded", zap.String("email", req.Email))
+		return errors.New(constants.ErrMagicLinkRateLimited)
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err.Error() == constants.ErrUserNotFound {
+			s.logger.Info("Magic link requested for unknown email", zap.String("email", req.Email))
+			return nil
+		}
+		return err
+	}
+
+	s.sendInBackground(ctx, user, "magic link email", func(ctx context.Context) error {
+		return s.sendMagicLinkEmail(ctx, user)
+	})
+
+	return nil
+}
+
+// MagicLinkLogin redeems a login link. The link stands in for the password only;
+// lockouts and MFA still apply.
+func (s *authService) MagicLinkLogin(ctx context.Context, req *model.MagicLinkLoginRequest) (*model.AuthResponse, error) {
+	claims, err := s.actionTokens.consume(ctx, req.Token, model.TokenPurposeMagicLink)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	if user.Email != claims.Email {
+		return nil, errors.New(constants.ErrInvalidActionToken)
+	}
+
+	if err := s.lockoutService.Check(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	// Redeeming the emailed link proves ownership of the address
+	if !user.EmailVerifiedAt.Valid {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	mfaEnabled, err := s.mfaService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking mfa: %w", err)
+	}
+	if mfaEnabled {
+		challenge, err := s.mfaService.CreateChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		s.logger.Info("Magic link accepted, MFA challenge issued", zap.String("user_id", user.ID.String()))
+		return nil, &MFARequiredError{Challenge: challenge}
+	}
+
+	authResponse, err := s.tokenService.IssueTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User logged in with magic link", zap.String("user_id", user.ID.String()))
+	return authResponse, nil
+}
+
+// PruneMagicLinkRequests deletes requests that no longer count toward the rate limit
+func (s *authService) PruneMagicLinkRequests(ctx context.Context) (int, error) {
+	return s.magicLinkRepo.DeleteMagicLinkRequests(ctx, s.config.Auth.MagicLinkRateWindow)
+}
+
+func (s *authService) sendMagicLinkEmail(ctx context.Context, user *model.User) error {
+	token, err := s.actionTokens.issue(ctx, user, model.TokenPurposeMagicLink, s.config.Auth.MagicLinkTTL)
+	if err != nil {
+		return fmt.Errorf("error issuing magic link token: %w", err)
+	}
+
+	return s.emailService.SendMagicLinkEmail(ctx, user, token)
 }
 
 func (s *authService) GetUserProfile(ctx context.Context, userID pgtype.UUID) (*model.User, error) {
@@ -108,13 +536,7 @@ func (s *authService) GetUserProfile(ctx context.Context, userID pgtype.UUID) (*
 }
 
 func (s *authService) UpdateUserProfile(ctx context.Context, userID pgtype.UUID, req *model.UpdateUserRequest) (*model.User, error) {
-	// Cast to concrete type to access UpdateUser method
-	repo, ok := s.userRepo.(*repository.UserRepositoryImpl)
-	if !ok {
-		return nil, errors.New("repository type assertion failed")
-	}
-
-	user, err := repo.UpdateUser(ctx, userID, req)
+	user, err := s.userRepo.UpdateUser(ctx, userID, req)
 	if err != nil {
 		return nil, fmt.Errorf("error updating user profile: %w", err)
 	}
@@ -127,6 +549,108 @@ func (s *authService) UpdateUserProfile(ctx context.Context, userID pgtype.UUID,
 	return user, nil
 }
 
+// RequestEmailChange parks the new address until its owner confirms it. The
+// current address is told about the change and can undo it, so a stolen token
+// alone cannot take over the account.
+func (s *authService) RequestEmailChange(ctx context.Context, userID pgtype.UUID, req *model.ChangeEmailRequest) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	req.Email = utils.NormalizeEmail(req.Email)
+	if strings.EqualFold(req.Email, user.Email) {
+		return errors.New(constants.ErrEmailUnchanged)
+	}
+
+	if _, err := s.userRepo.GetUserByEmail(ctx, req.Email); err == nil {
+		return errors.New(constants.ErrUserExists)
+	} else if err.Error() != constants.ErrUserNotFound {
+		return err
+	}
+
+	if err := s.userRepo.SetPendingEmail(ctx, userID, pgtype.Text{String: req.Email, Valid: true}); err != nil {
+		return err
+	}
+
+	// The confirmation token is bound to the new address, the undo token to the
+	// current one. Undo tokens are not replaced by later requests, so a second
+	// change cannot take away the first one's undo link.
+	pending := *user
+	pending.Email = req.Email
+	confirmToken, err := s.actionTokens.issue(ctx, &pending, model.TokenPurposeEmailChange, s.config.Auth.EmailChangeTTL)
+	if err != nil {
+		return fmt.Errorf("error issuing email change token: %w", err)
+	}
+	undoToken, err := s.actionTokens.add(ctx, user, model.TokenPurposeEmailChangeUndo, s.config.Auth.EmailChangeUndoTTL)
+	if err != nil {
+		return fmt.Errorf("error issuing email change undo token: %w", err)
+	}
+
+	s.sendInBackground(ctx, user, "email change confirmation", func(ctx context.Context) error {
+		return s.emailService.SendEmailChangeConfirmation(ctx, user, req.Email, confirmToken)
+	})
+	s.sendInBackground(ctx, user, "email change notice", func(ctx context.Context) error {
+		return s.emailService.SendEmailChangeNotice(ctx, user, req.Email, undoToken)
+	})
+
+	s.logger.Info("Email change requested", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+func (s *authService) ConfirmEmailChange(ctx context.Context, req *model.EmailChangeTokenRequest) error {
+	claims, err := s.actionTokens.consume(ctx, req.Token, model.TokenPurposeEmailChange)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	// The change may have been undone or superseded since the link was sent
+	if !user.PendingEmail.Valid || user.PendingEmail.String != claims.Email {
+		return errors.New(constants.ErrInvalidActionToken)
+	}
+
+	if _, err := s.userRepo.ChangeEmail(ctx, user.ID, claims.Email); err != nil {
+		return err
+	}
+
+	s.logger.Info("Email changed", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+// UndoEmailChange cancels a pending change or restores the address the link was
+// sent to, and signs out every session in case the change was not the user's
+func (s *authService) UndoEmailChange(ctx context.Context, req *model.EmailChangeTokenRequest) error {
+	claims, err := s.actionTokens.consume(ctx, req.Token, model.TokenPurposeEmailChangeUndo)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	if user.Email != claims.Email {
+		if _, err := s.userRepo.ChangeEmail(ctx, user.ID, claims.Email); err != nil {
+			return err
+		}
+	} else if err := s.userRepo.SetPendingEmail(ctx, user.ID, pgtype.Text{}); err != nil {
+		return err
+	}
+
+	if err := s.revocationService.RevokeAllUserTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.logger.Warn("Email change undone", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
 func (s *authService) ChangePassword(ctx context.Context, userID pgtype.UUID, req *model.ChangePasswordRequest) error {
 	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
@@ -134,28 +658,57 @@ func (s *authService) ChangePassword(ctx context.Context, userID pgtype.UUID, re
 	}
 
 	// Verify old password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword))
+	valid, err := s.passwordHasher.Verify(user.Password, req.OldPassword)
 	if err != nil {
+		return fmt.Errorf("error verifying password: %w", err)
+	}
+	if !valid {
 		return errors.New("invalid current password")
 	}
 
+	if err := s.passwordPolicy.Validate(ctx, "new_password", req.NewPassword, user); err != nil {
+		return err
+	}
+
 	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	passwordHash, err := s.passwordHasher.Hash(req.NewPassword)
 	if err != nil {
 		return fmt.Errorf("error hashing password: %w", err)
 	}
 
 	// Update password using repository
-	repo, ok := s.userRepo.(*repository.UserRepositoryImpl)
-	if !ok {
-		return errors.New("repository type assertion failed")
-	}
-
-	err = repo.UpdatePassword(ctx, userID, string(hashedPassword))
+	err = s.userRepo.UpdatePassword(ctx, userID, passwordHash)
 	if err != nil {
 		return fmt.Errorf("error updating password: %w", err)
 	}
 
+	if err := s.passwordPolicy.Remember(ctx, userID, passwordHash); err != nil {
+		return fmt.Errorf("error recording password history: %w", err)
+	}
+
+	// Invalidate every session issued with the old password
+	if err := s.revocationService.RevokeAllUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("error revoking tokens: %w", err)
+	}
+
 	s.logger.Info("Password changed successfully", zap.String("user_id", userID.String()))
 	return nil
 }
+
+// sendInBackground sends an email to user after the request has been answered,
+// so the response time does not depend on the mail server. The send gets its
+// own deadline since the request context ends with the response. Failures can
+// only be logged.
+func (s *authService) sendInBackground(ctx context.Context, user *model.User, what string, send func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundEmailTimeout)
+		defer cancel()
+
+		if err := send(ctx); err != nil {
+			s.logger.Error("Failed to send "+what,
+				zap.String("user_id", user.ID.String()),
+				zap.Error(err),
+			)
+		}
+	}()
+}
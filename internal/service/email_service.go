@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/mailer"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"go.uber.org/zap"
+)
+
+// EmailService renders and sends transactional email
+type EmailService interface {
+	SendVerificationEmail(ctx context.Context, user *model.User, token string) error
+	SendPasswordResetEmail(ctx context.Context, user *model.User, token string) error
+	SendMagicLinkEmail(ctx context.Context, user *model.User, token string) error
+	SendInvitationEmail(ctx context.Context, invitation *model.Invitation, org *model.Organization, inviter *model.Membership, token string) error
+	SendEmailChangeConfirmation(ctx context.Context, user *model.User, newEmail, token string) error
+	SendEmailChangeNotice(ctx context.Context, user *model.User, newEmail, token string) error
+}
+
+type emailService struct {
+	mailer mailer.Mailer
+	config *config.Config
+	logger *zap.Logger
+}
+
+func NewEmailService(mailer mailer.Mailer, config *config.Config, logger *zap.Logger) EmailService {
+	return &emailService{
+		mailer: mailer,
+		config: config,
+		logger: logger,
+	}
+}
+
+func (s *emailService) SendVerificationEmail(ctx context.Context, user *model.User, token string) error {
+	link := s.link("/verify-email", token)
+	body := fmt.Sprintf(`Hi %s,
+
+Please confirm your email address by opening the link below:
+
+%s
+
+The link expires in %s. If you did not create an account, you can ignore this email.
+`, user.Name, link, s.config.Auth.EmailVerificationTTL)
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    body,
+	})
+}
+
+func (s *emailService) SendPasswordResetEmail(ctx context.Context, user *model.User, token string) error {
+	link := s.link("/reset-password", token)
+	body := fmt.Sprintf(`Hi %s,
+
+We received a request to reset your password. Open the link below to choose a new one:
+
+%s
+
+The link expires in %s and can only be used once. If you did not ask for a reset, you can ignore this email.
+`, user.Name, link, s.config.Auth.PasswordResetTTL)
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	})
+}
+
+func (s *emailService) SendMagicLinkEmail(ctx context.Context, user *model.User, token string) error {
+	link := s.link("/magic-link", token)
+	body := fmt.Sprintf(`Hi %s,
+
+Open the link below to sign in:
+
+%s
+
+The link expires in %s and can only be used once. If you did not try to sign in, you can ignore this email.
+`, user.Name, link, s.config.Auth.MagicLinkTTL)
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body:    body,
+	})
+}
+
+func (s *emailService) SendInvitationEmail(ctx context.Context, invitation *model.Invitation, org *model.Organization, inviter *model.Membership, token string) error {
+	link := s.link("/invitations", token)
+	body := fmt.Sprintf(`Hi,
+
+%s (%s) has invited you to join %s as %s. Open the link below to accept or decline:
+
+%s
+
+The link expires in %s. If you do not have an account yet, sign up with this email address first.
+`, inviter.Name, inviter.Email, org.Name, invitation.Role, link, s.config.Auth.InvitationTTL)
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s", org.Name),
+		Body:    body,
+	})
+}
+
+// SendEmailChangeConfirmation goes to the new address, which only becomes the
+// account's email once the link is opened
+func (s *emailService) SendEmailChangeConfirmation(ctx context.Context, user *model.User, newEmail, token string) error {
+	link := s.link("/confirm-email-change", token)
+	body := fmt.Sprintf(`Hi %s,
+
+Please confirm that you want to use this address for your account by opening the link below:
+
+%s
+
+The link expires in %s. Until then your account keeps using %s. If you did not ask for this, you can ignore this email.
+`, user.Name, link, s.config.Auth.EmailChangeTTL, user.Email)
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body:    body,
+	})
+}
+
+// SendEmailChangeNotice warns the current address and lets it undo the change
+func (s *emailService) SendEmailChangeNotice(ctx context.Context, user *model.User, newEmail, token string) error {
+	link := s.link("/undo-email-change", token)
+	body := fmt.Sprintf(`Hi %s,
+
+Someone asked to change the email address of your account to %s. The change takes effect once the new address is confirmed.
+
+If this was not you, open the link below to cancel the change, or undo it if it was already confirmed, and sign out all sessions:
+
+%s
+
+The link can be used within %s. We recommend changing your password afterwards.
+`, user.Name, newEmail, link, s.config.Auth.EmailChangeUndoTTL)
+
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body:    body,
+	})
+}
+
+// link builds a frontend URL carrying the token as a query parameter
+func (s *emailService) link(path, token string) string {
+	return strings.TrimRight(s.config.Auth.AppBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/federation"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	federationStateBytes    = 16
+	federationVerifierBytes = 32
+	federatedPasswordBytes  = 32
+)
+
+// federationState is kept encrypted in a cookie between the redirect to the
+// provider and the callback, binding the callback to the browser that started it
+type federationState struct {
+	Provider  string `json:"p"`
+	State     string `json:"s"`
+	Nonce     string `json:"n"`
+	Verifier  string `json:"v"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// FederationService signs users in through upstream OpenID Connect providers,
+// linking each external identity to a users row
+type FederationService interface {
+	Providers() []*model.FederatedProvider
+	BeginLogin(ctx context.Context, provider string) (string, string, error)
+	FinishLogin(ctx context.Context, provider string, req *model.FederatedCallbackRequest, stateCookie string) (*model.AuthResponse, error)
+}
+
+type federationService struct {
+	providers      map[string]*federation.Provider
+	userRepo       repository.UserRepository
+	identityRepo   repository.IdentityRepository
+	tokenService   TokenService
+	mfaService     MFAService
+	passwordHasher *utils.PasswordHasher
+	encryptor      *utils.Encryptor
+	config         *config.Config
+	logger         *zap.Logger
+}
+
+func NewFederationService(
+	providers map[string]*federation.Provider,
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	tokenService TokenService,
+	mfaService MFAService,
+	passwordHasher *utils.PasswordHasher,
+	encryptor *utils.Encryptor,
+	config *config.Config,
+	logger *zap.Logger,
+) FederationService {
+	return &federationService{
+		providers:      providers,
+		userRepo:       userRepo,
+		identityRepo:   identityRepo,
+		tokenService:   tokenService,
+		mfaService:     mfaService,
+		passwordHasher: passwordHasher,
+		encryptor:      encryptor,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+func (s *federationService) Providers() []*model.FederatedProvider {
+	providers := make([]*model.FederatedProvider, 0, len(s.providers))
+	for name, p := range s.providers {
+		providers = append(providers, &model.FederatedProvider{
+			Name:        name,
+			DisplayName: p.DisplayName(),
+			LoginURL:    "/auth/federated/" + name + "/login",
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
+// BeginLogin returns the provider's authorization URL and the encrypted state
+// the caller must store in a cookie for the callback
+func (s *federationService) BeginLogin(ctx context.Context, provider string) (string, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", errors.New(constants.ErrIdentityProviderNotFound)
+	}
+
+	state := federationState{
+		Provider:  provider,
+		ExpiresAt: time.Now().Add(s.config.Federation.StateTTL).Unix(),
+	}
+	var err error
+	if state.State, err = utils.GenerateRandomToken(federationStateBytes); err != nil {
+		return "", "", err
+	}
+	if state.Nonce, err = utils.GenerateRandomToken(federationStateBytes); err != nil {
+		return "", "", err
+	}
+	if state.Verifier, err = utils.GenerateRandomToken(federationVerifierBytes); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	authURL, err := p.AuthCodeURL(ctx, state.State, state.Nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return "", "", err
+	}
+	cookie, err := s.encryptor.Encrypt(payload)
+	if err != nil {
+		return "", "", fmt.Errorf("error encrypting login state: %w", err)
+	}
+
+	return authURL, cookie, nil
+}
+
+func (s *federationService) FinishLogin(ctx context.Context, provider string, req *model.FederatedCallbackRequest, stateCookie string) (*model.AuthResponse, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, errors.New(constants.ErrIdentityProviderNotFound)
+	}
+
+	state, err := s.decodeState(stateCookie)
+	if err != nil || state.Provider != provider ||
+		subtle.ConstantTimeCompare([]byte(state.State), []byte(req.State)) != 1 {
+		s.logger.Warn("Federated login callback with invalid state", zap.String("provider", provider))
+		return nil, errors.New(constants.ErrInvalidFederationState)
+	}
+
+	if req.Error != "" {
+		s.logger.Info("Identity provider returned an error",
+			zap.String("provider", provider),
+			zap.String("error", req.Error),
+			zap.String("description", req.ErrorDescription),
+		)
+		return nil, errors.New(constants.ErrFederatedLoginFailed)
+	}
+
+	claims, err := p.Exchange(ctx, req.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		s.logger.Warn("Federated login failed", zap.String("provider", provider), zap.Error(err))
+		return nil, errors.New(constants.ErrFederatedLoginFailed)
+	}
+
+	user, err := s.resolveUser(ctx, provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.Auth.RequireEmailVerification && !user.EmailVerifiedAt.Valid {
+		return nil, errors.New(constants.ErrEmailNotVerified)
+	}
+
+	mfaEnabled, err := s.mfaService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking mfa: %w", err)
+	}
+	if mfaEnabled {
+		challenge, err := s.mfaService.CreateChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &MFARequiredError{Challenge: challenge}
+	}
+
+	s.logger.Info("User logged in through identity provider",
+		zap.String("provider", provider),
+		zap.String("user_id", user.ID.String()),
+	)
+
+	return s.tokenService.IssueTokens(ctx, user)
+}
+
+// resolveUser finds the user linked to the external identity. Unknown identities
+// are linked to the account with the same email only when the provider vouches
+// for that email; otherwise a new account is created just in time.
+func (s *federationService) resolveUser(ctx context.Context, provider string, claims *federation.IDTokenClaims) (*model.User, error) {
+	identity, err := s.identityRepo.GetIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		if err := s.identityRepo.TouchIdentity(ctx, identity.ID, claims.Email); err != nil {
+			return nil, err
+		}
+		return s.userRepo.GetUserByID(ctx, identity.UserID)
+	}
+	if err.Error() != constants.ErrIdentityNotFound {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		s.logger.Warn("Identity provider did not return an email", zap.String("provider", provider))
+		return nil, errors.New(constants.ErrFederatedLoginFailed)
+	}
+
+	claims.Email = utils.NormalizeEmail(claims.Email)
+	user, err := s.userRepo.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if !claims.EmailVerified {
+			// Linking on an unverified address would let anyone claim the account
+			return nil, errors.New(constants.ErrUserExists)
+		}
+	case err.Error() == constants.ErrUserNotFound:
+		if user, err = s.createUser(ctx, claims); err != nil {
+			return nil, err
+		}
+		s.logger.Info("User created from identity provider",
+			zap.String("provider", provider),
+			zap.String("user_id", user.ID.String()),
+		)
+	default:
+		return nil, err
+	}
+
+	if _, err := s.identityRepo.CreateIdentity(ctx, user.ID, provider, claims.Subject, claims.Email); err != nil {
+		return nil, err
+	}
+
+	if claims.EmailVerified && !user.EmailVerifiedAt.Valid {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		return s.userRepo.GetUserByID(ctx, user.ID)
+	}
+
+	return user, nil
+}
+
+// createUser provisions an account with a random password nobody knows. The user
+// can set one later through the forgot-password flow.
+func (s *federationService) createUser(ctx context.Context, claims *federation.IDTokenClaims) (*model.User, error) {
+	password, err := utils.GenerateRandomToken(federatedPasswordBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing password: %w", err)
+	}
+
+	return s.userRepo.CreateUser(ctx, claims.Email, passwordHash, name)
+}
+
+func (s *federationService) decodeState(cookie string) (*federationState, error) {
+	if cookie == "" {
+		return nil, errors.New(constants.ErrInvalidFederationState)
+	}
+
+	payload, err := s.encryptor.Decrypt(cookie)
+	if err != nil {
+		return nil, err
+	}
+
+	var state federationState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, err
+	}
+
+	if time.Now().Unix() > state.ExpiresAt {
+		return nil, errors.New(constants.ErrInvalidFederationState)
+	}
+
+	return &state, nil
+}
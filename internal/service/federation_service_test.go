@@ -0,0 +1,509 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/federation"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	mockIdPClientID     = "mock-client"
+	mockIdPClientSecret = "mock-secret"
+	mockIdPRedirectURL  = "https://auth.example.com/auth/federated/mock/callback"
+	mockIdPKeyID        = "mock-key"
+)
+
+// mockAuthorization is what the mock IdP remembers between its authorization
+// endpoint and the code exchange
+type mockAuthorization struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// mockIDToken is the ID token the mock IdP is about to sign. Test cases tamper
+// with it before it goes out.
+type mockIDToken struct {
+	claims *federation.IDTokenClaims
+	key    *ecdsa.PrivateKey
+	kid    string
+}
+
+// mockIdP is an in-process OpenID Connect provider serving discovery, a key set
+// and a token endpoint that checks client credentials and PKCE
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *ecdsa.PrivateKey
+	codes  map[string]mockAuthorization
+	// claims are released for the next code exchange
+	claims federation.IDTokenClaims
+	tamper func(token *mockIDToken)
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	idp := &mockIdP{t: t, key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("GET /jwks", idp.jwks)
+	mux.HandleFunc("POST /token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	idp.writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 idp.server.URL,
+		"authorization_endpoint": idp.server.URL + "/authorize",
+		"token_endpoint":         idp.server.URL + "/token",
+		"jwks_uri":               idp.server.URL + "/jwks",
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	jwk, err := utils.NewJWK(mockIdPKeyID, jwt.SigningMethodES256.Alg(), &idp.key.PublicKey)
+	if err != nil {
+		idp.t.Errorf("NewJWK: %v", err)
+	}
+	idp.writeJSON(w, http.StatusOK, utils.JWKSet{Keys: []utils.JWK{jwk}})
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	clientID, _ = url.QueryUnescape(clientID)
+	clientSecret, _ = url.QueryUnescape(clientSecret)
+	if !ok || clientID != mockIdPClientID || clientSecret != mockIdPClientSecret {
+		idp.writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostFormValue("code")
+	auth, ok := idp.codes[code]
+	delete(idp.codes, code)
+	if r.PostFormValue("grant_type") != "authorization_code" || !ok ||
+		r.PostFormValue("redirect_uri") != auth.redirectURI ||
+		!utils.VerifyCodeChallenge(r.PostFormValue("code_verifier"), auth.challenge, utils.PKCEMethodS256) {
+		idp.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := idp.claims
+	claims.Nonce = auth.nonce
+	claims.Issuer = idp.server.URL
+	claims.Audience = jwt.ClaimStrings{mockIdPClientID}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(5 * time.Minute))
+
+	idToken := &mockIDToken{claims: &claims, key: idp.key, kid: mockIdPKeyID}
+	if idp.tamper != nil {
+		idp.tamper(idToken)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, idToken.claims)
+	token.Header["kid"] = idToken.kid
+	signed, err := token.SignedString(idToken.key)
+	if err != nil {
+		idp.t.Errorf("signing id token: %v", err)
+	}
+	idp.writeJSON(w, http.StatusOK, map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+// authorize plays the user consenting at the IdP and returns the code and state
+// the IdP redirects back with
+func (idp *mockIdP) authorize(authURL string) (string, string) {
+	idp.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatalf("parsing authorization URL: %v", err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("response_type") != "code" || q.Get("client_id") != mockIdPClientID ||
+		q.Get("code_challenge_method") != utils.PKCEMethodS256 || q.Get("nonce") == "" {
+		idp.t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	code := "code-" + q.Get("state")
+	idp.codes[code] = mockAuthorization{
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+	}
+	return code, q.Get("state")
+}
+
+func (idp *mockIdP) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		idp.t.Errorf("writing response: %v", err)
+	}
+}
+
+type fakeFederationUsers struct {
+	repository.UserRepository
+	users []*model.User
+}
+
+func (f *fakeFederationUsers) GetUserByID(ctx context.Context, id pgtype.UUID) (*model.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, errors.New(constants.ErrUserNotFound)
+}
+
+func (f *fakeFederationUsers) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, errors.New(constants.ErrUserNotFound)
+}
+
+func (f *fakeFederationUsers) CreateUser(ctx context.Context, email, passwordHash, name string) (*model.User, error) {
+	user := &model.User{
+		ID:       pgtype.UUID{Bytes: [16]byte{0xbb, byte(len(f.users) + 1)}, Valid: true},
+		Email:    email,
+		Password: passwordHash,
+		Name:     name,
+	}
+	f.users = append(f.users, user)
+	return user, nil
+}
+
+func (f *fakeFederationUsers) MarkEmailVerified(ctx context.Context, id pgtype.UUID) error {
+	user, err := f.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.EmailVerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return nil
+}
+
+type fakeIdentities struct {
+	repository.IdentityRepository
+	identities []*model.UserIdentity
+}
+
+func (f *fakeIdentities) GetIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	for _, i := range f.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, errors.New(constants.ErrIdentityNotFound)
+}
+
+func (f *fakeIdentities) CreateIdentity(ctx context.Context, userID pgtype.UUID, provider, subject, email string) (*model.UserIdentity, error) {
+	identity := &model.UserIdentity{
+		ID:       pgtype.UUID{Bytes: [16]byte{0xcc, byte(len(f.identities) + 1)}, Valid: true},
+		UserID:   userID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    pgtype.Text{String: email, Valid: email != ""},
+	}
+	f.identities = append(f.identities, identity)
+	return identity, nil
+}
+
+func (f *fakeIdentities) TouchIdentity(ctx context.Context, id pgtype.UUID, email string) error {
+	return nil
+}
+
+type fakeNoMFA struct {
+	MFAService
+}
+
+func (f *fakeNoMFA) IsEnabled(ctx context.Context, userID pgtype.UUID) (bool, error) {
+	return false, nil
+}
+
+func newTestFederationService(t *testing.T, idp *mockIdP, users *fakeFederationUsers, identities *fakeIdentities, requireVerification bool) FederationService {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Federation.StateTTL = time.Minute
+	cfg.Auth.RequireEmailVerification = requireVerification
+
+	providers, err := federation.New(&config.FederationConfig{Providers: []config.FederatedProviderConfig{{
+		Name:         "mock",
+		Issuer:       idp.server.URL,
+		ClientID:     mockIdPClientID,
+		ClientSecret: mockIdPClientSecret,
+		RedirectURL:  mockIdPRedirectURL,
+	}}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("federation.New: %v", err)
+	}
+
+	hasher, err := utils.NewPasswordHasher(&config.PasswordHashingConfig{Algorithm: utils.PasswordAlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+	encryptor, err := utils.NewEncryptor(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("NewEncryptor: %v", err)
+	}
+
+	return NewFederationService(providers, users, identities, &fakeTokenIssuer{}, &fakeNoMFA{}, hasher, encryptor, cfg, zap.NewNop())
+}
+
+func TestFederatedLogin(t *testing.T) {
+	existingID := pgtype.UUID{Bytes: [16]byte{0xbb, 0xff}, Valid: true}
+	existing := func() *model.User {
+		return &model.User{
+			ID:              existingID,
+			Email:           "existing@example.com",
+			EmailVerifiedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		}
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name                string
+		users               []*model.User
+		identities          []*model.UserIdentity
+		claims              federation.IDTokenClaims
+		requireVerification bool
+		tamperToken         func(token *mockIDToken)
+		tamperCallback      func(req *model.FederatedCallbackRequest, cookie *string)
+		wantErr             string
+		// wantUser is the email of the user tokens are issued for
+		wantUser       string
+		wantUsers      int
+		wantIdentities int
+	}{
+		{
+			name:           "new user is created",
+			claims:         idTokenClaims("sub-1", "New.User@Example.com", true),
+			wantUser:       "new.user@example.com",
+			wantUsers:      1,
+			wantIdentities: 1,
+		},
+		{
+			name:           "linked identity logs in",
+			users:          []*model.User{existing()},
+			identities:     []*model.UserIdentity{{UserID: existingID, Provider: "mock", Subject: "sub-1"}},
+			claims:         idTokenClaims("sub-1", "changed@example.com", false),
+			wantUser:       "existing@example.com",
+			wantUsers:      1,
+			wantIdentities: 1,
+		},
+		{
+			name:           "verified email is linked to the existing account",
+			users:          []*model.User{existing()},
+			claims:         idTokenClaims("sub-1", "Existing@Example.com", true),
+			wantUser:       "existing@example.com",
+			wantUsers:      1,
+			wantIdentities: 1,
+		},
+		{
+			name:      "unverified email is not linked to the existing account",
+			users:     []*model.User{existing()},
+			claims:    idTokenClaims("sub-1", "existing@example.com", false),
+			wantErr:   constants.ErrUserExists,
+			wantUsers: 1,
+		},
+		{
+			name:                "unverified new user when verification is required",
+			claims:              idTokenClaims("sub-1", "new@example.com", false),
+			requireVerification: true,
+			wantErr:             constants.ErrEmailNotVerified,
+			wantUsers:           1,
+			wantIdentities:      1,
+		},
+		{
+			name:    "no email",
+			claims:  idTokenClaims("sub-1", "", true),
+			wantErr: constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:        "missing subject",
+			claims:      idTokenClaims("sub-1", "new@example.com", true),
+			tamperToken: func(token *mockIDToken) { token.claims.Subject = "" },
+			wantErr:     constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:        "wrong nonce",
+			claims:      idTokenClaims("sub-1", "new@example.com", true),
+			tamperToken: func(token *mockIDToken) { token.claims.Nonce = "replayed" },
+			wantErr:     constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:        "wrong audience",
+			claims:      idTokenClaims("sub-1", "new@example.com", true),
+			tamperToken: func(token *mockIDToken) { token.claims.Audience = jwt.ClaimStrings{"another-client"} },
+			wantErr:     constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:        "wrong issuer",
+			claims:      idTokenClaims("sub-1", "new@example.com", true),
+			tamperToken: func(token *mockIDToken) { token.claims.Issuer = "https://evil.example.com" },
+			wantErr:     constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:   "expired id token",
+			claims: idTokenClaims("sub-1", "new@example.com", true),
+			tamperToken: func(token *mockIDToken) {
+				token.claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				token.claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			},
+			wantErr: constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:        "signed with another key",
+			claims:      idTokenClaims("sub-1", "new@example.com", true),
+			tamperToken: func(token *mockIDToken) { token.key = otherKey },
+			wantErr:     constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:        "unknown key id",
+			claims:      idTokenClaims("sub-1", "new@example.com", true),
+			tamperToken: func(token *mockIDToken) { token.kid = "rotated-away" },
+			wantErr:     constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:   "state mismatch",
+			claims: idTokenClaims("sub-1", "new@example.com", true),
+			tamperCallback: func(req *model.FederatedCallbackRequest, cookie *string) {
+				req.State = "forged"
+			},
+			wantErr: constants.ErrInvalidFederationState,
+		},
+		{
+			name:   "missing state cookie",
+			claims: idTokenClaims("sub-1", "new@example.com", true),
+			tamperCallback: func(req *model.FederatedCallbackRequest, cookie *string) {
+				*cookie = ""
+			},
+			wantErr: constants.ErrInvalidFederationState,
+		},
+		{
+			name:   "provider returned an error",
+			claims: idTokenClaims("sub-1", "new@example.com", true),
+			tamperCallback: func(req *model.FederatedCallbackRequest, cookie *string) {
+				req.Code = ""
+				req.Error = "access_denied"
+			},
+			wantErr: constants.ErrFederatedLoginFailed,
+		},
+		{
+			name:   "unknown code",
+			claims: idTokenClaims("sub-1", "new@example.com", true),
+			tamperCallback: func(req *model.FederatedCallbackRequest, cookie *string) {
+				req.Code = "stolen"
+			},
+			wantErr: constants.ErrFederatedLoginFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.claims = tt.claims
+			idp.tamper = tt.tamperToken
+
+			users := &fakeFederationUsers{users: tt.users}
+			identities := &fakeIdentities{identities: tt.identities}
+			svc := newTestFederationService(t, idp, users, identities, tt.requireVerification)
+
+			authURL, cookie, err := svc.BeginLogin(context.Background(), "mock")
+			if err != nil {
+				t.Fatalf("BeginLogin: %v", err)
+			}
+			code, state := idp.authorize(authURL)
+
+			req := &model.FederatedCallbackRequest{Code: code, State: state}
+			if tt.tamperCallback != nil {
+				tt.tamperCallback(req, &cookie)
+			}
+
+			resp, err := svc.FinishLogin(context.Background(), "mock", req, cookie)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("FinishLogin error = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("FinishLogin: %v", err)
+				}
+				if resp.User.Email != tt.wantUser {
+					t.Errorf("logged in as %q, want %q", resp.User.Email, tt.wantUser)
+				}
+				if !resp.User.EmailVerifiedAt.Valid {
+					t.Error("federated user's email is not verified")
+				}
+			}
+
+			if len(users.users) != tt.wantUsers {
+				t.Errorf("%d users, want %d", len(users.users), tt.wantUsers)
+			}
+			if len(identities.identities) != tt.wantIdentities {
+				t.Errorf("%d identities, want %d", len(identities.identities), tt.wantIdentities)
+			}
+		})
+	}
+}
+
+func TestFederatedLoginCodeIsSingleUse(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.claims = idTokenClaims("sub-1", "new@example.com", true)
+	svc := newTestFederationService(t, idp, &fakeFederationUsers{}, &fakeIdentities{}, false)
+
+	authURL, cookie, err := svc.BeginLogin(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	code, state := idp.authorize(authURL)
+	req := &model.FederatedCallbackRequest{Code: code, State: state}
+
+	if _, err := svc.FinishLogin(context.Background(), "mock", req, cookie); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if _, err := svc.FinishLogin(context.Background(), "mock", req, cookie); err == nil || err.Error() != constants.ErrFederatedLoginFailed {
+		t.Errorf("replayed FinishLogin error = %v, want %q", err, constants.ErrFederatedLoginFailed)
+	}
+}
+
+func idTokenClaims(subject, email string, emailVerified bool) federation.IDTokenClaims {
+	return federation.IDTokenClaims{
+		Email:            email,
+		EmailVerified:    emailVerified,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+	}
+}
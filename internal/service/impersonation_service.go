@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// ImpersonationService lets support staff act as a user to reproduce issues.
+// Impersonation tokens are short-lived, cannot be refreshed and carry an act
+// claim naming the admin, so every request made with them is attributable.
+type ImpersonationService interface {
+	Impersonate(ctx context.Context, actorID, userID pgtype.UUID, reason string) (*model.AuthResponse, error)
+}
+
+type impersonationService struct {
+	userRepo repository.UserRepository
+	keys     *utils.KeyRing
+	config   *config.Config
+	logger   *zap.Logger
+}
+
+func NewImpersonationService(userRepo repository.UserRepository, keys *utils.KeyRing, config *config.Config, logger *zap.Logger) ImpersonationService {
+	return &impersonationService{
+		userRepo: userRepo,
+		keys:     keys,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+func (s *impersonationService) Impersonate(ctx context.Context, actorID, userID pgtype.UUID, reason string) (*model.AuthResponse, error) {
+	actor, err := s.userRepo.GetUserByID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting actor: %w", err)
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, claims, err := utils.GenerateImpersonationToken(user, actor, s.config.Auth.ImpersonationTTL, s.keys)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	s.logger.Warn("Security event",
+		zap.String("event", "impersonation_started"),
+		zap.String("actor_id", actor.ID.String()),
+		zap.String("actor_email", actor.Email),
+		zap.String("user_id", user.ID.String()),
+		zap.String("user_email", user.Email),
+		zap.String("reason", reason),
+		zap.String("token_id", claims.ID),
+		zap.Time("expires_at", claims.ExpiresAt.Time),
+	)
+
+	return &model.AuthResponse{
+		User:      user,
+		Token:     token,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}, nil
+}
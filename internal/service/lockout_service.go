@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AccountLockedError is returned by Check while the account is locked
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return constants.ErrAccountLocked
+}
+
+// LoginThrottledError is returned by Check when the previous failure was too
+// recent. RetryAfter is how long the client has to wait.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return constants.ErrLoginThrottled
+}
+
+type LockoutService interface {
+	Check(ctx context.Context, userID pgtype.UUID) error
+	RecordFailure(ctx context.Context, userID pgtype.UUID) error
+	Reset(ctx context.Context, userID pgtype.UUID) error
+}
+
+type lockoutService struct {
+	lockoutRepo repository.LockoutRepository
+	config      *config.Config
+	logger      *zap.Logger
+}
+
+func NewLockoutService(lockoutRepo repository.LockoutRepository, config *config.Config, logger *zap.Logger) LockoutService {
+	return &lockoutService{
+		lockoutRepo: lockoutRepo,
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// Check must run before the password is compared so a locked or throttled
+// account cannot be used as a password oracle
+func (s *lockoutService) Check(ctx context.Context, userID pgtype.UUID) error {
+	failures, err := s.lockoutRepo.GetLoginFailures(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if failures.LockedUntil.Valid && failures.LockedUntil.Time.After(now) {
+		return &AccountLockedError{Until: failures.LockedUntil.Time}
+	}
+
+	delay := s.delay(failures.FailedAttempts)
+	if delay > 0 && failures.LastFailedAt.Valid {
+		if retryAt := failures.LastFailedAt.Time.Add(delay); retryAt.After(now) {
+			return &LoginThrottledError{RetryAfter: retryAt.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed attempt and locks the account once the
+// threshold is reached
+func (s *lockoutService) RecordFailure(ctx context.Context, userID pgtype.UUID) error {
+	failures, err := s.lockoutRepo.RecordLoginFailure(ctx, userID, s.config.Auth.LockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	if s.config.Auth.LockoutThreshold > 0 && failures.FailedAttempts >= s.config.Auth.LockoutThreshold {
+		until := time.Now().Add(s.config.Auth.LockoutDuration)
+		if err := s.lockoutRepo.LockAccount(ctx, userID, until); err != nil {
+			return err
+		}
+		s.logger.Warn("Account locked after repeated login failures",
+			zap.String("user_id", userID.String()),
+			zap.Int("failed_attempts", failures.FailedAttempts),
+			zap.Time("locked_until", until),
+		)
+	}
+
+	return nil
+}
+
+func (s *lockoutService) Reset(ctx context.Context, userID pgtype.UUID) error {
+	return s.lockoutRepo.ResetLoginFailures(ctx, userID)
+}
+
+// delay doubles with every failure past ThrottleAfter
+func (s *lockoutService) delay(failedAttempts int) time.Duration {
+	if s.config.Auth.ThrottleAfter <= 0 || failedAttempts < s.config.Auth.ThrottleAfter {
+		return 0
+	}
+
+	delay := s.config.Auth.ThrottleBaseDelay
+	for i := s.config.Auth.ThrottleAfter; i < failedAttempts && delay < s.config.Auth.ThrottleMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, s.config.Auth.ThrottleMaxDelay)
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// fakeLockouts keeps login_failures in memory
+type fakeLockouts struct {
+	failures map[pgtype.UUID]*model.LoginFailures
+}
+
+func newFakeLockouts() *fakeLockouts {
+	return &fakeLockouts{failures: map[pgtype.UUID]*model.LoginFailures{}}
+}
+
+func (f *fakeLockouts) GetLoginFailures(ctx context.Context, userID pgtype.UUID) (*model.LoginFailures, error) {
+	if failures, ok := f.failures[userID]; ok {
+		copied := *failures
+		return &copied, nil
+	}
+	return &model.LoginFailures{UserID: userID}, nil
+}
+
+func (f *fakeLockouts) RecordLoginFailure(ctx context.Context, userID pgtype.UUID, window time.Duration) (*model.LoginFailures, error) {
+	failures, ok := f.failures[userID]
+	if !ok {
+		failures = &model.LoginFailures{UserID: userID}
+		f.failures[userID] = failures
+	}
+	failures.FailedAttempts++
+	failures.LastFailedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	copied := *failures
+	return &copied, nil
+}
+
+func (f *fakeLockouts) LockAccount(ctx context.Context, userID pgtype.UUID, until time.Time) error {
+	f.failures[userID].LockedUntil = pgtype.Timestamptz{Time: until, Valid: true}
+	return nil
+}
+
+func (f *fakeLockouts) ResetLoginFailures(ctx context.Context, userID pgtype.UUID) error {
+	delete(f.failures, userID)
+	return nil
+}
+
+// backdate moves the last failure into the past, as if the caller had waited
+func (f *fakeLockouts) backdate(userID pgtype.UUID, d time.Duration) {
+	f.failures[userID].LastFailedAt.Time = f.failures[userID].LastFailedAt.Time.Add(-d)
+}
+
+func testLockoutConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Auth.ThrottleAfter = 3
+	cfg.Auth.ThrottleBaseDelay = time.Second
+	cfg.Auth.ThrottleMaxDelay = 10 * time.Second
+	cfg.Auth.LockoutThreshold = 8
+	cfg.Auth.LockoutDuration = 15 * time.Minute
+	return cfg
+}
+
+func TestLockoutDelay(t *testing.T) {
+	s := &lockoutService{config: testLockoutConfig()}
+
+	tests := []struct {
+		failedAttempts int
+		want           time.Duration
+	}{
+		{failedAttempts: 0, want: 0},
+		{failedAttempts: 2, want: 0},
+		{failedAttempts: 3, want: time.Second},
+		{failedAttempts: 4, want: 2 * time.Second},
+		{failedAttempts: 5, want: 4 * time.Second},
+		{failedAttempts: 6, want: 8 * time.Second},
+		{failedAttempts: 7, want: 10 * time.Second},
+		{failedAttempts: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := s.delay(tt.failedAttempts); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failedAttempts, got, tt.want)
+		}
+	}
+}
+
+func TestLockoutThrottlesThenLocks(t *testing.T) {
+	ctx := context.Background()
+	userID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	cfg := testLockoutConfig()
+	repo := newFakeLockouts()
+	lockout := NewLockoutService(repo, cfg, zap.NewNop())
+
+	for attempt := 1; attempt < cfg.Auth.LockoutThreshold; attempt++ {
+		if err := lockout.RecordFailure(ctx, userID); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+
+		want := lockout.(*lockoutService).delay(attempt)
+		err := lockout.Check(ctx, userID)
+		if want == 0 {
+			if err != nil {
+				t.Fatalf("attempt %d: Check = %v, want nil", attempt, err)
+			}
+			continue
+		}
+
+		var throttled *LoginThrottledError
+		if !errors.As(err, &throttled) {
+			t.Fatalf("attempt %d: Check = %v, want LoginThrottledError", attempt, err)
+		}
+		if throttled.RetryAfter <= 0 || throttled.RetryAfter > want {
+			t.Errorf("attempt %d: RetryAfter = %v, want at most %v", attempt, throttled.RetryAfter, want)
+		}
+
+		// Once the delay has passed the next attempt is let through
+		repo.backdate(userID, want)
+		if err := lockout.Check(ctx, userID); err != nil {
+			t.Fatalf("attempt %d: Check after waiting = %v, want nil", attempt, err)
+		}
+	}
+
+	if err := lockout.RecordFailure(ctx, userID); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+
+	var locked *AccountLockedError
+	if err := lockout.Check(ctx, userID); !errors.As(err, &locked) {
+		t.Fatalf("Check at the threshold = %v, want AccountLockedError", err)
+	}
+	if until := time.Until(locked.Until); until <= cfg.Auth.LockoutDuration-time.Minute || until > cfg.Auth.LockoutDuration {
+		t.Errorf("locked for %v, want %v", until, cfg.Auth.LockoutDuration)
+	}
+
+	// Waiting out the throttle does not lift a lock
+	repo.backdate(userID, time.Hour)
+	if err := lockout.Check(ctx, userID); !errors.As(err, &locked) {
+		t.Errorf("Check after the throttle delay = %v, want AccountLockedError", err)
+	}
+
+	if err := lockout.Reset(ctx, userID); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if err := lockout.Check(ctx, userID); err != nil {
+		t.Errorf("Check after Reset = %v, want nil", err)
+	}
+}
+
+type fakeLockoutUsers struct {
+	repository.UserRepository
+	user *model.User
+}
+
+func (f *fakeLockoutUsers) GetUserByID(ctx context.Context, id pgtype.UUID) (*model.User, error) {
+	if id != f.user.ID {
+		return nil, errors.New(constants.ErrUserNotFound)
+	}
+	return f.user, nil
+}
+
+func (f *fakeLockoutUsers) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	if email != f.user.Email {
+		return nil, errors.New(constants.ErrUserNotFound)
+	}
+	return f.user, nil
+}
+
+func (f *fakeLockoutUsers) UpdatePassword(ctx context.Context, id pgtype.UUID, passwordHash string) error {
+	f.user.Password = passwordHash
+	return nil
+}
+
+func (f *fakeLockoutUsers) MarkEmailVerified(ctx context.Context, id pgtype.UUID) error {
+	return nil
+}
+
+// fakeResetTokens accepts each registered reset link once
+type fakeResetTokens struct {
+	repository.UserTokenRepository
+	issued map[string]pgtype.UUID
+}
+
+func (f *fakeResetTokens) ConsumeUserToken(ctx context.Context, purpose, tokenHash string) (pgtype.UUID, error) {
+	userID, ok := f.issued[tokenHash]
+	if !ok {
+		return pgtype.UUID{}, errors.New(constants.ErrInvalidActionToken)
+	}
+	delete(f.issued, tokenHash)
+	return userID, nil
+}
+
+type fakeOpenPolicy struct {
+	PasswordPolicyService
+}
+
+func (f *fakeOpenPolicy) Validate(ctx context.Context, field, password string, user *model.User) error {
+	return nil
+}
+
+func (f *fakeOpenPolicy) Remember(ctx context.Context, userID pgtype.UUID, passwordHash string) error {
+	return nil
+}
+
+func (f *fakeOpenPolicy) IsExpired(user *model.User) bool {
+	return false
+}
+
+type fakeUserRevocations struct {
+	TokenRevocationService
+}
+
+func (f *fakeUserRevocations) RevokeAllUserTokens(ctx context.Context, userID pgtype.UUID) error {
+	return nil
+}
+
+func TestLoginLockoutAndPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	cfg := testLockoutConfig()
+	cfg.JWT.Secret = "test-secret-test-secret-test-secret"
+	cfg.JWT.ExpiresIn = 60
+
+	keys, err := utils.NewKeyRing(&cfg.JWT)
+	if err != nil {
+		t.Fatalf("NewKeyRing: %v", err)
+	}
+	hasher, err := utils.NewPasswordHasher(&config.PasswordHashingConfig{Algorithm: utils.PasswordAlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+	passwordHash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	user := &model.User{ID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Email: "user@example.com", Password: passwordHash}
+	tokens := &fakeResetTokens{issued: map[string]pgtype.UUID{}}
+	repo := newFakeLockouts()
+	lockout := NewLockoutService(repo, cfg, zap.NewNop())
+	auth := NewAuthService(&fakeLockoutUsers{user: user}, tokens, nil, &fakeTokenIssuer{}, &fakeUserRevocations{},
+		nil, &fakeNoMFA{}, lockout, hasher, &fakeOpenPolicy{}, keys, cfg, zap.NewNop())
+
+	login := func(password string) error {
+		_, err := auth.Login(ctx, &model.LoginRequest{Email: "User@example.com", Password: password})
+		return err
+	}
+
+	for attempt := 1; attempt <= cfg.Auth.LockoutThreshold; attempt++ {
+		if err := login("wrong"); err == nil || err.Error() != constants.ErrInvalidCredentials {
+			t.Fatalf("attempt %d: Login = %v, want %q", attempt, err, constants.ErrInvalidCredentials)
+		}
+		// Skip the throttle delay so every attempt reaches the password check
+		repo.backdate(user.ID, time.Hour)
+	}
+
+	var locked *AccountLockedError
+	if err := login("correct horse"); !errors.As(err, &locked) {
+		t.Fatalf("Login with the right password while locked = %v, want AccountLockedError", err)
+	}
+
+	token, _, _, err := utils.GenerateActionToken(user, model.TokenPurposePasswordReset, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("GenerateActionToken: %v", err)
+	}
+	claims, err := utils.ValidateActionToken(token, model.TokenPurposePasswordReset, keys)
+	if err != nil {
+		t.Fatalf("ValidateActionToken: %v", err)
+	}
+	tokens.issued[utils.HashToken(claims.ID)] = user.ID
+
+	if err := auth.ResetPassword(ctx, &model.ResetPasswordRequest{Token: token, NewPassword: "new correct horse"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, ok := repo.failures[user.ID]; ok {
+		t.Error("ResetPassword left the login failures in place")
+	}
+
+	if err := login("new correct horse"); err != nil {
+		t.Errorf("Login after the reset = %v, want nil", err)
+	}
+}
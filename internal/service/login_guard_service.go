@@ -0,0 +1,302 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"math/bits"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Subnet sizes failures are aggregated over, so rotating addresses within one
+// allocation does not reset the count
+const (
+	loginGuardIPv4Prefix = 24
+	loginGuardIPv6Prefix = 64
+)
+
+type LoginGuardAction int
+
+const (
+	LoginGuardAllow LoginGuardAction = iota
+	LoginGuardChallenge
+	LoginGuardBlock
+)
+
+type LoginGuardVerdict struct {
+	Action     LoginGuardAction
+	RetryAfter time.Duration
+}
+
+// LoginGuardService detects credential stuffing: one source trying many
+// different accounts. State is kept in memory per replica.
+type LoginGuardService interface {
+	Check(ip string) LoginGuardVerdict
+	RecordFailure(ip, email string)
+	IssueChallenge(ip string) (*model.LoginChallenge, error)
+	VerifyChallenge(ip, challenge, solution string) error
+}
+
+// loginSource aggregates failures of one IP or subnet. accounts maps each
+// attempted email to the time of its latest failure.
+type loginSource struct {
+	accounts     map[string]time.Time
+	blockedUntil time.Time
+	challenged   bool
+}
+
+type challengePayload struct {
+	IP         string `json:"ip"`
+	Difficulty int    `json:"difficulty"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+type loginGuardService struct {
+	encryptor *utils.Encryptor
+	config    *config.Config
+	logger    *zap.Logger
+
+	mu        sync.Mutex
+	ips       map[string]*loginSource
+	subnets   map[string]*loginSource
+	solved    map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewLoginGuardService(encryptor *utils.Encryptor, config *config.Config, logger *zap.Logger) LoginGuardService {
+	return &loginGuardService{
+		encryptor: encryptor,
+		config:    config,
+		logger:    logger,
+		ips:       make(map[string]*loginSource),
+		subnets:   make(map[string]*loginSource),
+		solved:    make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *loginGuardService) Check(ip string) LoginGuardVerdict {
+	cfg := &s.config.LoginGuard
+	if !cfg.Enabled {
+		return LoginGuardVerdict{Action: LoginGuardAllow}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	ipSource := s.ips[ip]
+	subnetSource := s.subnets[subnetOf(ip)]
+
+	var blockedUntil time.Time
+	for _, source := range []*loginSource{ipSource, subnetSource} {
+		if source != nil && source.blockedUntil.After(blockedUntil) {
+			blockedUntil = source.blockedUntil
+		}
+	}
+	if blockedUntil.After(now) {
+		return LoginGuardVerdict{Action: LoginGuardBlock, RetryAfter: blockedUntil.Sub(now)}
+	}
+
+	if s.distinctAccounts(ipSource, now) >= cfg.IPChallengeThreshold ||
+		s.distinctAccounts(subnetSource, now) >= cfg.SubnetChallengeThreshold {
+		return LoginGuardVerdict{Action: LoginGuardChallenge}
+	}
+
+	return LoginGuardVerdict{Action: LoginGuardAllow}
+}
+
+func (s *loginGuardService) RecordFailure(ip, email string) {
+	cfg := &s.config.LoginGuard
+	if !cfg.Enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	email = utils.NormalizeEmail(email)
+	subnet := subnetOf(ip)
+	s.record(s.ips, ip, "ip", ip, email, cfg.IPChallengeThreshold, cfg.IPBlockThreshold, now)
+	s.record(s.subnets, subnet, "subnet", ip, email, cfg.SubnetChallengeThreshold, cfg.SubnetBlockThreshold, now)
+}
+
+// record adds one failure to the source under key and escalates once a
+// threshold is crossed. Each escalation is logged once as a security event.
+func (s *loginGuardService) record(sources map[string]*loginSource, key, kind, ip, email string, challengeAt, blockAt int, now time.Time) {
+	source, ok := sources[key]
+	if !ok {
+		source = &loginSource{accounts: make(map[string]time.Time)}
+		sources[key] = source
+	}
+	source.accounts[email] = now
+
+	distinct := s.distinctAccounts(source, now)
+	switch {
+	case distinct >= blockAt:
+		source.blockedUntil = now.Add(s.config.LoginGuard.BlockDuration)
+		source.accounts = make(map[string]time.Time)
+		source.challenged = false
+		s.logger.Warn("Security event",
+			zap.String("event", "credential_stuffing_blocked"),
+			zap.String("source_type", kind),
+			zap.String("source", key),
+			zap.String("ip", ip),
+			zap.Int("distinct_accounts", distinct),
+			zap.Time("blocked_until", source.blockedUntil),
+		)
+	case distinct >= challengeAt && !source.challenged:
+		source.challenged = true
+		s.logger.Warn("Security event",
+			zap.String("event", "credential_stuffing_challenge"),
+			zap.String("source_type", kind),
+			zap.String("source", key),
+			zap.String("ip", ip),
+			zap.Int("distinct_accounts", distinct),
+		)
+	}
+}
+
+// distinctAccounts drops failures that fell out of the window and counts the rest
+func (s *loginGuardService) distinctAccounts(source *loginSource, now time.Time) int {
+	if source == nil {
+		return 0
+	}
+
+	cutoff := now.Add(-s.config.LoginGuard.Window)
+	for email, at := range source.accounts {
+		if at.Before(cutoff) {
+			delete(source.accounts, email)
+		}
+	}
+	if len(source.accounts) == 0 {
+		source.challenged = false
+	}
+
+	return len(source.accounts)
+}
+
+// sweep forgets idle sources and expired challenges once per window
+func (s *loginGuardService) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.config.LoginGuard.Window {
+		return
+	}
+	s.lastSweep = now
+
+	for _, sources := range []map[string]*loginSource{s.ips, s.subnets} {
+		for key, source := range sources {
+			if s.distinctAccounts(source, now) == 0 && !source.blockedUntil.After(now) {
+				delete(sources, key)
+			}
+		}
+	}
+	for challenge, expiresAt := range s.solved {
+		if expiresAt.Before(now) {
+			delete(s.solved, challenge)
+		}
+	}
+}
+
+// IssueChallenge seals the puzzle parameters so no server-side state is needed
+// until it is solved
+func (s *loginGuardService) IssueChallenge(ip string) (*model.LoginChallenge, error) {
+	cfg := &s.config.LoginGuard
+	expiresAt := time.Now().Add(cfg.ChallengeTTL)
+
+	payload, err := json.Marshal(challengePayload{
+		IP:         ip,
+		Difficulty: cfg.ChallengeDifficulty,
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.encryptor.Encrypt(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginChallenge{
+		Challenge:  challenge,
+		Difficulty: cfg.ChallengeDifficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// VerifyChallenge accepts each solved challenge once, from the IP it was issued to
+func (s *loginGuardService) VerifyChallenge(ip, challenge, solution string) error {
+	if challenge == "" || solution == "" {
+		return errors.New(constants.ErrInvalidLoginChallenge)
+	}
+
+	plaintext, err := s.encryptor.Decrypt(challenge)
+	if err != nil {
+		return errors.New(constants.ErrInvalidLoginChallenge)
+	}
+
+	var payload challengePayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return errors.New(constants.ErrInvalidLoginChallenge)
+	}
+
+	expiresAt := time.Unix(payload.ExpiresAt, 0)
+	if payload.IP != ip || time.Now().After(expiresAt) {
+		return errors.New(constants.ErrInvalidLoginChallenge)
+	}
+
+	if leadingZeroBits(sha256.Sum256([]byte(challenge+":"+solution))) < payload.Difficulty {
+		return errors.New(constants.ErrInvalidLoginChallenge)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, used := s.solved[challenge]; used {
+		return errors.New(constants.ErrInvalidLoginChallenge)
+	}
+	s.solved[challenge] = expiresAt
+
+	return nil
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// subnetOf returns the /24 or /64 network the address belongs to
+func subnetOf(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	bits := loginGuardIPv6Prefix
+	if addr.Unmap().Is4() {
+		addr, bits = addr.Unmap(), loginGuardIPv4Prefix
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	recoveryCodeCount   = 10
+	recoveryCodeHalfLen = 5
+)
+
+// MFARequiredError is returned by Login when the password was correct but a
+// second factor is still needed. Challenge is what the client has to send back.
+type MFARequiredError struct {
+	Challenge *model.MFAChallengeResponse
+}
+
+func (e *MFARequiredError) Error() string {
+	return constants.ErrMFARequired
+}
+
+type MFAService interface {
+	Enroll(ctx context.Context, userID pgtype.UUID) (*model.MFAEnrollResponse, error)
+	Confirm(ctx context.Context, userID pgtype.UUID, req *model.MFACodeRequest) (*model.MFARecoveryCodesResponse, error)
+	Disable(ctx context.Context, userID pgtype.UUID, req *model.MFACodeRequest) error
+	IsEnabled(ctx context.Context, userID pgtype.UUID) (bool, error)
+	CreateChallenge(ctx context.Context, user *model.User) (*model.MFAChallengeResponse, error)
+	VerifyChallenge(ctx context.Context, req *model.MFAVerifyRequest) (*model.AuthResponse, error)
+}
+
+type mfaService struct {
+	userRepo       repository.UserRepository
+	mfaRepo        repository.MFARepository
+	tokenService   TokenService
+	lockoutService LockoutService
+	encryptor      *utils.Encryptor
+	actionTokens   *actionTokens
+	config         *config.Config
+	logger         *zap.Logger
+}
+
+func NewMFAService(
+	userRepo repository.UserRepository,
+	mfaRepo repository.MFARepository,
+	userTokenRepo repository.UserTokenRepository,
+	tokenService TokenService,
+	lockoutService LockoutService,
+	encryptor *utils.Encryptor,
+	keys *utils.KeyRing,
+	config *config.Config,
+	logger *zap.Logger,
+) MFAService {
+	return &mfaService{
+		userRepo:       userRepo,
+		mfaRepo:        mfaRepo,
+		tokenService:   tokenService,
+		lockoutService: lockoutService,
+		encryptor:      encryptor,
+		actionTokens:   &actionTokens{userTokenRepo: userTokenRepo, keys: keys},
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// Enroll generates a secret that stays pending until Confirm proves the
+// authenticator app produces matching codes
+func (s *mfaService) Enroll(ctx context.Context, userID pgtype.UUID) (*model.MFAEnrollResponse, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating secret: %w", err)
+	}
+
+	encrypted, err := s.encryptor.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting secret: %w", err)
+	}
+
+	if err := s.mfaRepo.UpsertMFASecret(ctx, userID, encrypted); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("MFA enrollment started", zap.String("user_id", userID.String()))
+
+	return &model.MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURL: utils.TOTPURI(s.config.Auth.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *mfaService) Confirm(ctx context.Context, userID pgtype.UUID, req *model.MFACodeRequest) (*model.MFARecoveryCodesResponse, error) {
+	mfa, err := s.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa.EnabledAt.Valid {
+		return nil, errors.New(constants.ErrMFAAlreadyEnabled)
+	}
+
+	if err := s.verifyTOTP(ctx, mfa, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("error generating recovery codes: %w", err)
+	}
+
+	if err := s.mfaRepo.EnableMFA(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &model.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *mfaService) Disable(ctx context.Context, userID pgtype.UUID, req *model.MFACodeRequest) error {
+	mfa, err := s.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if mfa.EnabledAt.Valid {
+		if err := s.verifyTOTP(ctx, mfa, req.Code); err != nil {
+			return err
+		}
+	}
+
+	return s.mfaRepo.DeleteMFA(ctx, userID)
+}
+
+func (s *mfaService) IsEnabled(ctx context.Context, userID pgtype.UUID) (bool, error) {
+	mfa, err := s.mfaRepo.GetMFA(ctx, userID)
+	if err != nil {
+		if err.Error() == constants.ErrMFANotEnrolled {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return mfa.EnabledAt.Valid, nil
+}
+
+// CreateChallenge issues a short-lived token proving the first factor succeeded.
+// It replaces the user's earlier challenges, so guesses cannot be spread over
+// several of them.
+func (s *mfaService) CreateChallenge(ctx context.Context, user *model.User) (*model.MFAChallengeResponse, error) {
+	expiresAt := time.Now().Add(s.config.Auth.MFAChallengeTTL)
+	token, err := s.actionTokens.issue(ctx, user, model.TokenPurposeMFAChallenge, s.config.Auth.MFAChallengeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("error generating mfa challenge: %w", err)
+	}
+
+	return &model.MFAChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresAt:      expiresAt.Unix(),
+	}, nil
+}
+
+// VerifyChallenge exchanges a challenge token plus a TOTP or recovery code for
+// real tokens. A challenge allows auth.mfa_max_attempts codes and is used up by
+// the correct one. Wrong codes count toward the account lockout like wrong
+// passwords.
+func (s *mfaService) VerifyChallenge(ctx context.Context, req *model.MFAVerifyRequest) (*model.AuthResponse, error) {
+	claims, err := s.actionTokens.attempt(ctx, req.ChallengeToken, model.TokenPurposeMFAChallenge, s.config.Auth.MFAMaxAttempts)
+	if err != nil {
+		if err.Error() == constants.ErrInvalidActionToken {
+			return nil, errors.New(constants.ErrInvalidToken)
+		}
+		return nil, err
+	}
+
+	if err := s.lockoutService.Check(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.mfaRepo.GetMFA(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.EnabledAt.Valid {
+		return nil, errors.New(constants.ErrMFANotEnrolled)
+	}
+
+	if err := s.verifySecondFactor(ctx, mfa, req); err != nil {
+		if err.Error() == constants.ErrInvalidMFACode {
+			if err := s.lockoutService.RecordFailure(ctx, claims.UserID); err != nil {
+				s.logger.Error("Failed to record MFA failure", zap.Error(err))
+			}
+		}
+		return nil, err
+	}
+
+	if _, err := s.actionTokens.consume(ctx, req.ChallengeToken, model.TokenPurposeMFAChallenge); err != nil {
+		if err.Error() == constants.ErrInvalidActionToken {
+			return nil, errors.New(constants.ErrInvalidToken)
+		}
+		return nil, err
+	}
+
+	if err := s.lockoutService.Reset(ctx, claims.UserID); err != nil {
+		return nil, fmt.Errorf("error resetting login failures: %w", err)
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	s.logger.Info("User completed MFA login", zap.String("user_id", user.ID.String()))
+	return s.tokenService.IssueTokens(ctx, user)
+}
+
+// verifySecondFactor checks the recovery code if one was sent, the TOTP code otherwise
+func (s *mfaService) verifySecondFactor(ctx context.Context, mfa *model.UserMFA, req *model.MFAVerifyRequest) error {
+	if req.RecoveryCode == "" {
+		return s.verifyTOTP(ctx, mfa, req.Code)
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, mfa.UserID, utils.HashToken(normalizeRecoveryCode(req.RecoveryCode)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		s.logger.Warn("Invalid MFA recovery code", zap.String("user_id", mfa.UserID.String()))
+		return errors.New(constants.ErrInvalidMFACode)
+	}
+
+	s.logger.Info("MFA recovery code used", zap.String("user_id", mfa.UserID.String()))
+	return nil
+}
+
+func (s *mfaService) verifyTOTP(ctx context.Context, mfa *model.UserMFA, code string) error {
+	secret, err := s.encryptor.Decrypt(mfa.SecretEncrypted)
+	if err != nil {
+		return fmt.Errorf("error decrypting mfa secret: %w", err)
+	}
+
+	step, ok := utils.ValidateTOTP(string(secret), code, time.Now())
+	if !ok {
+		s.logger.Warn("Invalid TOTP code", zap.String("user_id", mfa.UserID.String()))
+		return errors.New(constants.ErrInvalidMFACode)
+	}
+
+	fresh, err := s.mfaRepo.UseTOTPStep(ctx, mfa.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		s.logger.Warn("Replayed TOTP code", zap.String("user_id", mfa.UserID.String()))
+		return errors.New(constants.ErrInvalidMFACode)
+	}
+
+	return nil
+}
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx along with their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(secret[:2*recoveryCodeHalfLen])
+		code := raw[:recoveryCodeHalfLen] + "-" + raw[recoveryCodeHalfLen:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
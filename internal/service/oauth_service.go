@@ -0,0 +1,789 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	authorizationCodeBytes = 32
+	oauthClientIDBytes     = 16
+	oauthClientSecretBytes = 32
+
+	scopeOpenID        = "openid"
+	scopeOfflineAccess = "offline_access"
+)
+
+// SupportedScopes are the scopes a client may be registered for
+var SupportedScopes = []string{scopeOpenID, "profile", "email", scopeOfflineAccess}
+
+// ServiceScopes are the scopes a machine client may be registered for. Each one
+// is the RBAC permission of the same name.
+var ServiceScopes = []string{model.PermissionUsersRead, model.PermissionUsersManage, model.PermissionRolesManage}
+
+// OAuthError is a protocol error reported to the client as defined by RFC 6749
+// section 5.2. RedirectURI is set when the error must be delivered by redirect.
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	RedirectURI string `json:"-"`
+	State       string `json:"-"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// RedirectURL returns the client callback carrying the error, or "" when the
+// error must not be redirected (unknown client or redirect URI)
+func (e *OAuthError) RedirectURL() string {
+	if e.RedirectURI == "" {
+		return ""
+	}
+	params := url.Values{"error": {e.Code}}
+	if e.Description != "" {
+		params.Set("error_description", e.Description)
+	}
+	if e.State != "" {
+		params.Set("state", e.State)
+	}
+	return appendQuery(e.RedirectURI, params)
+}
+
+// OAuthService implements the OpenID Connect authorization-code flow with PKCE,
+// the client_credentials grant for machine clients, and token introspection and
+// revocation. Logging in and consenting happen in the first-party app through
+// AuthService; this service only turns that decision into codes and tokens.
+type OAuthService interface {
+	ValidateAuthorizeRequest(ctx context.Context, req *model.AuthorizeRequest) (*model.OAuthClient, error)
+	Authorize(ctx context.Context, userID, sessionID pgtype.UUID, req *model.AuthorizeDecisionRequest) (*model.AuthorizeDecisionResponse, error)
+	Token(ctx context.Context, req *model.TokenRequest) (*model.TokenResponse, error)
+	Introspect(ctx context.Context, req *model.TokenIntrospectionRequest) (*model.IntrospectionResponse, error)
+	Revoke(ctx context.Context, req *model.TokenIntrospectionRequest) error
+	UserInfo(ctx context.Context, userID pgtype.UUID, scopes []string) (*model.UserInfoResponse, error)
+	Discovery() *model.OpenIDConfiguration
+	CreateClient(ctx context.Context, name string, redirectURIs, scopes []string, public bool) (*model.OAuthClient, string, error)
+	CreateServiceClient(ctx context.Context, name string, scopes []string) (*model.OAuthClient, string, error)
+	CreateResourceServer(ctx context.Context, name string) (*model.OAuthClient, string, error)
+	DisableClient(ctx context.Context, clientID string) error
+	RotateClientSecret(ctx context.Context, clientID string) (string, error)
+}
+
+type oauthService struct {
+	oauthRepo         repository.OAuthRepository
+	refreshRepo       repository.RefreshTokenRepository
+	sessionRepo       repository.SessionRepository
+	apiKeyRepo        repository.APIKeyRepository
+	authService       AuthService
+	tokenService      TokenService
+	revocationService TokenRevocationService
+	keys              *utils.KeyRing
+	config            *config.Config
+	logger            *zap.Logger
+}
+
+func NewOAuthService(
+	oauthRepo repository.OAuthRepository,
+	refreshRepo repository.RefreshTokenRepository,
+	sessionRepo repository.SessionRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	authService AuthService,
+	tokenService TokenService,
+	revocationService TokenRevocationService,
+	keys *utils.KeyRing,
+	config *config.Config,
+	logger *zap.Logger,
+) OAuthService {
+	return &oauthService{
+		oauthRepo:         oauthRepo,
+		refreshRepo:       refreshRepo,
+		sessionRepo:       sessionRepo,
+		apiKeyRepo:        apiKeyRepo,
+		authService:       authService,
+		tokenService:      tokenService,
+		revocationService: revocationService,
+		keys:              keys,
+		config:            config,
+		logger:            logger,
+	}
+}
+
+// ValidateAuthorizeRequest checks an authorization request before the user is sent
+// to log in. Errors about the client or redirect URI are never redirected.
+func (s *oauthService) ValidateAuthorizeRequest(ctx context.Context, req *model.AuthorizeRequest) (*model.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClientByClientID(ctx, req.ClientID)
+	if err != nil {
+		if err.Error() == constants.ErrOAuthClientNotFound {
+			return nil, &OAuthError{Code: "invalid_client", Description: "unknown client"}
+		}
+		return nil, err
+	}
+
+	if !client.AllowsGrant(model.GrantTypeAuthorizationCode) {
+		return nil, &OAuthError{Code: "unauthorized_client", Description: "client may not use the authorization code grant"}
+	}
+
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, &OAuthError{Code: "invalid_request", Description: "redirect_uri is not registered for this client"}
+	}
+
+	redirectErr := func(code, description string) error {
+		return &OAuthError{Code: code, Description: description, RedirectURI: req.RedirectURI, State: req.State}
+	}
+
+	if req.ResponseType != "code" {
+		return nil, redirectErr("unsupported_response_type", "only the code response type is supported")
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if !slices.Contains(scopes, scopeOpenID) {
+		return nil, redirectErr("invalid_scope", "the openid scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, redirectErr("invalid_scope", fmt.Sprintf("scope %q is not allowed for this client", scope))
+		}
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != utils.PKCEMethodS256 {
+		return nil, redirectErr("invalid_request", "a PKCE code_challenge with method S256 is required")
+	}
+
+	return client, nil
+}
+
+// Authorize records the logged-in user's consent decision and returns where the
+// browser should go next: the client callback with either a code or access_denied.
+// sessionID is the login session the consent page was called with; the ID
+// token's auth_time is when that session began.
+func (s *oauthService) Authorize(ctx context.Context, userID, sessionID pgtype.UUID, req *model.AuthorizeDecisionRequest) (*model.AuthorizeDecisionResponse, error) {
+	client, err := s.ValidateAuthorizeRequest(ctx, &req.AuthorizeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Approve {
+		s.logger.Info("OAuth consent denied",
+			zap.String("user_id", userID.String()),
+			zap.String("client_id", client.ClientID),
+		)
+		denied := &OAuthError{Code: "access_denied", Description: "the user denied the request", RedirectURI: req.RedirectURI, State: req.State}
+		return &model.AuthorizeDecisionResponse{RedirectTo: denied.RedirectURL()}, nil
+	}
+
+	user, err := s.authService.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refreshing issues new access tokens but does not authenticate the user
+	// again, so the time comes from the session rather than the token
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != user.ID || session.RevokedAt.Valid {
+		return nil, errors.New(constants.ErrSessionNotFound)
+	}
+
+	code, err := utils.GenerateRandomToken(authorizationCodeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.oauthRepo.CreateAuthorizationCode(ctx, &model.AuthorizationCode{
+		CodeHash:            utils.HashToken(code),
+		ClientID:            client.ClientID,
+		UserID:              user.ID,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              strings.Fields(req.Scope),
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            session.CreatedAt,
+		ExpiresAt:           pgtype.Timestamptz{Time: time.Now().Add(s.config.OIDC.AuthCodeTTL), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("OAuth authorization code issued",
+		zap.String("user_id", user.ID.String()),
+		zap.String("client_id", client.ClientID),
+	)
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	return &model.AuthorizeDecisionResponse{RedirectTo: appendQuery(req.RedirectURI, params)}, nil
+}
+
+func (s *oauthService) Token(ctx context.Context, req *model.TokenRequest) (*model.TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken, model.GrantTypeClientCredentials:
+	default:
+		return nil, &OAuthError{Code: "unsupported_grant_type"}
+	}
+
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, &OAuthError{Code: "unauthorized_client", Description: "client may not use this grant type"}
+	}
+
+	switch req.GrantType {
+	case model.GrantTypeAuthorizationCode:
+		return s.exchangeCode(ctx, client, req)
+	case model.GrantTypeRefreshToken:
+		return s.refresh(ctx, client, req)
+	default:
+		return s.clientCredentials(ctx, client, req)
+	}
+}
+
+func (s *oauthService) exchangeCode(ctx context.Context, client *model.OAuthClient, req *model.TokenRequest) (*model.TokenResponse, error) {
+	code, err := s.oauthRepo.ConsumeAuthorizationCode(ctx, utils.HashToken(req.Code))
+	if err != nil {
+		if err.Error() == constants.ErrInvalidAuthorizationCode {
+			return nil, &OAuthError{Code: "invalid_grant", Description: "authorization code is invalid or expired"}
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, &OAuthError{Code: "invalid_grant", Description: "authorization code was issued to another client or redirect_uri"}
+	}
+
+	if !utils.VerifyCodeChallenge(req.CodeVerifier, code.CodeChallenge, code.CodeChallengeMethod) {
+		s.logger.Warn("PKCE verification failed", zap.String("client_id", client.ClientID))
+		return nil, &OAuthError{Code: "invalid_grant", Description: "code_verifier does not match the code challenge"}
+	}
+
+	user, err := s.authService.GetUserProfile(ctx, code.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	authResponse, err := s.tokenService.IssueOAuthTokens(ctx, user, client.ClientID, code.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := utils.GenerateIDToken(user, client.ClientID, code.Nonce, code.AuthTime.Time, authResponse.Token, code.Scopes, s.keys, s.config)
+	if err != nil {
+		return nil, fmt.Errorf("error signing id token: %w", err)
+	}
+
+	response := s.tokenResponse(authResponse, code.Scopes)
+	response.IDToken = idToken
+
+	s.logger.Info("OAuth tokens issued",
+		zap.String("user_id", user.ID.String()),
+		zap.String("client_id", client.ClientID),
+	)
+
+	return response, nil
+}
+
+func (s *oauthService) refresh(ctx context.Context, client *model.OAuthClient, req *model.TokenRequest) (*model.TokenResponse, error) {
+	if !slices.Contains(client.Scopes, scopeOfflineAccess) {
+		return nil, &OAuthError{Code: "unauthorized_client", Description: "client may not use refresh tokens"}
+	}
+
+	authResponse, err := s.tokenService.RefreshTokens(ctx, req.RefreshToken, client.ClientID)
+	if err != nil {
+		switch err.Error() {
+		case constants.ErrInvalidRefreshToken, constants.ErrRefreshTokenReused:
+			return nil, &OAuthError{Code: "invalid_grant", Description: err.Error()}
+		}
+		return nil, err
+	}
+
+	s.logger.Info("OAuth tokens refreshed",
+		zap.String("user_id", authResponse.User.ID.String()),
+		zap.String("client_id", client.ClientID),
+	)
+
+	return s.tokenResponse(authResponse, authResponse.Scopes), nil
+}
+
+// clientCredentials issues a service token. Requested scopes must be a subset of
+// the client's; requesting none grants all of them.
+func (s *oauthService) clientCredentials(ctx context.Context, client *model.OAuthClient, req *model.TokenRequest) (*model.TokenResponse, error) {
+	if client.IsPublic() {
+		return nil, &OAuthError{Code: "unauthorized_client", Description: "public clients may not use the client credentials grant"}
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, &OAuthError{Code: "invalid_scope", Description: fmt.Sprintf("scope %q is not allowed for this client", scope)}
+		}
+	}
+
+	token, claims, err := utils.GenerateServiceToken(client, scopes, s.keys, s.config)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	s.logger.Info("OAuth service token issued",
+		zap.String("client_id", client.ClientID),
+		zap.String("token_id", claims.ID),
+		zap.Strings("scopes", scopes),
+	)
+
+	return &model.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.JWT.ExpiresIn),
+		Scope:       claims.Scope,
+	}, nil
+}
+
+// tokenResponse maps an AuthResponse to the OAuth wire format. Refresh tokens are
+// only handed out when offline_access was granted.
+func (s *oauthService) tokenResponse(authResponse *model.AuthResponse, scopes []string) *model.TokenResponse {
+	response := &model.TokenResponse{
+		AccessToken: authResponse.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.JWT.ExpiresIn),
+		Scope:       strings.Join(scopes, " "),
+	}
+	if slices.Contains(scopes, scopeOfflineAccess) {
+		response.RefreshToken = authResponse.RefreshToken
+	}
+	return response
+}
+
+// Introspect reports on any token this service issues: JWT access tokens
+// (including service and impersonation tokens), refresh tokens and API keys.
+// Only resource servers may introspect, so a client cannot learn about tokens
+// it was handed by mistake. Tokens are told apart by their format, so
+// token_type_hint is not needed.
+func (s *oauthService) Introspect(ctx context.Context, req *model.TokenIntrospectionRequest) (*model.IntrospectionResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.ResourceServer {
+		s.logger.Warn("OAuth introspection refused", zap.String("client_id", client.ClientID))
+		return nil, &OAuthError{Code: "invalid_client", Description: "only resource servers may introspect tokens"}
+	}
+
+	inactive := &model.IntrospectionResponse{Active: false}
+
+	switch {
+	case req.Token == "":
+		return inactive, nil
+
+	case strings.HasPrefix(req.Token, APIKeyPrefix):
+		key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, utils.HashToken(req.Token))
+		if err != nil {
+			if err.Error() == constants.ErrInvalidAPIKey {
+				return inactive, nil
+			}
+			return nil, err
+		}
+		return &model.IntrospectionResponse{
+			Active:    true,
+			Scope:     strings.Join(key.Scopes, " "),
+			Username:  key.UserEmail,
+			TokenType: "Bearer",
+			ExpiresAt: key.ExpiresAt.Time.Unix(),
+			IssuedAt:  key.CreatedAt.Time.Unix(),
+			Subject:   key.UserID.String(),
+		}, nil
+
+	case strings.Count(req.Token, ".") == 2:
+		claims, err := s.parseAccessToken(req.Token)
+		if err != nil {
+			return inactive, nil
+		}
+		revoked, err := s.revocationService.IsRevoked(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return inactive, nil
+		}
+		return introspectClaims(claims), nil
+
+	default:
+		token, err := s.refreshRepo.GetRefreshTokenByHash(ctx, utils.HashToken(req.Token))
+		if err != nil {
+			if err.Error() == constants.ErrInvalidRefreshToken {
+				return inactive, nil
+			}
+			return nil, err
+		}
+		if token.RevokedAt.Valid || token.UsedAt.Valid || time.Now().After(token.ExpiresAt.Time) {
+			return inactive, nil
+		}
+		return &model.IntrospectionResponse{
+			Active:    true,
+			ExpiresAt: token.ExpiresAt.Time.Unix(),
+			IssuedAt:  token.CreatedAt.Time.Unix(),
+			Subject:   token.UserID.String(),
+		}, nil
+	}
+}
+
+// parseAccessToken accepts first-party access tokens as well as those issued to
+// OAuth clients
+func (s *oauthService) parseAccessToken(token string) (*utils.Claims, error) {
+	if claims, err := utils.ValidateToken(token, s.keys); err == nil {
+		return claims, nil
+	}
+	return utils.ValidateOAuthToken(token, s.config.OIDC.Issuer, s.keys)
+}
+
+// introspectClaims describes a valid access token. First-party user tokens carry
+// no scope because they grant everything their user may do.
+func introspectClaims(claims *utils.Claims) *model.IntrospectionResponse {
+	response := &model.IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		Subject:   claims.Subject,
+		Issuer:    claims.Issuer,
+		TokenID:   claims.ID,
+	}
+	if claims.IssuedAt != nil {
+		response.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if !claims.IsService() {
+		response.Username = claims.Email
+	}
+	if claims.Impersonated() {
+		response.Actor = &model.IntrospectionActor{Subject: claims.Actor.UserID.String(), Email: claims.Actor.Email}
+	}
+	return response
+}
+
+// Revoke invalidates a token. As RFC 7009 requires, unknown, expired and already
+// revoked tokens are not an error, and a token may only be revoked by the client
+// it was issued to. Public clients only identify themselves, so this is what
+// keeps them from revoking tokens that are not theirs. API keys and first-party
+// tokens were issued to no client and cannot be revoked here. Revoking a refresh
+// token ends its session, so the access tokens issued from it stop working too.
+func (s *oauthService) Revoke(ctx context.Context, req *model.TokenIntrospectionRequest) error {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	notIssued := &OAuthError{Code: "unauthorized_client", Description: "token was not issued to this client"}
+
+	switch {
+	case req.Token == "":
+		return nil
+
+	case strings.HasPrefix(req.Token, APIKeyPrefix):
+		key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, utils.HashToken(req.Token))
+		if err != nil {
+			if err.Error() == constants.ErrInvalidAPIKey {
+				return nil
+			}
+			return err
+		}
+		s.logger.Warn("OAuth client tried to revoke an API key",
+			zap.String("client_id", client.ClientID),
+			zap.String("api_key_id", key.ID.String()),
+		)
+		return notIssued
+
+	case strings.Count(req.Token, ".") == 2:
+		claims, err := s.parseAccessToken(req.Token)
+		if err != nil {
+			// Expired or forged tokens need no revoking
+			return nil
+		}
+		if claims.ClientID != client.ClientID {
+			return notIssued
+		}
+		return s.revocationService.RevokeToken(ctx, claims)
+
+	default:
+		token, err := s.refreshRepo.GetRefreshTokenByHash(ctx, utils.HashToken(req.Token))
+		if err != nil {
+			if err.Error() == constants.ErrInvalidRefreshToken {
+				return nil
+			}
+			return err
+		}
+		session, err := s.sessionRepo.GetSession(ctx, token.FamilyID)
+		if err != nil {
+			// Families without a session predate OAuth sessions and are first-party
+			if err.Error() == constants.ErrSessionNotFound {
+				return notIssued
+			}
+			return err
+		}
+		if session.ClientID.String != client.ClientID {
+			return notIssued
+		}
+		if err := s.revocationService.RevokeSession(ctx, token.FamilyID); err != nil {
+			return err
+		}
+		s.logger.Info("Refresh token revoked by OAuth client",
+			zap.String("client_id", client.ClientID),
+			zap.String("user_id", token.UserID.String()),
+			zap.String("session_id", token.FamilyID.String()),
+		)
+		return nil
+	}
+}
+
+// authenticateClient checks the client secret of confidential clients. Public
+// clients only identify themselves; PKCE binds the code to them instead.
+func (s *oauthService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*model.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		if err.Error() == constants.ErrOAuthClientNotFound {
+			return nil, &OAuthError{Code: "invalid_client", Description: "client authentication failed"}
+		}
+		return nil, err
+	}
+
+	if client.IsPublic() {
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		s.logger.Warn("OAuth client authentication failed", zap.String("client_id", clientID))
+		return nil, &OAuthError{Code: "invalid_client", Description: "client authentication failed"}
+	}
+
+	return client, nil
+}
+
+// UserInfo returns the claims covered by scopes, as the ID token does
+func (s *oauthService) UserInfo(ctx context.Context, userID pgtype.UUID, scopes []string) (*model.UserInfoResponse, error) {
+	user, err := s.authService.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &model.UserInfoResponse{Subject: user.ID.String()}
+	if slices.Contains(scopes, "email") {
+		verified := user.EmailVerifiedAt.Valid
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+	if slices.Contains(scopes, "profile") {
+		info.Name = user.Name
+	}
+
+	return info, nil
+}
+
+func (s *oauthService) Discovery() *model.OpenIDConfiguration {
+	issuer := strings.TrimSuffix(s.config.OIDC.Issuer, "/")
+
+	return &model.OpenIDConfiguration{
+		Issuer:                                    issuer,
+		AuthorizationEndpoint:                     issuer + "/oauth/authorize",
+		TokenEndpoint:                             issuer + "/oauth/token",
+		UserInfoEndpoint:                          issuer + "/oauth/userinfo",
+		IntrospectionEndpoint:                     issuer + "/oauth/introspect",
+		RevocationEndpoint:                        issuer + "/oauth/revoke",
+		JWKSURI:                                   issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:                    []string{"code"},
+		GrantTypesSupported:                       []string{model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken, model.GrantTypeClientCredentials},
+		SubjectTypesSupported:                     []string{"public"},
+		IDTokenSigningAlgValuesSupported:          []string{s.keys.ActiveAlgorithm()},
+		ScopesSupported:                           SupportedScopes,
+		ClaimsSupported:                           []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified", "name"},
+		TokenEndpointAuthMethodsSupported:         []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:             []string{utils.PKCEMethodS256},
+		IntrospectionEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		RevocationEndpointAuthMethodsSupported:    []string{"client_secret_basic", "client_secret_post", "none"},
+	}
+}
+
+// CreateClient registers a client. The plaintext secret is returned once and only
+// its hash is stored; public clients get no secret.
+func (s *oauthService) CreateClient(ctx context.Context, name string, redirectURIs, scopes []string, public bool) (*model.OAuthClient, string, error) {
+	for _, scope := range scopes {
+		if !slices.Contains(SupportedScopes, scope) {
+			return nil, "", fmt.Errorf("unsupported scope %q", scope)
+		}
+	}
+	if !slices.Contains(scopes, scopeOpenID) {
+		scopes = append([]string{scopeOpenID}, scopes...)
+	}
+
+	for _, uri := range redirectURIs {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Fragment != "" {
+			return nil, "", fmt.Errorf("invalid redirect uri %q", uri)
+		}
+	}
+
+	clientID, err := utils.GenerateRandomToken(oauthClientIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var secret, secretHash string
+	if !public {
+		if secret, err = utils.GenerateRandomToken(oauthClientSecretBytes); err != nil {
+			return nil, "", err
+		}
+		secretHash = utils.HashToken(secret)
+	}
+
+	client, err := s.oauthRepo.CreateClient(ctx, &model.OAuthClient{
+		ClientID:     clientID,
+		SecretHash:   secretHash,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		GrantTypes:   []string{model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Info("OAuth client registered",
+		zap.String("client_id", client.ClientID),
+		zap.String("name", client.Name),
+	)
+
+	return client, secret, nil
+}
+
+// CreateServiceClient registers a machine client for the client_credentials
+// grant. Like CreateClient, it returns the plaintext secret once.
+func (s *oauthService) CreateServiceClient(ctx context.Context, name string, scopes []string) (*model.OAuthClient, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", errors.New("a service client needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(ServiceScopes, scope) {
+			return nil, "", fmt.Errorf("unsupported scope %q", scope)
+		}
+	}
+
+	clientID, err := utils.GenerateRandomToken(oauthClientIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := utils.GenerateRandomToken(oauthClientSecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := s.oauthRepo.CreateClient(ctx, &model.OAuthClient{
+		ClientID:     clientID,
+		SecretHash:   utils.HashToken(secret),
+		Name:         name,
+		RedirectURIs: []string{},
+		Scopes:       scopes,
+		GrantTypes:   []string{model.GrantTypeClientCredentials},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Info("OAuth service client registered",
+		zap.String("client_id", client.ClientID),
+		zap.String("name", client.Name),
+	)
+
+	return client, secret, nil
+}
+
+// CreateResourceServer registers an API that validates our tokens through the
+// introspection endpoint. It is always confidential and may use no grant.
+func (s *oauthService) CreateResourceServer(ctx context.Context, name string) (*model.OAuthClient, string, error) {
+	clientID, err := utils.GenerateRandomToken(oauthClientIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := utils.GenerateRandomToken(oauthClientSecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := s.oauthRepo.CreateClient(ctx, &model.OAuthClient{
+		ClientID:       clientID,
+		SecretHash:     utils.HashToken(secret),
+		Name:           name,
+		RedirectURIs:   []string{},
+		Scopes:         []string{},
+		GrantTypes:     []string{},
+		ResourceServer: true,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Info("OAuth resource server registered",
+		zap.String("client_id", client.ClientID),
+		zap.String("name", client.Name),
+	)
+
+	return client, secret, nil
+}
+
+// DisableClient stops a client from authenticating. Tokens already issued to it
+// are refused once replicas' revocation caches expire.
+func (s *oauthService) DisableClient(ctx context.Context, clientID string) error {
+	if err := s.oauthRepo.DisableClient(ctx, clientID); err != nil {
+		return err
+	}
+
+	s.logger.Info("OAuth client disabled", zap.String("client_id", clientID))
+	return nil
+}
+
+// RotateClientSecret gives a confidential client a new secret, returned once
+// like at registration, and invalidates the tokens issued under the old one
+func (s *oauthService) RotateClientSecret(ctx context.Context, clientID string) (string, error) {
+	secret, err := utils.GenerateRandomToken(oauthClientSecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.oauthRepo.RotateClientSecret(ctx, clientID, utils.HashToken(secret)); err != nil {
+		return "", err
+	}
+
+	s.logger.Info("OAuth client secret rotated", zap.String("client_id", clientID))
+	return secret, nil
+}
+
+// appendQuery adds params to a URL that may already carry a query string
+func appendQuery(rawURL string, params url.Values) string {
+	if strings.Contains(rawURL, "?") {
+		return rawURL + "&" + params.Encode()
+	}
+	return rawURL + "?" + params.Encode()
+}
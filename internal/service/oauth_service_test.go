@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"go.uber.org/zap"
+)
+
+type fakeConsentClients struct {
+	repository.OAuthRepository
+	client *model.OAuthClient
+	codes  []*model.AuthorizationCode
+}
+
+func (f *fakeConsentClients) GetClientByClientID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	if clientID != f.client.ClientID {
+		return nil, errors.New(constants.ErrOAuthClientNotFound)
+	}
+	return f.client, nil
+}
+
+func (f *fakeConsentClients) CreateAuthorizationCode(ctx context.Context, code *model.AuthorizationCode) error {
+	f.codes = append(f.codes, code)
+	return nil
+}
+
+type fakeLoginSessions struct {
+	repository.SessionRepository
+	sessions map[pgtype.UUID]*model.Session
+}
+
+func (f *fakeLoginSessions) GetSession(ctx context.Context, id pgtype.UUID) (*model.Session, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, errors.New(constants.ErrSessionNotFound)
+	}
+	return session, nil
+}
+
+type fakeProfiles struct {
+	AuthService
+}
+
+func (f *fakeProfiles) GetUserProfile(ctx context.Context, userID pgtype.UUID) (*model.User, error) {
+	return &model.User{ID: userID, Email: "user@example.com"}, nil
+}
+
+func TestAuthorizeUsesSessionAuthTime(t *testing.T) {
+	userID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	loggedInAt := time.Now().Add(-6 * time.Hour).Truncate(time.Second)
+
+	sessions := &fakeLoginSessions{sessions: map[pgtype.UUID]*model.Session{
+		{Bytes: [16]byte{2}, Valid: true}: {
+			UserID:    userID,
+			CreatedAt: pgtype.Timestamptz{Time: loggedInAt, Valid: true},
+		},
+		{Bytes: [16]byte{3}, Valid: true}: {
+			UserID:    pgtype.UUID{Bytes: [16]byte{9}, Valid: true},
+			CreatedAt: pgtype.Timestamptz{Time: loggedInAt, Valid: true},
+		},
+		{Bytes: [16]byte{4}, Valid: true}: {
+			UserID:    userID,
+			CreatedAt: pgtype.Timestamptz{Time: loggedInAt, Valid: true},
+			RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		},
+	}}
+
+	tests := []struct {
+		name      string
+		sessionID pgtype.UUID
+		wantErr   string
+	}{
+		{name: "session of the user", sessionID: pgtype.UUID{Bytes: [16]byte{2}, Valid: true}},
+		{name: "session of another user", sessionID: pgtype.UUID{Bytes: [16]byte{3}, Valid: true}, wantErr: constants.ErrSessionNotFound},
+		{name: "revoked session", sessionID: pgtype.UUID{Bytes: [16]byte{4}, Valid: true}, wantErr: constants.ErrSessionNotFound},
+		{name: "unknown session", sessionID: pgtype.UUID{Bytes: [16]byte{5}, Valid: true}, wantErr: constants.ErrSessionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &fakeConsentClients{client: &model.OAuthClient{
+				ClientID:     "client-1",
+				RedirectURIs: []string{"https://app.example.com/callback"},
+				Scopes:       []string{"openid", "email"},
+				GrantTypes:   []string{model.GrantTypeAuthorizationCode},
+			}}
+			cfg := &config.Config{}
+			cfg.OIDC.AuthCodeTTL = time.Minute
+
+			svc := NewOAuthService(clients, nil, sessions, nil, &fakeProfiles{}, nil, nil, nil, cfg, zap.NewNop())
+			_, err := svc.Authorize(context.Background(), userID, tt.sessionID, &model.AuthorizeDecisionRequest{
+				AuthorizeRequest: model.AuthorizeRequest{
+					ResponseType:        "code",
+					ClientID:            "client-1",
+					RedirectURI:         "https://app.example.com/callback",
+					Scope:               "openid email",
+					CodeChallenge:       "challenge",
+					CodeChallengeMethod: "S256",
+				},
+				Approve: true,
+			})
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Authorize error = %v, want %q", err, tt.wantErr)
+				}
+				if len(clients.codes) != 0 {
+					t.Error("issued an authorization code without a valid session")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authorize: %v", err)
+			}
+			if len(clients.codes) != 1 {
+				t.Fatalf("issued %d authorization codes, want 1", len(clients.codes))
+			}
+			if got := clients.codes[0].AuthTime.Time; !got.Equal(loggedInAt) {
+				t.Errorf("auth_time = %v, want the session start %v", got, loggedInAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const invitationTokenBytes = 32
+
+// orgRoleRank orders organization roles; a higher rank includes the lower ones
+var orgRoleRank = map[string]int{
+	model.OrgRoleMember: 1,
+	model.OrgRoleAdmin:  2,
+	model.OrgRoleOwner:  3,
+}
+
+// OrgRoleAtLeast reports whether role grants everything minimum does
+func OrgRoleAtLeast(role, minimum string) bool {
+	return orgRoleRank[role] >= orgRoleRank[minimum]
+}
+
+// OrganizationService manages tenants, their members and invitations. Methods
+// taking an actor membership assume RequireOrgMember has loaded it; role rules
+// that depend on the target (e.g. only owners manage owners) are enforced here.
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, userID pgtype.UUID, req *model.CreateOrganizationRequest) (*model.Organization, error)
+	ListUserOrganizations(ctx context.Context, userID pgtype.UUID) ([]*model.Organization, error)
+	GetOrganization(ctx context.Context, orgID pgtype.UUID) (*model.Organization, error)
+	UpdateOrganization(ctx context.Context, orgID pgtype.UUID, req *model.UpdateOrganizationRequest) (*model.Organization, error)
+	DeleteOrganization(ctx context.Context, orgID pgtype.UUID) error
+
+	GetMembership(ctx context.Context, orgID, userID pgtype.UUID) (*model.Membership, error)
+	ListMembers(ctx context.Context, orgID pgtype.UUID) ([]*model.Membership, error)
+	UpdateMemberRole(ctx context.Context, actor *model.Membership, userID pgtype.UUID, role string) error
+	RemoveMember(ctx context.Context, actor *model.Membership, userID pgtype.UUID) error
+
+	Invite(ctx context.Context, actor *model.Membership, req *model.InviteMemberRequest) (*model.Invitation, error)
+	ListInvitations(ctx context.Context, orgID pgtype.UUID) ([]*model.Invitation, error)
+	RevokeInvitation(ctx context.Context, orgID, id pgtype.UUID) error
+	AcceptInvitation(ctx context.Context, userID pgtype.UUID, token string) (*model.Organization, error)
+	DeclineInvitation(ctx context.Context, userID pgtype.UUID, token string) error
+
+	SwitchOrganization(ctx context.Context, claims *utils.Claims, orgID pgtype.UUID) (*model.AuthResponse, error)
+	SwitchSessionOrganization(ctx context.Context, session *model.Session, orgID pgtype.UUID) error
+}
+
+type organizationService struct {
+	orgRepo      repository.OrganizationRepository
+	userRepo     repository.UserRepository
+	sessionRepo  repository.SessionRepository
+	tokenService TokenService
+	emailService EmailService
+	config       *config.Config
+	logger       *zap.Logger
+}
+
+func NewOrganizationService(
+	orgRepo repository.OrganizationRepository,
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	tokenService TokenService,
+	emailService EmailService,
+	config *config.Config,
+	logger *zap.Logger,
+) OrganizationService {
+	return &organizationService{
+		orgRepo:      orgRepo,
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		tokenService: tokenService,
+		emailService: emailService,
+		config:       config,
+		logger:       logger,
+	}
+}
+
+func (s *organizationService) CreateOrganization(ctx context.Context, userID pgtype.UUID, req *model.CreateOrganizationRequest) (*model.Organization, error) {
+	org, err := s.orgRepo.CreateOrganization(ctx, req.Name, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Organization created",
+		zap.String("org_id", org.ID.String()),
+		zap.String("user_id", userID.String()),
+	)
+	return org, nil
+}
+
+func (s *organizationService) ListUserOrganizations(ctx context.Context, userID pgtype.UUID) ([]*model.Organization, error) {
+	return s.orgRepo.ListUserOrganizations(ctx, userID)
+}
+
+func (s *organizationService) GetOrganization(ctx context.Context, orgID pgtype.UUID) (*model.Organization, error) {
+	return s.orgRepo.GetOrganization(ctx, orgID)
+}
+
+func (s *organizationService) UpdateOrganization(ctx context.Context, orgID pgtype.UUID, req *model.UpdateOrganizationRequest) (*model.Organization, error) {
+	return s.orgRepo.UpdateOrganization(ctx, orgID, req.Name)
+}
+
+func (s *organizationService) DeleteOrganization(ctx context.Context, orgID pgtype.UUID) error {
+	if err := s.orgRepo.DeleteOrganization(ctx, orgID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Organization deleted", zap.String("org_id", orgID.String()))
+	return nil
+}
+
+func (s *organizationService) GetMembership(ctx context.Context, orgID, userID pgtype.UUID) (*model.Membership, error) {
+	return s.orgRepo.GetMembership(ctx, orgID, userID)
+}
+
+func (s *organizationService) ListMembers(ctx context.Context, orgID pgtype.UUID) ([]*model.Membership, error) {
+	return s.orgRepo.ListMembers(ctx, orgID)
+}
+
+// UpdateMemberRole lets admins manage members and admins; granting or taking
+// away ownership takes an owner
+func (s *organizationService) UpdateMemberRole(ctx context.Context, actor *model.Membership, userID pgtype.UUID, role string) error {
+	target, err := s.orgRepo.GetMembership(ctx, actor.OrgID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !s.canManage(actor, target.Role) || !s.canManage(actor, role) {
+		return errors.New(constants.ErrInsufficientOrgRole)
+	}
+
+	updated, err := s.orgRepo.UpdateMemberRole(ctx, actor.OrgID, userID, role)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.New(constants.ErrLastOrgOwner)
+	}
+
+	s.logger.Info("Organization member role changed",
+		zap.String("org_id", actor.OrgID.String()),
+		zap.String("user_id", userID.String()),
+		zap.String("actor_id", actor.UserID.String()),
+		zap.String("role", role),
+	)
+	return nil
+}
+
+// RemoveMember also covers leaving: any member may remove themselves
+func (s *organizationService) RemoveMember(ctx context.Context, actor *model.Membership, userID pgtype.UUID) error {
+	target, err := s.orgRepo.GetMembership(ctx, actor.OrgID, userID)
+	if err != nil {
+		return err
+	}
+
+	if target.UserID != actor.UserID && !s.canManage(actor, target.Role) {
+		return errors.New(constants.ErrInsufficientOrgRole)
+	}
+
+	removed, err := s.orgRepo.RemoveMember(ctx, actor.OrgID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New(constants.ErrLastOrgOwner)
+	}
+
+	// Access is already gone, since membership is checked per request; this keeps
+	// the stale organization out of tokens refreshed from now on
+	if err := s.sessionRepo.ClearSessionOrganization(ctx, userID, actor.OrgID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Organization member removed",
+		zap.String("org_id", actor.OrgID.String()),
+		zap.String("user_id", userID.String()),
+		zap.String("actor_id", actor.UserID.String()),
+	)
+	return nil
+}
+
+// canManage reports whether actor may act on a member holding role, or grant it.
+// Admins manage admins and members; only owners manage owners.
+func (s *organizationService) canManage(actor *model.Membership, role string) bool {
+	if role == model.OrgRoleOwner {
+		return actor.Role == model.OrgRoleOwner
+	}
+	return OrgRoleAtLeast(actor.Role, model.OrgRoleAdmin)
+}
+
+// Invite emails a link to join the organization. Inviting the same address
+// again replaces the earlier invitation.
+func (s *organizationService) Invite(ctx context.Context, actor *model.Membership, req *model.InviteMemberRequest) (*model.Invitation, error) {
+	if !s.canManage(actor, req.Role) {
+		return nil, errors.New(constants.ErrInsufficientOrgRole)
+	}
+
+	email := utils.NormalizeEmail(req.Email)
+	if user, err := s.userRepo.GetUserByEmail(ctx, email); err == nil {
+		if _, err := s.orgRepo.GetMembership(ctx, actor.OrgID, user.ID); err == nil {
+			return nil, errors.New(constants.ErrAlreadyMember)
+		}
+	}
+
+	org, err := s.orgRepo.GetOrganization(ctx, actor.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(invitationTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error generating invitation token: %w", err)
+	}
+
+	invitation, err := s.orgRepo.CreateInvitation(ctx, &model.Invitation{
+		OrgID:     actor.OrgID,
+		Email:     email,
+		Role:      req.Role,
+		InvitedBy: actor.UserID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(s.config.Auth.InvitationTTL), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.emailService.SendInvitationEmail(ctx, invitation, org, actor, token); err != nil {
+		return nil, fmt.Errorf("error sending invitation email: %w", err)
+	}
+
+	s.logger.Info("Organization invitation sent",
+		zap.String("org_id", actor.OrgID.String()),
+		zap.String("invitation_id", invitation.ID.String()),
+		zap.String("actor_id", actor.UserID.String()),
+	)
+	return invitation, nil
+}
+
+func (s *organizationService) ListInvitations(ctx context.Context, orgID pgtype.UUID) ([]*model.Invitation, error) {
+	return s.orgRepo.ListPendingInvitations(ctx, orgID)
+}
+
+func (s *organizationService) RevokeInvitation(ctx context.Context, orgID, id pgtype.UUID) error {
+	return s.orgRepo.DeleteInvitation(ctx, orgID, id)
+}
+
+// AcceptInvitation requires the invitation to have been sent to the user's own address
+func (s *organizationService) AcceptInvitation(ctx context.Context, userID pgtype.UUID, token string) (*model.Organization, error) {
+	invitation, err := s.invitationFor(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.orgRepo.AcceptInvitation(ctx, invitation, userID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Organization invitation accepted",
+		zap.String("org_id", invitation.OrgID.String()),
+		zap.String("invitation_id", invitation.ID.String()),
+		zap.String("user_id", userID.String()),
+	)
+
+	org, err := s.orgRepo.GetOrganization(ctx, invitation.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	membership, err := s.orgRepo.GetMembership(ctx, invitation.OrgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	org.Role = membership.Role
+
+	return org, nil
+}
+
+func (s *organizationService) DeclineInvitation(ctx context.Context, userID pgtype.UUID, token string) error {
+	invitation, err := s.invitationFor(ctx, userID, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.DeclineInvitation(ctx, invitation.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Organization invitation declined",
+		zap.String("org_id", invitation.OrgID.String()),
+		zap.String("invitation_id", invitation.ID.String()),
+		zap.String("user_id", userID.String()),
+	)
+	return nil
+}
+
+func (s *organizationService) invitationFor(ctx context.Context, userID pgtype.UUID, token string) (*model.Invitation, error) {
+	invitation, err := s.orgRepo.GetInvitationByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, errors.New(constants.ErrInvitationNotFound)
+	}
+
+	return invitation, nil
+}
+
+// SwitchOrganization sets the organization carried by the session's tokens. An
+// unset orgID goes back to no active organization.
+func (s *organizationService) SwitchOrganization(ctx context.Context, claims *utils.Claims, orgID pgtype.UUID) (*model.AuthResponse, error) {
+	if !claims.SessionID.Valid {
+		return nil, errors.New(constants.ErrSessionNotFound)
+	}
+
+	if orgID.Valid {
+		if _, err := s.orgRepo.GetMembership(ctx, orgID, claims.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	return s.tokenService.SwitchOrganization(ctx, user, claims.SessionID, orgID)
+}
+
+// SwitchSessionOrganization sets the organization of a cookie session. The
+// session lives on the server, so storing it there is enough; there is no
+// token to reissue.
+func (s *organizationService) SwitchSessionOrganization(ctx context.Context, session *model.Session, orgID pgtype.UUID) error {
+	if orgID.Valid {
+		if _, err := s.orgRepo.GetMembership(ctx, orgID, session.UserID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.sessionRepo.SetSessionOrganization(ctx, session.ID, orgID); err != nil {
+		return err
+	}
+	session.OrgID = orgID
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// minUserInfoLength keeps short names and email parts like "al" from rejecting
+// half of all passwords
+const minUserInfoLength = 3
+
+// PasswordPolicyError lists every rule a new password broke
+type PasswordPolicyError struct {
+	Violations []model.PasswordPolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return constants.ErrPasswordPolicy
+}
+
+type PasswordPolicyService interface {
+	// Validate checks a new password for user. For a user that does not exist
+	// yet only Email and Name need to be set.
+	Validate(ctx context.Context, field, password string, user *model.User) error
+	// Remember adds a freshly set password hash to the user's history
+	Remember(ctx context.Context, userID pgtype.UUID, passwordHash string) error
+	IsExpired(user *model.User) bool
+}
+
+type passwordPolicyService struct {
+	historyRepo    repository.PasswordHistoryRepository
+	passwordHasher *utils.PasswordHasher
+	breached       *utils.BreachedPasswords
+	config         *config.Config
+	logger         *zap.Logger
+}
+
+// NewPasswordPolicyService takes a nil breached list when none is configured
+func NewPasswordPolicyService(
+	historyRepo repository.PasswordHistoryRepository,
+	passwordHasher *utils.PasswordHasher,
+	breached *utils.BreachedPasswords,
+	config *config.Config,
+	logger *zap.Logger,
+) PasswordPolicyService {
+	return &passwordPolicyService{
+		historyRepo:    historyRepo,
+		passwordHasher: passwordHasher,
+		breached:       breached,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+func (s *passwordPolicyService) Validate(ctx context.Context, field, password string, user *model.User) error {
+	policy := &s.config.PasswordPolicy
+	var violations []model.PasswordPolicyViolation
+	violate := func(rule, message string) {
+		violations = append(violations, model.PasswordPolicyViolation{Field: field, Rule: rule, Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		violate(model.PasswordRuleMinLength, fmt.Sprintf("Password must be at least %d characters long", policy.MinLength))
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		violate(model.PasswordRuleMaxLength, fmt.Sprintf("Password must be at most %d characters long", policy.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUppercase && !hasUpper {
+		violate(model.PasswordRuleUppercase, "Password must contain an uppercase letter")
+	}
+	if policy.RequireLowercase && !hasLower {
+		violate(model.PasswordRuleLowercase, "Password must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		violate(model.PasswordRuleDigit, "Password must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		violate(model.PasswordRuleSymbol, "Password must contain a symbol")
+	}
+
+	if policy.DisallowUserInfo && containsUserInfo(password, user) {
+		violate(model.PasswordRuleUserInfo, "Password must not contain your email address or name")
+	}
+
+	// The list only adds protection, so a failed lookup fails open rather than
+	// blocking every registration and password change
+	if s.breached != nil {
+		breached, err := s.breached.Contains(password)
+		if err != nil {
+			s.logger.Error("Breached password check failed, skipping it", zap.Error(err))
+		}
+		if breached {
+			violate(model.PasswordRuleBreached, "Password has appeared in a data breach, choose another one")
+		}
+	}
+
+	if policy.HistorySize > 0 && user.ID.Valid {
+		reused, err := s.isReused(ctx, password, user)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violate(model.PasswordRuleHistory, fmt.Sprintf("Password must differ from your last %d passwords", policy.HistorySize))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isReused compares against the current password and the recorded history
+func (s *passwordPolicyService) isReused(ctx context.Context, password string, user *model.User) (bool, error) {
+	hashes, err := s.historyRepo.GetRecentPasswordHashes(ctx, user.ID, s.config.PasswordPolicy.HistorySize)
+	if err != nil {
+		return false, err
+	}
+	if user.Password != "" {
+		hashes = append(hashes, user.Password)
+	}
+
+	for _, hash := range hashes {
+		match, err := s.passwordHasher.Verify(hash, password)
+		if err != nil {
+			s.logger.Warn("Skipping unreadable password history entry",
+				zap.String("user_id", user.ID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *passwordPolicyService) Remember(ctx context.Context, userID pgtype.UUID, passwordHash string) error {
+	if s.config.PasswordPolicy.HistorySize <= 0 {
+		return nil
+	}
+	return s.historyRepo.AddPasswordHistory(ctx, userID, passwordHash, s.config.PasswordPolicy.HistorySize)
+}
+
+func (s *passwordPolicyService) IsExpired(user *model.User) bool {
+	maxAge := s.config.PasswordPolicy.MaxAge
+	if maxAge <= 0 || !user.PasswordChangedAt.Valid {
+		return false
+	}
+	return time.Since(user.PasswordChangedAt.Time) > maxAge
+}
+
+// containsUserInfo reports whether the password contains the email address,
+// its local part or any part of the name
+func containsUserInfo(password string, user *model.User) bool {
+	lowered := strings.ToLower(password)
+
+	email := strings.ToLower(user.Email)
+	localPart, _, _ := strings.Cut(email, "@")
+	candidates := []string{email, localPart}
+	candidates = append(candidates, strings.Fields(strings.ToLower(user.Name))...)
+
+	for _, candidate := range candidates {
+		if utf8.RuneCountInString(candidate) >= minUserInfoLength && strings.Contains(lowered, candidate) {
+			return true
+		}
+	}
+	return false
+}
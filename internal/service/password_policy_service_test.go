@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+func TestPasswordPolicyBreachedCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		closeList bool
+		wantRule  string
+	}{
+		{name: "breached password", password: "password", wantRule: model.PasswordRuleBreached},
+		{name: "unlisted password", password: "unlisted password"},
+		{name: "lookup fails open", password: "password", closeList: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "breached.txt")
+			// SHA-1 of "password"
+			if err := os.WriteFile(path, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"), 0o600); err != nil {
+				t.Fatalf("writing list: %v", err)
+			}
+			list, err := utils.OpenBreachedPasswords(path)
+			if err != nil {
+				t.Fatalf("OpenBreachedPasswords: %v", err)
+			}
+			defer list.Close()
+			if tt.closeList {
+				list.Close()
+			}
+
+			policy := NewPasswordPolicyService(nil, nil, list, &config.Config{}, zap.NewNop())
+			err = policy.Validate(context.Background(), "password", tt.password, &model.User{Email: "user@example.com"})
+
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Validate error = %v, want a PasswordPolicyError", err)
+			}
+			if len(policyErr.Violations) != 1 || policyErr.Violations[0].Rule != tt.wantRule {
+				t.Errorf("violations = %+v, want only %q", policyErr.Violations, tt.wantRule)
+			}
+		})
+	}
+}
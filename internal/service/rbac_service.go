@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// rolePermissionsTTL bounds how long a change to role_permissions takes to apply
+const rolePermissionsTTL = time.Minute
+
+// RBACService resolves permissions from roles and manages role assignments.
+// Access tokens carry the user's roles; permissions are looked up per request
+// so they can change without reissuing tokens.
+type RBACService interface {
+	HasPermission(ctx context.Context, roles []string, permission string) (bool, error)
+	GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error)
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+	AssignRole(ctx context.Context, userID pgtype.UUID, role string) error
+	RemoveRole(ctx context.Context, userID pgtype.UUID, role string) error
+	SeedBootstrapAdmin(ctx context.Context) error
+}
+
+type rbacService struct {
+	userRepo          repository.UserRepository
+	roleRepo          repository.RoleRepository
+	revocationService TokenRevocationService
+	passwordHasher    *utils.PasswordHasher
+	config            *config.Config
+	logger            *zap.Logger
+
+	mu              sync.RWMutex
+	rolePermissions map[string][]string
+	loadedAt        time.Time
+}
+
+func NewRBACService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, revocationService TokenRevocationService, passwordHasher *utils.PasswordHasher, config *config.Config, logger *zap.Logger) RBACService {
+	return &rbacService{
+		userRepo:          userRepo,
+		roleRepo:          roleRepo,
+		revocationService: revocationService,
+		passwordHasher:    passwordHasher,
+		config:            config,
+		logger:            logger,
+	}
+}
+
+func (s *rbacService) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	rolePermissions, err := s.getRolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range roles {
+		if slices.Contains(rolePermissions[role], permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *rbacService) GetUserRoles(ctx context.Context, userID pgtype.UUID) ([]string, error) {
+	return s.roleRepo.GetUserRoles(ctx, userID)
+}
+
+func (s *rbacService) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	return s.roleRepo.ListRoles(ctx)
+}
+
+// AssignRole takes effect with the user's next token
+func (s *rbacService) AssignRole(ctx context.Context, userID pgtype.UUID, role string) error {
+	if err := s.roleRepo.AssignRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	s.logger.Info("Role assigned",
+		zap.String("user_id", userID.String()),
+		zap.String("role", role),
+	)
+	return nil
+}
+
+// RemoveRole revokes the user's tokens, since they still list the removed role
+func (s *rbacService) RemoveRole(ctx context.Context, userID pgtype.UUID, role string) error {
+	removed, err := s.roleRepo.RemoveRole(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New(constants.ErrRoleNotFound)
+	}
+
+	if err := s.revocationService.RevokeAllUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("error revoking tokens: %w", err)
+	}
+
+	s.logger.Info("Role removed",
+		zap.String("user_id", userID.String()),
+		zap.String("role", role),
+	)
+	return nil
+}
+
+// SeedBootstrapAdmin makes the configured bootstrap account an admin, creating it
+// if needed, so a fresh deployment can be administered without touching the
+// database. It does nothing once any admin exists: otherwise whoever registered
+// the bootstrap email could claim the role on the next restart. For the same
+// reason an existing account must have verified its email.
+func (s *rbacService) SeedBootstrapAdmin(ctx context.Context) error {
+	email := utils.NormalizeEmail(s.config.Auth.BootstrapAdminEmail)
+	if email == "" {
+		return nil
+	}
+
+	hasAdmin, err := s.roleRepo.RoleHasMembers(ctx, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if hasAdmin {
+		s.logger.Info("Bootstrap admin skipped, an admin already exists")
+		return nil
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err == nil && !user.EmailVerifiedAt.Valid {
+		s.logger.Warn("⚠️ Bootstrap admin not seeded, the existing account has not verified its email", zap.String("email", email))
+		return nil
+	}
+	if err != nil {
+		if err.Error() != constants.ErrUserNotFound {
+			return err
+		}
+		if s.config.Auth.BootstrapAdminPassword == "" {
+			s.logger.Warn("⚠️ Bootstrap admin does not exist and no password is configured to create it", zap.String("email", email))
+			return nil
+		}
+
+		passwordHash, err := s.passwordHasher.Hash(s.config.Auth.BootstrapAdminPassword)
+		if err != nil {
+			return fmt.Errorf("error hashing password: %w", err)
+		}
+		user, err = s.userRepo.CreateUser(ctx, email, passwordHash, "Administrator")
+		if err != nil {
+			return fmt.Errorf("error creating bootstrap admin: %w", err)
+		}
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return err
+		}
+		s.logger.Info("Bootstrap admin created", zap.String("email", email))
+	}
+
+	return s.roleRepo.AssignRole(ctx, user.ID, model.RoleAdmin)
+}
+
+func (s *rbacService) getRolePermissions(ctx context.Context) (map[string][]string, error) {
+	s.mu.RLock()
+	if s.rolePermissions != nil && time.Since(s.loadedAt) < rolePermissionsTTL {
+		rolePermissions := s.rolePermissions
+		s.mu.RUnlock()
+		return rolePermissions, nil
+	}
+	s.mu.RUnlock()
+
+	roles, err := s.roleRepo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rolePermissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		rolePermissions[role.Name] = role.Permissions
+	}
+
+	s.mu.Lock()
+	s.rolePermissions = rolePermissions
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return rolePermissions, nil
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// TokenRevocationService decides whether an otherwise valid access token has been
+// revoked, either individually (logout), through its session, through the
+// user's token version or through the OAuth client it was issued to.
+type TokenRevocationService interface {
+	RevokeToken(ctx context.Context, claims *utils.Claims) error
+	RevokeSession(ctx context.Context, sessionID pgtype.UUID) error
+	RevokeAllUserTokens(ctx context.Context, userID pgtype.UUID) error
+	IsRevoked(ctx context.Context, claims *utils.Claims) (bool, error)
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+type cachedRevocation struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+// cachedClient is the token cutoff of an OAuth client; gone clients were deleted
+type cachedClient struct {
+	cutoff    time.Time
+	gone      bool
+	expiresAt time.Time
+}
+
+type cachedVersion struct {
+	version   int
+	expiresAt time.Time
+}
+
+type tokenRevocationService struct {
+	revocationRepo repository.RevocationRepository
+	refreshRepo    repository.RefreshTokenRepository
+	sessionRepo    repository.SessionRepository
+	oauthRepo      repository.OAuthRepository
+	cacheTTL       time.Duration
+	accessTokenTTL time.Duration
+	logger         *zap.Logger
+
+	mu       sync.RWMutex
+	tokens   map[string]cachedRevocation
+	sessions map[pgtype.UUID]cachedRevocation
+	versions map[pgtype.UUID]cachedVersion
+	clients  map[string]cachedClient
+}
+
+func NewTokenRevocationService(revocationRepo repository.RevocationRepository, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, oauthRepo repository.OAuthRepository, config *config.Config, logger *zap.Logger) TokenRevocationService {
+	return &tokenRevocationService{
+		revocationRepo: revocationRepo,
+		refreshRepo:    refreshRepo,
+		sessionRepo:    sessionRepo,
+		oauthRepo:      oauthRepo,
+		cacheTTL:       config.JWT.RevocationCacheTTL,
+		accessTokenTTL: time.Duration(config.JWT.ExpiresIn) * time.Second,
+		logger:         logger,
+		tokens:         make(map[string]cachedRevocation),
+		sessions:       make(map[pgtype.UUID]cachedRevocation),
+		versions:       make(map[pgtype.UUID]cachedVersion),
+		clients:        make(map[string]cachedClient),
+	}
+}
+
+func (s *tokenRevocationService) RevokeToken(ctx context.Context, claims *utils.Claims) error {
+	expiresAt := claims.ExpiresAt.Time
+	if err := s.revocationRepo.RevokeToken(ctx, claims.ID, claims.UserID, expiresAt); err != nil {
+		return err
+	}
+
+	// A revoked token stays revoked, so it can be cached until it expires anyway
+	s.mu.Lock()
+	s.tokens[claims.ID] = cachedRevocation{revoked: true, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	s.logger.Info("Access token revoked",
+		zap.String("user_id", claims.UserID.String()),
+		zap.String("jti", claims.ID),
+	)
+	return nil
+}
+
+// RevokeSession logs one device out: its refresh tokens stop working and so
+// does every access token carrying its sid
+func (s *tokenRevocationService) RevokeSession(ctx context.Context, sessionID pgtype.UUID) error {
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.refreshRepo.RevokeRefreshTokenFamily(ctx, sessionID); err != nil {
+		return err
+	}
+
+	// No access token of the session outlives this entry
+	s.mu.Lock()
+	s.sessions[sessionID] = cachedRevocation{revoked: true, expiresAt: time.Now().Add(s.accessTokenTTL)}
+	s.mu.Unlock()
+
+	s.logger.Info("Session revoked", zap.String("session_id", sessionID.String()))
+	return nil
+}
+
+// RevokeAllUserTokens bumps the user's token version and revokes every refresh token and session
+func (s *tokenRevocationService) RevokeAllUserTokens(ctx context.Context, userID pgtype.UUID) error {
+	version, err := s.revocationRepo.IncrementTokenVersion(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.versions[userID] = cachedVersion{version: version, expiresAt: time.Now().Add(s.cacheTTL)}
+	s.mu.Unlock()
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.sessionRepo.RevokeUserSessions(ctx, userID)
+}
+
+// IsRevoked consults the in-process cache first. Negative results are only cached
+// for the configured TTL so revocations made by other replicas are picked up.
+func (s *tokenRevocationService) IsRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	// Disabling a client or rotating its secret ends every token issued to it
+	if claims.ClientID != "" {
+		revoked, err := s.clientRevoked(ctx, claims)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	// Service tokens have no user, so only their jti can be revoked otherwise
+	if claims.IsService() {
+		return s.tokenRevoked(ctx, claims)
+	}
+
+	version, err := s.tokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	if claims.TokenVersion != version {
+		return true, nil
+	}
+
+	// Tokens issued before sessions were recorded carry no sid
+	if claims.SessionID.Valid {
+		revoked, err := s.sessionRevoked(ctx, claims.SessionID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	return s.tokenRevoked(ctx, claims)
+}
+
+// sessionRevoked also refreshes the session's last_seen_at whenever the cache is
+// missed, so activity is recorded at most once per cache TTL
+func (s *tokenRevocationService) sessionRevoked(ctx context.Context, sessionID pgtype.UUID) (bool, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	cached, ok := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.revoked, nil
+	}
+
+	active, err := s.sessionRepo.TouchSession(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	expiresAt := now.Add(s.cacheTTL)
+	if !active {
+		expiresAt = now.Add(s.accessTokenTTL)
+	}
+
+	s.mu.Lock()
+	s.pruneLocked(now)
+	s.sessions[sessionID] = cachedRevocation{revoked: !active, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	return !active, nil
+}
+
+// clientRevoked reports whether the token was issued before its client was
+// disabled, deleted or given a new secret. iat only has second precision, so
+// tokens issued in the same second as the cutoff count as revoked.
+func (s *tokenRevocationService) clientRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	cached, ok := s.clients[claims.ClientID]
+	s.mu.RUnlock()
+	if !ok || !now.Before(cached.expiresAt) {
+		cutoff, err := s.oauthRepo.GetClientTokenCutoff(ctx, claims.ClientID)
+		if err != nil && err.Error() != constants.ErrOAuthClientNotFound {
+			return false, err
+		}
+		cached = cachedClient{cutoff: cutoff.Time, gone: err != nil, expiresAt: now.Add(s.cacheTTL)}
+
+		s.mu.Lock()
+		s.pruneLocked(now)
+		s.clients[claims.ClientID] = cached
+		s.mu.Unlock()
+	}
+
+	if cached.gone {
+		return true, nil
+	}
+	if cached.cutoff.IsZero() {
+		return false, nil
+	}
+	return claims.IssuedAt == nil || claims.IssuedAt.Time.Before(cached.cutoff), nil
+}
+
+func (s *tokenRevocationService) tokenVersion(ctx context.Context, userID pgtype.UUID) (int, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	cached, ok := s.versions[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.version, nil
+	}
+
+	version, err := s.revocationRepo.GetTokenVersion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.versions[userID] = cachedVersion{version: version, expiresAt: now.Add(s.cacheTTL)}
+	s.mu.Unlock()
+
+	return version, nil
+}
+
+func (s *tokenRevocationService) tokenRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	now := time.Now()
+
+	s.mu.RLock()
+	cached, ok := s.tokens[claims.ID]
+	s.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := s.revocationRepo.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return false, err
+	}
+
+	expiresAt := now.Add(s.cacheTTL)
+	if revoked {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	s.mu.Lock()
+	s.pruneLocked(now)
+	s.tokens[claims.ID] = cachedRevocation{revoked: revoked, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+// pruneLocked drops stale cache entries once the cache grows large
+func (s *tokenRevocationService) pruneLocked(now time.Time) {
+	const maxEntries = 10000
+	if len(s.tokens) < maxEntries && len(s.sessions) < maxEntries && len(s.clients) < maxEntries {
+		return
+	}
+
+	for jti, entry := range s.tokens {
+		if now.After(entry.expiresAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	for sessionID, entry := range s.sessions {
+		if now.After(entry.expiresAt) {
+			delete(s.sessions, sessionID)
+		}
+	}
+	for userID, entry := range s.versions {
+		if now.After(entry.expiresAt) {
+			delete(s.versions, userID)
+		}
+	}
+	for clientID, entry := range s.clients {
+		if now.After(entry.expiresAt) {
+			delete(s.clients, clientID)
+		}
+	}
+}
+
+// PurgeExpired deletes revocations of tokens past their expiry, which
+// ValidateToken rejects without looking them up
+func (s *tokenRevocationService) PurgeExpired(ctx context.Context) (int, error) {
+	return s.revocationRepo.DeleteExpiredRevocations(ctx)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+type fakeClientCutoffs struct {
+	repository.OAuthRepository
+	cutoffs map[string]time.Time
+}
+
+func (f *fakeClientCutoffs) GetClientTokenCutoff(ctx context.Context, clientID string) (pgtype.Timestamptz, error) {
+	cutoff, ok := f.cutoffs[clientID]
+	if !ok {
+		return pgtype.Timestamptz{}, errors.New(constants.ErrOAuthClientNotFound)
+	}
+	return pgtype.Timestamptz{Time: cutoff, Valid: !cutoff.IsZero()}, nil
+}
+
+type fakeRevokedTokens struct {
+	repository.RevocationRepository
+}
+
+func (f *fakeRevokedTokens) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return false, nil
+}
+
+func TestIsRevokedChecksServiceClient(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	oauthRepo := &fakeClientCutoffs{cutoffs: map[string]time.Time{
+		"untouched": {},
+		"rotated":   now,
+	}}
+
+	cfg := &config.Config{}
+	cfg.JWT.RevocationCacheTTL = time.Minute
+	revocations := NewTokenRevocationService(&fakeRevokedTokens{}, nil, nil, oauthRepo, cfg, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		clientID string
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "client never rotated", clientID: "untouched", issuedAt: now.Add(-time.Hour), want: false},
+		{name: "issued before rotation", clientID: "rotated", issuedAt: now.Add(-time.Second), want: true},
+		{name: "issued at the cutoff", clientID: "rotated", issuedAt: now, want: false},
+		{name: "issued after rotation", clientID: "rotated", issuedAt: now.Add(time.Second), want: false},
+		{name: "client deleted", clientID: "deleted", issuedAt: now, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &utils.Claims{
+				ClientID: tt.clientID,
+				RegisteredClaims: jwt.RegisteredClaims{
+					ID:        tt.name,
+					IssuedAt:  jwt.NewNumericDate(tt.issuedAt),
+					ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				},
+			}
+
+			revoked, err := revocations.IsRevoked(context.Background(), claims)
+			if err != nil {
+				t.Fatalf("IsRevoked: %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
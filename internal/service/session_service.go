@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+// sessionTouchInterval limits last_seen_at writes for cookie sessions, which are
+// looked up on every request
+const sessionTouchInterval = time.Minute
+
+// SessionService lets users see and end their logins on other devices, and
+// authenticates cookie sessions. currentID is the caller's session; it is unset
+// for API keys.
+type SessionService interface {
+	AuthenticateCookie(ctx context.Context, secret string) (*model.Session, error)
+	ListSessions(ctx context.Context, userID, currentID pgtype.UUID) ([]*model.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID pgtype.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID, currentID pgtype.UUID) (int, error)
+}
+
+type sessionService struct {
+	sessionRepo       repository.SessionRepository
+	revocationService TokenRevocationService
+	config            *config.Config
+	logger            *zap.Logger
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, revocationService TokenRevocationService, config *config.Config, logger *zap.Logger) SessionService {
+	return &sessionService{
+		sessionRepo:       sessionRepo,
+		revocationService: revocationService,
+		config:            config,
+		logger:            logger,
+	}
+}
+
+// AuthenticateCookie returns the active session the cookie secret belongs to
+func (s *sessionService) AuthenticateCookie(ctx context.Context, secret string) (*model.Session, error) {
+	session, err := s.sessionRepo.GetSessionByCookie(ctx, utils.HashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to record activity must not fail the request
+	if time.Since(session.LastSeenAt.Time) > sessionTouchInterval {
+		if _, err := s.sessionRepo.TouchSession(ctx, session.ID); err != nil {
+			s.logger.Warn("Failed to record session activity", zap.String("session_id", session.ID.String()), zap.Error(err))
+		}
+	}
+
+	return session, nil
+}
+
+// ListSessions leaves out sessions whose refresh tokens have all expired
+func (s *sessionService) ListSessions(ctx context.Context, userID, currentID pgtype.UUID) ([]*model.Session, error) {
+	seenSince := time.Now().Add(-time.Duration(s.config.JWT.RefreshExpiresIn) * time.Second)
+	sessions, err := s.sessionRepo.ListActiveSessions(ctx, userID, seenSince)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = currentID.Valid && session.ID == currentID
+	}
+
+	return sessions, nil
+}
+
+func (s *sessionService) RevokeSession(ctx context.Context, userID, sessionID pgtype.UUID) error {
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID || session.RevokedAt.Valid {
+		return errors.New(constants.ErrSessionNotFound)
+	}
+
+	return s.revocationService.RevokeSession(ctx, sessionID)
+}
+
+func (s *sessionService) RevokeOtherSessions(ctx context.Context, userID, currentID pgtype.UUID) (int, error) {
+	sessions, err := s.ListSessions(ctx, userID, currentID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.Current {
+			continue
+		}
+		if err := s.revocationService.RevokeSession(ctx, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	s.logger.Info("Other sessions revoked",
+		zap.String("user_id", userID.String()),
+		zap.Int("sessions", revoked),
+	)
+	return revoked, nil
+}
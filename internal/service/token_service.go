@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	refreshTokenBytes  = 32
+	sessionSecretBytes = 32
+	csrfTokenBytes     = 32
+)
+
+// TokenService issues access/refresh token pairs and rotates refresh tokens
+type TokenService interface {
+	IssueTokens(ctx context.Context, user *model.User) (*model.AuthResponse, error)
+	IssueOAuthTokens(ctx context.Context, user *model.User, clientID string, scopes []string) (*model.AuthResponse, error)
+	RefreshTokens(ctx context.Context, refreshToken, clientID string) (*model.AuthResponse, error)
+	RevokeRefreshToken(ctx context.Context, userID pgtype.UUID, refreshToken string) error
+	SwitchOrganization(ctx context.Context, user *model.User, sessionID, orgID pgtype.UUID) (*model.AuthResponse, error)
+}
+
+type tokenService struct {
+	userRepo    repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	roleRepo    repository.RoleRepository
+	sessionRepo repository.SessionRepository
+	keys        *utils.KeyRing
+	config      *config.Config
+	logger      *zap.Logger
+}
+
+func NewTokenService(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, roleRepo repository.RoleRepository, sessionRepo repository.SessionRepository, keys *utils.KeyRing, config *config.Config, logger *zap.Logger) TokenService {
+	return &tokenService{
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		roleRepo:    roleRepo,
+		sessionRepo: sessionRepo,
+		keys:        keys,
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// IssueTokens starts a new session, and with it a new refresh token family, for
+// the client described by the request context. Browsers that asked for a cookie
+// session get its secret and CSRF token instead of tokens.
+func (s *tokenService) IssueTokens(ctx context.Context, user *model.User) (*model.AuthResponse, error) {
+	// Logging in during the grace period cancels a pending account deletion
+	if user.DeletedAt.Valid {
+		if err := s.userRepo.RestoreUser(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.DeletedAt = pgtype.Timestamptz{}
+		user.PurgeAt = pgtype.Timestamptz{}
+		s.logger.Info("Deleted account restored by login", zap.String("user_id", user.ID.String()))
+	}
+
+	client := utils.ClientInfoFromContext(ctx)
+	session := &model.Session{
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+	}
+
+	if utils.CookieSessionRequested(ctx) {
+		return s.startCookieSession(ctx, user, session)
+	}
+
+	if err := s.sessionRepo.SaveSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, user, session)
+}
+
+// IssueOAuthTokens starts a session for an OAuth client acting on the user's
+// behalf. Its access tokens carry the granted scopes rather than the user's
+// roles, so first-party routes refuse them.
+func (s *tokenService) IssueOAuthTokens(ctx context.Context, user *model.User, clientID string, scopes []string) (*model.AuthResponse, error) {
+	client := utils.ClientInfoFromContext(ctx)
+	session := &model.Session{
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		ClientID:  pgtype.Text{String: clientID, Valid: true},
+		Scopes:    scopes,
+	}
+	if err := s.sessionRepo.SaveSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, user, session)
+}
+
+// startCookieSession saves a session that the browser proves with a secret
+// cookie. Only hashes of the secret and CSRF token are stored.
+func (s *tokenService) startCookieSession(ctx context.Context, user *model.User, session *model.Session) (*model.AuthResponse, error) {
+	secret, err := utils.GenerateRandomToken(sessionSecretBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error generating session secret: %w", err)
+	}
+
+	csrfToken, err := utils.GenerateRandomToken(csrfTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error generating csrf token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.config.SessionCookie.TTL)
+	session.CookieHash = pgtype.Text{String: utils.HashToken(secret), Valid: true}
+	session.CSRFTokenHash = pgtype.Text{String: utils.HashToken(csrfToken), Valid: true}
+	session.ExpiresAt = pgtype.Timestamptz{Time: expiresAt, Valid: true}
+	if err := s.sessionRepo.SaveSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Cookie session started",
+		zap.String("user_id", user.ID.String()),
+		zap.String("session_id", session.ID.String()),
+	)
+
+	return &model.AuthResponse{
+		User:          user,
+		ExpiresAt:     expiresAt.Unix(),
+		SessionSecret: secret,
+		CSRFToken:     csrfToken,
+	}, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new pair. Presenting a token that
+// was already rotated revokes its whole family, since one of the holders is not
+// the legitimate client. clientID is the OAuth client presenting the token, or ""
+// for the first-party app; a family only ever refreshes for the client it was
+// issued to.
+func (s *tokenService) RefreshTokens(ctx context.Context, refreshToken, clientID string) (*model.AuthResponse, error) {
+	current, err := s.refreshRepo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if current.RevokedAt.Valid || time.Now().After(current.ExpiresAt.Time) {
+		return nil, errors.New(constants.ErrInvalidRefreshToken)
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, current.FamilyID)
+	if err != nil {
+		if err.Error() != constants.ErrSessionNotFound {
+			return nil, err
+		}
+		// Families created before sessions existed get their session row here.
+		// They all belong to the first-party app.
+		session = &model.Session{ID: current.FamilyID, UserID: current.UserID}
+	}
+
+	if session.ClientID.String != clientID {
+		s.logger.Warn("Refresh token presented by another client",
+			zap.String("user_id", current.UserID.String()),
+			zap.String("family_id", current.FamilyID.String()),
+			zap.String("client_id", clientID),
+		)
+		return nil, errors.New(constants.ErrInvalidRefreshToken)
+	}
+
+	if current.UsedAt.Valid {
+		return nil, s.handleReuse(ctx, current)
+	}
+
+	marked, err := s.refreshRepo.MarkRefreshTokenUsed(ctx, current.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		// Lost a race against another refresh with the same token
+		return nil, s.handleReuse(ctx, current)
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, current.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	client := utils.ClientInfoFromContext(ctx)
+	session.UserAgent = client.UserAgent
+	session.IPAddress = client.IPAddress
+
+	return s.issue(ctx, user, session)
+}
+
+// RevokeRefreshToken ends the refresh token family the token belongs to
+func (s *tokenService) RevokeRefreshToken(ctx context.Context, userID pgtype.UUID, refreshToken string) error {
+	token, err := s.refreshRepo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+
+	if token.UserID != userID {
+		return errors.New(constants.ErrInvalidRefreshToken)
+	}
+
+	return s.refreshRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+}
+
+func (s *tokenService) handleReuse(ctx context.Context, token *model.RefreshToken) error {
+	s.logger.Warn("Refresh token reuse detected, revoking family",
+		zap.String("user_id", token.UserID.String()),
+		zap.String("family_id", token.FamilyID.String()),
+	)
+
+	if err := s.refreshRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeSession(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return errors.New(constants.ErrRefreshTokenReused)
+}
+
+// SwitchOrganization makes orgID the active organization of the session and
+// returns an access token carrying it. The session's refresh token stays valid
+// and keeps the new organization. The caller checks membership.
+func (s *tokenService) SwitchOrganization(ctx context.Context, user *model.User, sessionID, orgID pgtype.UUID) (*model.AuthResponse, error) {
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != user.ID || session.RevokedAt.Valid {
+		return nil, errors.New(constants.ErrSessionNotFound)
+	}
+
+	if err := s.sessionRepo.SetSessionOrganization(ctx, session.ID, orgID); err != nil {
+		return nil, err
+	}
+	session.OrgID = orgID
+
+	token, claims, err := s.accessToken(ctx, user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AuthResponse{
+		User:      user,
+		Token:     token,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}, nil
+}
+
+// accessToken mints an access token for the session and records it on the session
+func (s *tokenService) accessToken(ctx context.Context, user *model.User, session *model.Session) (string, *utils.Claims, error) {
+	token, claims, err := s.signAccessToken(ctx, user, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	session.TokenID = pgtype.Text{String: claims.ID, Valid: true}
+	if err := s.sessionRepo.SaveSession(ctx, session); err != nil {
+		return "", nil, err
+	}
+
+	return token, claims, nil
+}
+
+// signAccessToken signs an OAuth token for sessions started by an OAuth client
+// and a first-party token for all others
+func (s *tokenService) signAccessToken(ctx context.Context, user *model.User, session *model.Session) (string, *utils.Claims, error) {
+	if session.ClientID.Valid {
+		token, claims, err := utils.GenerateOAuthToken(user, session, s.keys, s.config)
+		if err != nil {
+			return "", nil, fmt.Errorf("error generating token: %w", err)
+		}
+		return token, claims, nil
+	}
+
+	// Roles are read at issue time; RBACService revokes tokens when a role is taken away
+	roles, err := s.roleRepo.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, claims, err := utils.GenerateToken(user, roles, session, s.keys, s.config)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	return token, claims, nil
+}
+
+// issue mints a token pair for the session
+func (s *tokenService) issue(ctx context.Context, user *model.User, session *model.Session) (*model.AuthResponse, error) {
+	token, claims, err := s.accessToken(ctx, user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error generating refresh token: %w", err)
+	}
+
+	refreshExpiresAt := time.Now().Add(time.Duration(s.config.JWT.RefreshExpiresIn) * time.Second)
+	if _, err := s.refreshRepo.CreateRefreshToken(ctx, user.ID, session.ID, utils.HashToken(refreshToken), refreshExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return &model.AuthResponse{
+		User:                  user,
+		Token:                 token,
+		ExpiresAt:             claims.ExpiresAt.Unix(),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt.Unix(),
+		Scopes:                session.Scopes,
+	}, nil
+}
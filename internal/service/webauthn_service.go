@@ -0,0 +1,372 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"github.com/manish-npx/go-echo-pg/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	webAuthnCeremonyRegistration = "registration"
+	webAuthnCeremonyLogin        = "login"
+)
+
+// WebAuthnService runs the passkey registration and assertion ceremonies. Pending
+// ceremonies live in webauthn_sessions so any replica can finish them.
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, userID pgtype.UUID) (*model.WebAuthnBeginResponse, error)
+	FinishRegistration(ctx context.Context, userID pgtype.UUID, req *model.WebAuthnFinishRequest) (*model.WebAuthnCredential, error)
+	BeginLogin(ctx context.Context, req *model.WebAuthnBeginLoginRequest) (*model.WebAuthnBeginResponse, error)
+	FinishLogin(ctx context.Context, req *model.WebAuthnFinishRequest) (*model.AuthResponse, error)
+	ListCredentials(ctx context.Context, userID pgtype.UUID) ([]*model.WebAuthnCredential, error)
+	DeleteCredential(ctx context.Context, userID, id pgtype.UUID) error
+}
+
+// webAuthnUser adapts model.User to webauthn.User. The user handle is the raw UUID.
+type webAuthnUser struct {
+	user        *model.User
+	credentials []*model.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.user.ID.Bytes[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, t := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+
+		creds = append(creds, webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   c.UserVerified,
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       c.AAGUID,
+				SignCount:    uint32(c.SignCount),
+				CloneWarning: c.CloneWarning,
+			},
+		})
+	}
+	return creds
+}
+
+type webAuthnService struct {
+	userRepo     repository.UserRepository
+	webAuthnRepo repository.WebAuthnRepository
+	tokenService TokenService
+	webAuthn     *webauthn.WebAuthn
+	config       *config.Config
+	logger       *zap.Logger
+}
+
+func NewWebAuthnService(
+	userRepo repository.UserRepository,
+	webAuthnRepo repository.WebAuthnRepository,
+	tokenService TokenService,
+	config *config.Config,
+	logger *zap.Logger,
+) (WebAuthnService, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          config.WebAuthn.RPID,
+		RPDisplayName: config.WebAuthn.RPDisplayName,
+		RPOrigins:     strings.Split(config.WebAuthn.RPOrigins, ","),
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: config.WebAuthn.Timeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: config.WebAuthn.Timeout},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error configuring webauthn: %w", err)
+	}
+
+	return &webAuthnService{
+		userRepo:     userRepo,
+		webAuthnRepo: webAuthnRepo,
+		tokenService: tokenService,
+		webAuthn:     wa,
+		config:       config,
+		logger:       logger,
+	}, nil
+}
+
+func (s *webAuthnService) BeginRegistration(ctx context.Context, userID pgtype.UUID) (*model.WebAuthnBeginResponse, error) {
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ask for a discoverable credential so it can be used without typing an email
+	options, session, err := s.webAuthn.BeginRegistration(waUser,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(waUser.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning webauthn registration: %w", err)
+	}
+
+	return s.saveSession(ctx, userID, webAuthnCeremonyRegistration, session, options)
+}
+
+func (s *webAuthnService) FinishRegistration(ctx context.Context, userID pgtype.UUID, req *model.WebAuthnFinishRequest) (*model.WebAuthnCredential, error) {
+	session, sessionUserID, err := s.loadSession(ctx, req.SessionID, webAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if sessionUserID != userID {
+		return nil, errors.New(constants.ErrWebAuthnSessionNotFound)
+	}
+
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		return nil, s.verificationError(userID, err)
+	}
+
+	credential, err := s.webAuthn.CreateCredential(waUser, *session, parsed)
+	if err != nil {
+		return nil, s.verificationError(userID, err)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Passkey"
+	}
+
+	return s.webAuthnRepo.CreateCredential(ctx, &model.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+	})
+}
+
+// BeginLogin starts a discoverable (username-less) login unless an email is given
+func (s *webAuthnService) BeginLogin(ctx context.Context, req *model.WebAuthnBeginLoginRequest) (*model.WebAuthnBeginResponse, error) {
+	if req.Email == "" {
+		options, session, err := s.webAuthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error beginning webauthn login: %w", err)
+		}
+		return s.saveSession(ctx, pgtype.UUID{}, webAuthnCeremonyLogin, session, options)
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, utils.NormalizeEmail(req.Email))
+	if err != nil {
+		return nil, errors.New(constants.ErrWebAuthnCredentialNotFound)
+	}
+
+	waUser, err := s.loadUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(waUser.credentials) == 0 {
+		return nil, errors.New(constants.ErrWebAuthnCredentialNotFound)
+	}
+
+	options, session, err := s.webAuthn.BeginLogin(waUser,
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning webauthn login: %w", err)
+	}
+
+	return s.saveSession(ctx, user.ID, webAuthnCeremonyLogin, session, options)
+}
+
+// FinishLogin verifies the assertion. User verification is required, so a passkey
+// login counts as multi-factor and issues tokens directly.
+func (s *webAuthnService) FinishLogin(ctx context.Context, req *model.WebAuthnFinishRequest) (*model.AuthResponse, error) {
+	session, sessionUserID, err := s.loadSession(ctx, req.SessionID, webAuthnCeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		return nil, s.verificationError(sessionUserID, err)
+	}
+
+	var waUser *webAuthnUser
+	var credential *webauthn.Credential
+	if sessionUserID.Valid {
+		if waUser, err = s.loadUser(ctx, sessionUserID); err != nil {
+			return nil, err
+		}
+		credential, err = s.webAuthn.ValidateLogin(waUser, *session, parsed)
+	} else {
+		credential, err = s.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			stored, err := s.webAuthnRepo.GetCredentialByCredentialID(ctx, rawID)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(stored.UserID.Bytes[:], userHandle) {
+				return nil, errors.New(constants.ErrWebAuthnCredentialNotFound)
+			}
+			waUser, err = s.loadUser(ctx, stored.UserID)
+			return waUser, err
+		}, *session, parsed)
+	}
+	if err != nil {
+		return nil, s.verificationError(sessionUserID, err)
+	}
+
+	if err := s.recordUsage(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User logged in with passkey", zap.String("user_id", waUser.user.ID.String()))
+	return s.tokenService.IssueTokens(ctx, waUser.user)
+}
+
+func (s *webAuthnService) ListCredentials(ctx context.Context, userID pgtype.UUID) ([]*model.WebAuthnCredential, error) {
+	return s.webAuthnRepo.ListCredentials(ctx, userID)
+}
+
+func (s *webAuthnService) DeleteCredential(ctx context.Context, userID, id pgtype.UUID) error {
+	if err := s.webAuthnRepo.DeleteCredential(ctx, userID, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("WebAuthn credential deleted", zap.String("user_id", userID.String()))
+	return nil
+}
+
+func (s *webAuthnService) recordUsage(ctx context.Context, credential *webauthn.Credential) error {
+	stored, err := s.webAuthnRepo.GetCredentialByCredentialID(ctx, credential.ID)
+	if err != nil {
+		return err
+	}
+
+	if credential.Authenticator.CloneWarning {
+		s.logger.Warn("WebAuthn sign counter went backwards, authenticator may be cloned",
+			zap.String("user_id", stored.UserID.String()),
+			zap.String("credential", stored.ID.String()),
+		)
+	}
+
+	return s.webAuthnRepo.UpdateCredentialUsage(ctx, stored.ID,
+		int64(credential.Authenticator.SignCount),
+		credential.Authenticator.CloneWarning,
+		credential.Flags.BackupState,
+	)
+}
+
+func (s *webAuthnService) loadUser(ctx context.Context, userID pgtype.UUID) (*webAuthnUser, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	credentials, err := s.webAuthnRepo.ListCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+func (s *webAuthnService) saveSession(ctx context.Context, userID pgtype.UUID, ceremony string, session *webauthn.SessionData, options any) (*model.WebAuthnBeginResponse, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding webauthn session: %w", err)
+	}
+
+	expiresAt := session.Expires
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(s.config.WebAuthn.Timeout)
+	}
+
+	id, err := s.webAuthnRepo.CreateSession(ctx, userID, ceremony, data, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.WebAuthnBeginResponse{
+		SessionID: id.String(),
+		Options:   options,
+	}, nil
+}
+
+func (s *webAuthnService) loadSession(ctx context.Context, sessionID, ceremony string) (*webauthn.SessionData, pgtype.UUID, error) {
+	var id pgtype.UUID
+	if err := id.Scan(sessionID); err != nil {
+		return nil, pgtype.UUID{}, errors.New(constants.ErrWebAuthnSessionNotFound)
+	}
+
+	userID, data, err := s.webAuthnRepo.ConsumeSession(ctx, id, ceremony)
+	if err != nil {
+		return nil, pgtype.UUID{}, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, pgtype.UUID{}, fmt.Errorf("error decoding webauthn session: %w", err)
+	}
+
+	return &session, userID, nil
+}
+
+func (s *webAuthnService) verificationError(userID pgtype.UUID, err error) error {
+	var protoErr *protocol.Error
+	details := err.Error()
+	if errors.As(err, &protoErr) {
+		details = protoErr.Details + ": " + protoErr.DevInfo
+	}
+
+	s.logger.Warn("WebAuthn verification failed",
+		zap.String("user_id", userID.String()),
+		zap.String("details", details),
+	)
+	return errors.New(constants.ErrWebAuthnVerification)
+}
@@ -0,0 +1,575 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/constants"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+	"github.com/manish-npx/go-echo-pg/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	testRPID   = "auth.example.com"
+	testOrigin = "https://auth.example.com"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// softAuthenticator is a platform authenticator in memory: one P-256 credential
+// with "none" attestation, answering whatever ceremony it is handed
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, userHandle []byte) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return &softAuthenticator{key: key, credentialID: credentialID, userHandle: userHandle}
+}
+
+// ceremonyInput is what the browser and authenticator put into a response. The
+// defaults are an honest answer; test cases tamper with them.
+type ceremonyInput struct {
+	clientType     string
+	challenge      string
+	origin         string
+	rpID           string
+	flags          byte
+	userHandle     []byte
+	credentialID   []byte
+	breakSignature bool
+}
+
+func (a *softAuthenticator) input(clientType string, challenge protocol.URLEncodedBase64, flags byte) *ceremonyInput {
+	return &ceremonyInput{
+		clientType:   clientType,
+		challenge:    challenge.String(),
+		origin:       testOrigin,
+		rpID:         testRPID,
+		flags:        flags,
+		userHandle:   a.userHandle,
+		credentialID: a.credentialID,
+	}
+}
+
+func (a *softAuthenticator) authenticatorData(in *ceremonyInput) []byte {
+	rpIDHash := sha256.Sum256([]byte(in.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, in.flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func clientDataJSON(t *testing.T, in *ceremonyInput) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      in.clientType,
+		"challenge": in.challenge,
+		"origin":    in.origin,
+	})
+	if err != nil {
+		t.Fatalf("encoding client data: %v", err)
+	}
+	return data
+}
+
+// attest answers navigator.credentials.create
+func (a *softAuthenticator) attest(t *testing.T, in *ceremonyInput) json.RawMessage {
+	t.Helper()
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encoding public key: %v", err)
+	}
+
+	authData := a.authenticatorData(in)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(in.credentialID)))
+	authData = append(authData, in.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatalf("encoding attestation object: %v", err)
+	}
+
+	return credentialJSON(t, in.credentialID, map[string]string{
+		"clientDataJSON":    b64(clientDataJSON(t, in)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// assert answers navigator.credentials.get
+func (a *softAuthenticator) assert(t *testing.T, in *ceremonyInput) json.RawMessage {
+	t.Helper()
+
+	a.signCount++
+	authData := a.authenticatorData(in)
+	clientData := clientDataJSON(t, in)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("signing assertion: %v", err)
+	}
+	if in.breakSignature {
+		digest[0] ^= 0xff
+		if signature, err = ecdsa.SignASN1(rand.Reader, a.key, digest[:]); err != nil {
+			t.Fatalf("signing assertion: %v", err)
+		}
+	}
+
+	return credentialJSON(t, in.credentialID, map[string]string{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(in.userHandle),
+	})
+}
+
+func credentialJSON(t *testing.T, credentialID []byte, response map[string]string) json.RawMessage {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"id":       b64(credentialID),
+		"rawId":    b64(credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatalf("encoding credential: %v", err)
+	}
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+type fakeWebAuthnUsers struct {
+	repository.UserRepository
+	user *model.User
+}
+
+func (f *fakeWebAuthnUsers) GetUserByID(ctx context.Context, id pgtype.UUID) (*model.User, error) {
+	if id != f.user.ID {
+		return nil, errors.New(constants.ErrUserNotFound)
+	}
+	return f.user, nil
+}
+
+func (f *fakeWebAuthnUsers) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	if email != f.user.Email {
+		return nil, errors.New(constants.ErrUserNotFound)
+	}
+	return f.user, nil
+}
+
+type fakeWebAuthnCeremony struct {
+	userID   pgtype.UUID
+	ceremony string
+	data     []byte
+}
+
+// fakeWebAuthnStore keeps credentials and pending ceremonies in memory. Like
+// the real table, a ceremony is deleted the first time it is consumed.
+type fakeWebAuthnStore struct {
+	repository.WebAuthnRepository
+	credentials []*model.WebAuthnCredential
+	sessions    map[pgtype.UUID]fakeWebAuthnCeremony
+	nextID      byte
+}
+
+func newFakeWebAuthnStore() *fakeWebAuthnStore {
+	return &fakeWebAuthnStore{sessions: map[pgtype.UUID]fakeWebAuthnCeremony{}}
+}
+
+func (f *fakeWebAuthnStore) newID() pgtype.UUID {
+	f.nextID++
+	return pgtype.UUID{Bytes: [16]byte{0xaa, f.nextID}, Valid: true}
+}
+
+func (f *fakeWebAuthnStore) CreateCredential(ctx context.Context, cred *model.WebAuthnCredential) (*model.WebAuthnCredential, error) {
+	stored := *cred
+	stored.ID = f.newID()
+	f.credentials = append(f.credentials, &stored)
+	return &stored, nil
+}
+
+func (f *fakeWebAuthnStore) ListCredentials(ctx context.Context, userID pgtype.UUID) ([]*model.WebAuthnCredential, error) {
+	var creds []*model.WebAuthnCredential
+	for _, c := range f.credentials {
+		if c.UserID == userID {
+			creds = append(creds, c)
+		}
+	}
+	return creds, nil
+}
+
+func (f *fakeWebAuthnStore) GetCredentialByCredentialID(ctx context.Context, credentialID []byte) (*model.WebAuthnCredential, error) {
+	for _, c := range f.credentials {
+		if bytes.Equal(c.CredentialID, credentialID) {
+			return c, nil
+		}
+	}
+	return nil, errors.New(constants.ErrWebAuthnCredentialNotFound)
+}
+
+func (f *fakeWebAuthnStore) UpdateCredentialUsage(ctx context.Context, id pgtype.UUID, signCount int64, cloneWarning, backupState bool) error {
+	for _, c := range f.credentials {
+		if c.ID == id {
+			c.SignCount = signCount
+			c.CloneWarning = cloneWarning
+			c.BackupState = backupState
+			return nil
+		}
+	}
+	return errors.New(constants.ErrWebAuthnCredentialNotFound)
+}
+
+func (f *fakeWebAuthnStore) CreateSession(ctx context.Context, userID pgtype.UUID, ceremony string, data []byte, expiresAt time.Time) (pgtype.UUID, error) {
+	id := f.newID()
+	f.sessions[id] = fakeWebAuthnCeremony{userID: userID, ceremony: ceremony, data: data}
+	return id, nil
+}
+
+func (f *fakeWebAuthnStore) ConsumeSession(ctx context.Context, id pgtype.UUID, ceremony string) (pgtype.UUID, []byte, error) {
+	session, ok := f.sessions[id]
+	if !ok || session.ceremony != ceremony {
+		return pgtype.UUID{}, nil, errors.New(constants.ErrWebAuthnSessionNotFound)
+	}
+	delete(f.sessions, id)
+	return session.userID, session.data, nil
+}
+
+type fakeTokenIssuer struct {
+	TokenService
+}
+
+func (f *fakeTokenIssuer) IssueTokens(ctx context.Context, user *model.User) (*model.AuthResponse, error) {
+	return &model.AuthResponse{User: user, Token: "access-token"}, nil
+}
+
+func newTestWebAuthnService(t *testing.T) (WebAuthnService, *fakeWebAuthnStore, *model.User) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.WebAuthn.RPID = testRPID
+	cfg.WebAuthn.RPDisplayName = "Example"
+	cfg.WebAuthn.RPOrigins = testOrigin
+	cfg.WebAuthn.Timeout = time.Minute
+
+	user := &model.User{
+		ID:    pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Email: "user@example.com",
+		Name:  "Test User",
+	}
+	store := newFakeWebAuthnStore()
+
+	svc, err := NewWebAuthnService(&fakeWebAuthnUsers{user: user}, store, &fakeTokenIssuer{}, cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewWebAuthnService: %v", err)
+	}
+	return svc, store, user
+}
+
+// register runs an honest registration ceremony so login tests have a passkey
+func register(t *testing.T, svc WebAuthnService, user *model.User, authenticator *softAuthenticator) {
+	t.Helper()
+
+	begin, err := svc.BeginRegistration(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	challenge := begin.Options.(*protocol.CredentialCreation).Response.Challenge
+
+	in := authenticator.input("webauthn.create", challenge, flagUserPresent|flagUserVerified|flagAttestedData)
+	if _, err := svc.FinishRegistration(context.Background(), user.ID, &model.WebAuthnFinishRequest{
+		SessionID:  begin.SessionID,
+		Credential: authenticator.attest(t, in),
+	}); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(in *ceremonyInput)
+		wantErr string
+	}{
+		{name: "valid attestation"},
+		{
+			name:    "wrong origin",
+			tamper:  func(in *ceremonyInput) { in.origin = "https://evil.example.com" },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "wrong challenge",
+			tamper:  func(in *ceremonyInput) { in.challenge = b64([]byte("not the challenge")) },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "wrong relying party",
+			tamper:  func(in *ceremonyInput) { in.rpID = "evil.example.com" },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "assertion instead of attestation",
+			tamper:  func(in *ceremonyInput) { in.clientType = "webauthn.get" },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "user not present",
+			tamper:  func(in *ceremonyInput) { in.flags &^= flagUserPresent },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, user := newTestWebAuthnService(t)
+			authenticator := newSoftAuthenticator(t, user.ID.Bytes[:])
+
+			begin, err := svc.BeginRegistration(context.Background(), user.ID)
+			if err != nil {
+				t.Fatalf("BeginRegistration: %v", err)
+			}
+			challenge := begin.Options.(*protocol.CredentialCreation).Response.Challenge
+
+			in := authenticator.input("webauthn.create", challenge, flagUserPresent|flagUserVerified|flagAttestedData)
+			if tt.tamper != nil {
+				tt.tamper(in)
+			}
+
+			cred, err := svc.FinishRegistration(context.Background(), user.ID, &model.WebAuthnFinishRequest{
+				SessionID:  begin.SessionID,
+				Credential: authenticator.attest(t, in),
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("FinishRegistration error = %v, want %q", err, tt.wantErr)
+				}
+				if len(store.credentials) != 0 {
+					t.Errorf("stored %d credentials after a failed registration", len(store.credentials))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FinishRegistration: %v", err)
+			}
+			if !bytes.Equal(cred.CredentialID, authenticator.credentialID) || cred.UserID != user.ID {
+				t.Errorf("stored credential %x for user %v", cred.CredentialID, cred.UserID)
+			}
+			if cred.Name != "Passkey" || cred.AttestationType != "none" || !cred.UserVerified {
+				t.Errorf("stored credential name %q, attestation %q, user verified %v", cred.Name, cred.AttestationType, cred.UserVerified)
+			}
+		})
+	}
+}
+
+func TestWebAuthnRegistrationSessionIsSingleUse(t *testing.T) {
+	svc, _, user := newTestWebAuthnService(t)
+	authenticator := newSoftAuthenticator(t, user.ID.Bytes[:])
+
+	begin, err := svc.BeginRegistration(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	challenge := begin.Options.(*protocol.CredentialCreation).Response.Challenge
+	req := &model.WebAuthnFinishRequest{
+		SessionID:  begin.SessionID,
+		Credential: authenticator.attest(t, authenticator.input("webauthn.create", challenge, flagUserPresent|flagUserVerified|flagAttestedData)),
+	}
+
+	if _, err := svc.FinishRegistration(context.Background(), user.ID, req); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if _, err := svc.FinishRegistration(context.Background(), user.ID, req); err == nil || err.Error() != constants.ErrWebAuthnSessionNotFound {
+		t.Errorf("replayed FinishRegistration error = %v, want %q", err, constants.ErrWebAuthnSessionNotFound)
+	}
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		tamper  func(in *ceremonyInput)
+		wantErr string
+	}{
+		{name: "discoverable login"},
+		{name: "login by email", email: "User@Example.com"},
+		{
+			name:    "bad signature",
+			tamper:  func(in *ceremonyInput) { in.breakSignature = true },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "wrong origin",
+			tamper:  func(in *ceremonyInput) { in.origin = "https://evil.example.com" },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "wrong challenge",
+			tamper:  func(in *ceremonyInput) { in.challenge = b64([]byte("not the challenge")) },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "wrong relying party",
+			tamper:  func(in *ceremonyInput) { in.rpID = "evil.example.com" },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "user not verified",
+			tamper:  func(in *ceremonyInput) { in.flags = flagUserPresent },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "user handle of another user",
+			tamper:  func(in *ceremonyInput) { in.userHandle = make([]byte, 16) },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "unknown credential",
+			tamper:  func(in *ceremonyInput) { in.credentialID = []byte("unknown credential") },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+		{
+			name:    "unknown credential by email",
+			email:   "user@example.com",
+			tamper:  func(in *ceremonyInput) { in.credentialID = []byte("unknown credential") },
+			wantErr: constants.ErrWebAuthnVerification,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, user := newTestWebAuthnService(t)
+			authenticator := newSoftAuthenticator(t, user.ID.Bytes[:])
+			register(t, svc, user, authenticator)
+
+			begin, err := svc.BeginLogin(context.Background(), &model.WebAuthnBeginLoginRequest{Email: tt.email})
+			if err != nil {
+				t.Fatalf("BeginLogin: %v", err)
+			}
+			challenge := begin.Options.(*protocol.CredentialAssertion).Response.Challenge
+
+			in := authenticator.input("webauthn.get", challenge, flagUserPresent|flagUserVerified)
+			if tt.tamper != nil {
+				tt.tamper(in)
+			}
+
+			resp, err := svc.FinishLogin(context.Background(), &model.WebAuthnFinishRequest{
+				SessionID:  begin.SessionID,
+				Credential: authenticator.assert(t, in),
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("FinishLogin error = %v, want %q", err, tt.wantErr)
+				}
+				if store.credentials[0].SignCount != 0 {
+					t.Errorf("sign count = %d after a failed login, want 0", store.credentials[0].SignCount)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+			if resp.User.ID != user.ID || resp.Token == "" {
+				t.Errorf("logged in as %v with token %q", resp.User.ID, resp.Token)
+			}
+			if got := store.credentials[0].SignCount; got != int64(authenticator.signCount) {
+				t.Errorf("sign count = %d, want %d", got, authenticator.signCount)
+			}
+		})
+	}
+}
+
+func TestWebAuthnLoginSessionIsSingleUse(t *testing.T) {
+	svc, _, user := newTestWebAuthnService(t)
+	authenticator := newSoftAuthenticator(t, user.ID.Bytes[:])
+	register(t, svc, user, authenticator)
+
+	begin, err := svc.BeginLogin(context.Background(), &model.WebAuthnBeginLoginRequest{})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	challenge := begin.Options.(*protocol.CredentialAssertion).Response.Challenge
+	req := &model.WebAuthnFinishRequest{
+		SessionID:  begin.SessionID,
+		Credential: authenticator.assert(t, authenticator.input("webauthn.get", challenge, flagUserPresent|flagUserVerified)),
+	}
+
+	if _, err := svc.FinishLogin(context.Background(), req); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if _, err := svc.FinishLogin(context.Background(), req); err == nil || err.Error() != constants.ErrWebAuthnSessionNotFound {
+		t.Errorf("replayed FinishLogin error = %v, want %q", err, constants.ErrWebAuthnSessionNotFound)
+	}
+}
+
+func TestWebAuthnLoginRejectsRegistrationSession(t *testing.T) {
+	svc, _, user := newTestWebAuthnService(t)
+	authenticator := newSoftAuthenticator(t, user.ID.Bytes[:])
+
+	begin, err := svc.BeginRegistration(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	challenge := begin.Options.(*protocol.CredentialCreation).Response.Challenge
+
+	_, err = svc.FinishLogin(context.Background(), &model.WebAuthnFinishRequest{
+		SessionID:  begin.SessionID,
+		Credential: authenticator.assert(t, authenticator.input("webauthn.get", challenge, flagUserPresent|flagUserVerified)),
+	})
+	if err == nil || err.Error() != constants.ErrWebAuthnSessionNotFound {
+		t.Errorf("FinishLogin error = %v, want %q", err, constants.ErrWebAuthnSessionNotFound)
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// BreachedPasswords looks passwords up in a local list of SHA-1 hashes, such
+// as the Have I Been Pwned download. The file holds one uppercase hex hash per
+// line, optionally followed by ":<count>", sorted ascending. It is binary
+// searched in place, so it never has to fit in memory.
+type BreachedPasswords struct {
+	file *os.File
+	size int64
+}
+
+func OpenBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening breached password list: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading breached password list: %w", err)
+	}
+
+	return &BreachedPasswords{file: file, size: info.Size()}, nil
+}
+
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// lo is always the start of a line; every line starting in [lo, hi) is a candidate
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, start, err := b.lineFrom(mid)
+		if err != nil {
+			return false, err
+		}
+		if start >= hi || line == "" {
+			hi = mid
+			continue
+		}
+
+		hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch strings.Compare(strings.ToUpper(hash), target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = start + int64(len(line))
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// lineFrom returns the first line starting at or after offset, including its
+// newline, and where it starts
+func (b *BreachedPasswords) lineFrom(offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(b.file, start, b.size-start))
+
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return "", b.size, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	return line, start, nil
+}
+
+func (b *BreachedPasswords) Close() error {
+	return b.file.Close()
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Hashes of "password", "letmein" and "hunter2", plus one sharing only the
+// first five characters with "correct horse battery staple" (ABF7AAD6...)
+var breachedList = []string{
+	"0000000A0E3B9F25FF41DE4B5AC238C2D545C7A8:2",
+	"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493",
+	"ABF7A000000000000000000000000000000000FF:1",
+	"B7A875FC1EA228B9061041B7CEC4BD3C52AB3CE3",
+	"f3bbbd66a63d4bf1747940578ec3d0103530e21d:17",
+}
+
+func openBreachedList(t *testing.T, content string) *BreachedPasswords {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing list: %v", err)
+	}
+
+	list, err := OpenBreachedPasswords(path)
+	if err != nil {
+		t.Fatalf("OpenBreachedPasswords: %v", err)
+	}
+	t.Cleanup(func() { list.Close() })
+	return list
+}
+
+func TestBreachedPasswordsContains(t *testing.T) {
+	lf := strings.Join(breachedList, "\n") + "\n"
+	crlf := strings.Join(breachedList, "\r\n") + "\r\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		password string
+		want     bool
+	}{
+		{name: "listed with count", content: lf, password: "password", want: true},
+		{name: "listed without count", content: lf, password: "letmein", want: true},
+		{name: "lowercase last line", content: lf, password: "hunter2", want: true},
+		{name: "shares only the prefix", content: lf, password: "correct horse battery staple", want: false},
+		{name: "not listed", content: lf, password: "not in the list", want: false},
+		{name: "crlf line endings", content: crlf, password: "letmein", want: true},
+		{name: "no trailing newline", content: strings.TrimSuffix(lf, "\n"), password: "hunter2", want: true},
+		{name: "single line", content: breachedList[1], password: "password", want: true},
+		{name: "empty list", content: "", password: "password", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := openBreachedList(t, tt.content)
+
+			got, err := list.Contains(tt.password)
+			if err != nil {
+				t.Fatalf("Contains: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachedPasswordsContainsReadError(t *testing.T) {
+	list := openBreachedList(t, strings.Join(breachedList, "\n"))
+	list.Close()
+
+	if _, err := list.Contains("password"); err == nil {
+		t.Fatal("Contains on a closed list returned no error")
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"context"
+	"strings"
+)
+
+// maxUserAgentLength keeps oversized headers out of the sessions table
+const maxUserAgentLength = 512
+
+// ClientInfo describes the client a request came from. It is put in the request
+// context by middleware.ClientInfo so services can record it.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type clientInfoKey struct{}
+
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	if len(info.UserAgent) > maxUserAgentLength {
+		info.UserAgent = info.UserAgent[:maxUserAgentLength]
+	}
+	info.UserAgent = strings.ToValidUTF8(info.UserAgent, "")
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the zero ClientInfo outside of a request
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
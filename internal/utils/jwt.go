@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,46 +11,197 @@ import (
 	"github.com/manish-npx/go-echo-pg/internal/model"
 )
 
+// TokenUseAccess marks access tokens. Every token we sign carries a token_use
+// claim so a token minted for one purpose is never accepted for another.
+const TokenUseAccess = "access"
+
+// TokenUseOAuthAccess marks access tokens issued to OAuth clients on a user's
+// behalf. ValidateToken refuses them, so they are only good for the endpoints
+// that check them with ValidateOAuthToken.
+const TokenUseOAuthAccess = "oauth_access"
+
 type Claims struct {
-	UserID pgtype.UUID `json:"user_id"`
+	UserID       pgtype.UUID `json:"user_id"`
+	Email        string      `json:"email"`
+	TokenVersion int         `json:"tv"`
+	TokenUse     string      `json:"token_use"`
+	Roles        []string    `json:"roles,omitempty"`
+	SessionID    pgtype.UUID `json:"sid"`
+	OrgID        pgtype.UUID `json:"org_id"`
+	Actor        *Actor      `json:"act,omitempty"`
+	// Set on service and OAuth tokens; service tokens carry no user (RFC 9068)
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor identifies the admin behind an impersonation token (RFC 8693 act claim)
+type Actor struct {
+	UserID pgtype.UUID `json:"sub"`
 	Email  string      `json:"email"`
+}
+
+// IsService reports whether the token was issued to a machine client rather than a user
+func (c *Claims) IsService() bool {
+	return !c.UserID.Valid && c.ClientID != ""
+}
+
+// Impersonated reports whether the token was issued to an admin acting as the user
+func (c *Claims) Impersonated() bool {
+	return c.Actor != nil
+}
+
+// ActionClaims are carried by single-use tokens emailed to users (verification links and the like)
+type ActionClaims struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Email    string      `json:"email"`
+	TokenUse string      `json:"token_use"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(user *model.User, config *config.Config) (string, int64, error) {
-	expirationTime := time.Now().Add(time.Duration(config.JWT.ExpiresIn) * time.Second)
-	expiresAt := expirationTime.Unix()
+const tokenIDBytes = 16
+
+// GenerateToken signs an access token for the session and returns it with its
+// claims. The token carries the session's active organization.
+func GenerateToken(user *model.User, roles []string, session *model.Session, keys *KeyRing, config *config.Config) (string, *Claims, error) {
+	claims, err := newAccessClaims(user, time.Duration(config.JWT.ExpiresIn)*time.Second)
+	if err != nil {
+		return "", nil, err
+	}
+	claims.Roles = roles
+	claims.SessionID = session.ID
+	claims.OrgID = session.OrgID
+
+	tokenString, err := keys.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, claims, nil
+}
+
+// GenerateOAuthToken signs an access token for the OAuth client the session was
+// started for. It carries the scopes the user granted instead of their roles,
+// and is addressed to this server as the OIDC issuer.
+func GenerateOAuthToken(user *model.User, session *model.Session, keys *KeyRing, config *config.Config) (string, *Claims, error) {
+	claims, err := newAccessClaims(user, time.Duration(config.JWT.ExpiresIn)*time.Second)
+	if err != nil {
+		return "", nil, err
+	}
+	claims.TokenUse = TokenUseOAuthAccess
+	// The email is released through userinfo, and only under the email scope
+	claims.Email = ""
+	claims.SessionID = session.ID
+	claims.ClientID = session.ClientID.String
+	claims.Scope = strings.Join(session.Scopes, " ")
+	claims.Audience = jwt.ClaimStrings{config.OIDC.Issuer}
+
+	tokenString, err := keys.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, claims, nil
+}
+
+// GenerateImpersonationToken signs an access token that lets actor act as user.
+// It carries none of the user's roles and belongs to no session.
+func GenerateImpersonationToken(user, actor *model.User, ttl time.Duration, keys *KeyRing) (string, *Claims, error) {
+	claims, err := newAccessClaims(user, ttl)
+	if err != nil {
+		return "", nil, err
+	}
+	claims.Actor = &Actor{UserID: actor.ID, Email: actor.Email}
+
+	tokenString, err := keys.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, claims, nil
+}
+
+// GenerateServiceToken signs an access token for a machine client acting on its
+// own behalf. The subject is the client ID and there is no user.
+func GenerateServiceToken(client *model.OAuthClient, scopes []string, keys *KeyRing, config *config.Config) (string, *Claims, error) {
+	now := time.Now()
+
+	tokenID, err := GenerateRandomToken(tokenIDBytes)
+	if err != nil {
+		return "", nil, err
+	}
 
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		TokenUse: TokenUseAccess,
+		ClientID: client.ClientID,
+		Scope:    strings.Join(scopes, " "),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(config.JWT.ExpiresIn) * time.Second)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "go-echo-pg-app",
-			Subject:   user.ID.String(),
+			Subject:   client.ClientID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.JWT.Secret))
+	tokenString, err := keys.Sign(claims)
 	if err != nil {
-		return "", 0, err
+		return "", nil, err
 	}
 
-	return tokenString, expiresAt, nil
+	return tokenString, claims, nil
 }
 
-func ValidateToken(tokenString string, config *config.Config) (*Claims, error) {
+func newAccessClaims(user *model.User, ttl time.Duration) (*Claims, error) {
+	now := time.Now()
+
+	tokenID, err := GenerateRandomToken(tokenIDBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		TokenVersion: user.TokenVersion,
+		TokenUse:     TokenUseAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "go-echo-pg-app",
+			Subject:   user.ID.String(),
+		},
+	}, nil
+}
+
+// ValidateToken accepts the first-party access tokens: user, impersonation and
+// service tokens
+func ValidateToken(tokenString string, keys *KeyRing) (*Claims, error) {
+	return validateAccessToken(tokenString, TokenUseAccess, keys)
+}
+
+// ValidateOAuthToken accepts only tokens from GenerateOAuthToken addressed to audience
+func ValidateOAuthToken(tokenString, audience string, keys *KeyRing) (*Claims, error) {
+	claims, err := validateAccessToken(tokenString, TokenUseOAuthAccess, keys, jwt.WithAudience(audience))
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.UserID.Valid || claims.ClientID == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+func validateAccessToken(tokenString, tokenUse string, keys *KeyRing, opts ...jwt.ParserOption) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(config.JWT.Secret), nil
-	})
+	opts = append(opts, jwt.WithValidMethods(keys.ValidMethods()))
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc, opts...)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -58,7 +210,7 @@ func ValidateToken(tokenString string, config *config.Config) (*Claims, error) {
 		return nil, err
 	}
 
-	if !token.Valid {
+	if !token.Valid || claims.TokenUse != tokenUse {
 		return nil, errors.New("invalid token")
 	}
 
@@ -69,3 +221,53 @@ func ValidateToken(tokenString string, config *config.Config) (*Claims, error) {
 
 	return claims, nil
 }
+
+// GenerateActionToken signs a short-lived token for the given purpose. The returned
+// jti is what callers persist to make the token single-use.
+func GenerateActionToken(user *model.User, purpose string, ttl time.Duration, keys *KeyRing) (string, string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	tokenID, err := GenerateRandomToken(tokenIDBytes)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	claims := &ActionClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		TokenUse: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "go-echo-pg-app",
+			Subject:   user.ID.String(),
+		},
+	}
+
+	tokenString, err := keys.Sign(claims)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return tokenString, tokenID, expiresAt, nil
+}
+
+func ValidateActionToken(tokenString, purpose string, keys *KeyRing) (*ActionClaims, error) {
+	claims := &ActionClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods()))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("token has expired")
+		}
+		return nil, err
+	}
+
+	if !token.Valid || claims.TokenUse != purpose || claims.ID == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
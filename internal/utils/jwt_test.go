@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+)
+
+const testIssuer = "https://auth.example.com"
+
+func testKeys(t *testing.T) (*KeyRing, *config.Config) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret-test-secret-test-secret"
+	cfg.JWT.ExpiresIn = 60
+	cfg.OIDC.Issuer = testIssuer
+
+	keys, err := NewKeyRing(&cfg.JWT)
+	if err != nil {
+		t.Fatalf("NewKeyRing: %v", err)
+	}
+	return keys, cfg
+}
+
+func testUser() *model.User {
+	return &model.User{
+		ID:    pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Email: "user@example.com",
+	}
+}
+
+func TestOAuthTokenIsSeparateFromAccessToken(t *testing.T) {
+	keys, cfg := testKeys(t)
+	user := testUser()
+	session := &model.Session{
+		ID:       pgtype.UUID{Bytes: [16]byte{2}, Valid: true},
+		ClientID: pgtype.Text{String: "client-1", Valid: true},
+		Scopes:   []string{"openid", "email"},
+	}
+
+	oauthToken, _, err := GenerateOAuthToken(user, session, keys, cfg)
+	if err != nil {
+		t.Fatalf("GenerateOAuthToken: %v", err)
+	}
+	accessToken, _, err := GenerateToken(user, []string{"admin"}, &model.Session{}, keys, cfg)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	if _, err := ValidateToken(oauthToken, keys); err == nil {
+		t.Error("ValidateToken accepted an OAuth token")
+	}
+	if _, err := ValidateOAuthToken(accessToken, testIssuer, keys); err == nil {
+		t.Error("ValidateOAuthToken accepted a first-party token")
+	}
+	if _, err := ValidateOAuthToken(oauthToken, "https://other.example.com", keys); err == nil {
+		t.Error("ValidateOAuthToken accepted a token for another audience")
+	}
+
+	claims, err := ValidateOAuthToken(oauthToken, testIssuer, keys)
+	if err != nil {
+		t.Fatalf("ValidateOAuthToken: %v", err)
+	}
+	if claims.ClientID != "client-1" || claims.Scope != "openid email" || len(claims.Roles) != 0 {
+		t.Errorf("claims = client %q, scope %q, roles %v", claims.ClientID, claims.Scope, claims.Roles)
+	}
+	if claims.SessionID != session.ID || claims.UserID != user.ID {
+		t.Errorf("claims name session %v and user %v", claims.SessionID, claims.UserID)
+	}
+}
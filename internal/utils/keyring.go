@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+)
+
+// DefaultKeyID identifies the HS256 key derived from JWT.Secret when no key ring is configured
+const DefaultKeyID = "default"
+
+// SigningKey is one key of the ring. signKey is nil for verify-only keys.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// KeyRing holds every key tokens may be verified with and the single active key
+// new tokens are signed with
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+func NewKeyRing(cfg *config.JWTConfig) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string]*SigningKey)}
+
+	keyConfigs := cfg.Keys
+	activeID := cfg.ActiveKeyID
+	if len(keyConfigs) == 0 {
+		keyConfigs = []config.JWTKeyConfig{{ID: DefaultKeyID, Algorithm: "HS256", Secret: cfg.Secret}}
+		activeID = DefaultKeyID
+	}
+
+	for i := range keyConfigs {
+		key, err := loadSigningKey(&keyConfigs[i])
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+		ring.order = append(ring.order, key.ID)
+	}
+
+	if activeID == "" && len(ring.order) == 1 {
+		activeID = ring.order[0]
+	}
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q not found in key ring", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private key", activeID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+// Sign signs the claims with the active key and stamps its kid in the header
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.signKey)
+}
+
+// Keyfunc resolves the verification key from the token's kid. Tokens without a kid
+// predate the key ring and are checked against the active key. The algorithm in
+// the header must match the key's algorithm.
+func (k *KeyRing) Keyfunc(token *jwt.Token) (any, error) {
+	key := k.active
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, ok = k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.verifyKey, nil
+}
+
+// ValidMethods lists the algorithms present in the ring
+func (k *KeyRing) ValidMethods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, id := range k.order {
+		alg := k.keys[id].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// ActiveAlgorithm returns the algorithm new tokens are signed with
+func (k *KeyRing) ActiveAlgorithm() string {
+	return k.active.Method.Alg()
+}
+
+// JWKS returns the public half of every asymmetric key. Shared HMAC secrets are never published.
+func (k *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, id := range k.order {
+		key := k.keys[id]
+		if key.Method == jwt.SigningMethodHS256 {
+			continue
+		}
+		jwk, err := NewJWK(key.ID, key.Method.Alg(), key.verifyKey)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func loadSigningKey(cfg *config.JWTKeyConfig) (*SigningKey, error) {
+	if cfg.ID == "" {
+		return nil, errors.New("jwt key is missing a kid")
+	}
+
+	key := &SigningKey{ID: cfg.ID}
+
+	switch cfg.Algorithm {
+	case "HS256":
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("jwt key %q: secret is required for HS256", cfg.ID)
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = []byte(cfg.Secret)
+		return key, nil
+	case "RS256":
+		key.Method = jwt.SigningMethodRS256
+	case "ES256":
+		key.Method = jwt.SigningMethodES256
+	case "EdDSA":
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported algorithm %q", cfg.ID, cfg.Algorithm)
+	}
+
+	privatePEM, err := readPEM(cfg.PrivateKey, cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", cfg.ID, err)
+	}
+	if privatePEM != nil {
+		signer, err := parsePrivateKey(privatePEM)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", cfg.ID, err)
+		}
+		key.signKey = signer
+		key.verifyKey = signer.Public()
+	} else {
+		publicPEM, err := readPEM(cfg.PublicKey, cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", cfg.ID, err)
+		}
+		if publicPEM == nil {
+			return nil, fmt.Errorf("jwt key %q: a private or public key is required", cfg.ID)
+		}
+		pub, err := parsePublicKey(publicPEM)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", cfg.ID, err)
+		}
+		key.verifyKey = pub
+	}
+
+	if err := checkKeyType(key.Method, key.verifyKey); err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", cfg.ID, err)
+	}
+
+	return key, nil
+}
+
+func readPEM(inline, path string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %w", err)
+	}
+	return data, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM private key")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unable to parse private key")
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM public key")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unable to parse public key")
+}
+
+func checkKeyType(method jwt.SigningMethod, pub any) error {
+	var ok bool
+	switch method {
+	case jwt.SigningMethodRS256:
+		_, ok = pub.(*rsa.PublicKey)
+	case jwt.SigningMethodES256:
+		var ecKey *ecdsa.PublicKey
+		ecKey, ok = pub.(*ecdsa.PublicKey)
+		ok = ok && ecKey.Curve == elliptic.P256()
+	case jwt.SigningMethodEdDSA:
+		_, ok = pub.(ed25519.PublicKey)
+	}
+	if !ok {
+		return fmt.Errorf("key type does not match algorithm %s", method.Alg())
+	}
+	return nil
+}
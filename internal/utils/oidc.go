@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"github.com/manish-npx/go-echo-pg/internal/model"
+)
+
+// PKCEMethodS256 is the only code challenge method we accept; "plain" offers no protection
+const PKCEMethodS256 = "S256"
+
+// IDTokenClaims is an OpenID Connect ID token. Email and profile claims are only
+// present when the matching scope was granted.
+type IDTokenClaims struct {
+	Nonce           string `json:"nonce,omitempty"`
+	AuthTime        int64  `json:"auth_time"`
+	AccessTokenHash string `json:"at_hash,omitempty"`
+	Email           string `json:"email,omitempty"`
+	EmailVerified   *bool  `json:"email_verified,omitempty"`
+	Name            string `json:"name,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func GenerateIDToken(user *model.User, clientID, nonce string, authTime time.Time, accessToken string, scopes []string, keys *KeyRing, config *config.Config) (string, error) {
+	now := time.Now()
+
+	claims := &IDTokenClaims{
+		Nonce:           nonce,
+		AuthTime:        authTime.Unix(),
+		AccessTokenHash: accessTokenHash(accessToken, keys.ActiveAlgorithm()),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    config.OIDC.Issuer,
+			Subject:   user.ID.String(),
+			Audience:  jwt.ClaimStrings{clientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(config.OIDC.IDTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	if slices.Contains(scopes, "email") {
+		verified := user.EmailVerifiedAt.Valid
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	if slices.Contains(scopes, "profile") {
+		claims.Name = user.Name
+	}
+
+	return keys.Sign(claims)
+}
+
+// accessTokenHash computes at_hash: the left half of the access token's digest,
+// using the hash that matches the signing algorithm
+func accessTokenHash(accessToken, alg string) string {
+	if accessToken == "" {
+		return ""
+	}
+
+	var sum []byte
+	if alg == "EdDSA" {
+		digest := sha512.Sum512([]byte(accessToken))
+		sum = digest[:]
+	} else {
+		digest := sha256.Sum256([]byte(accessToken))
+		sum = digest[:]
+	}
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// VerifyCodeChallenge checks a PKCE code_verifier against the stored S256 challenge
+func VerifyCodeChallenge(verifier, challenge, method string) bool {
+	if method != PKCEMethodS256 || verifier == "" {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
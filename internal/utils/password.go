@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported values of password_hashing.algorithm
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+var errUnknownPasswordHash = errors.New("unknown password hash format")
+
+// passwordScheme is one encoded hash format. Every stored hash names its scheme
+// and parameters, so hashes of different formats can live side by side.
+type passwordScheme interface {
+	// Matches reports whether encoded was produced by this scheme
+	Matches(encoded string) bool
+	Verify(encoded, password string) (bool, error)
+}
+
+// currentPasswordScheme is a scheme new hashes can be created with
+type currentPasswordScheme interface {
+	passwordScheme
+	Hash(password string) (string, error)
+	// Outdated reports whether encoded uses weaker parameters than configured
+	Outdated(encoded string) bool
+}
+
+// PasswordHasher hashes new passwords with the configured algorithm and
+// verifies every format it knows, including the legacy ones users are imported with
+type PasswordHasher struct {
+	current currentPasswordScheme
+	schemes []passwordScheme
+}
+
+func NewPasswordHasher(cfg *config.PasswordHashingConfig) (*PasswordHasher, error) {
+	argon := &argon2idScheme{
+		memory:      cfg.Argon2Memory,
+		iterations:  cfg.Argon2Iterations,
+		parallelism: cfg.Argon2Parallelism,
+		saltLength:  cfg.Argon2SaltLength,
+		keyLength:   cfg.Argon2KeyLength,
+	}
+	bcryptHasher := &bcryptScheme{cost: cfg.BcryptCost}
+
+	hasher := &PasswordHasher{schemes: []passwordScheme{
+		argon,
+		bcryptHasher,
+		&pbkdf2SHA256Scheme{},
+		&scryptScheme{},
+	}}
+
+	switch cfg.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		if argon.memory == 0 || argon.iterations == 0 || argon.parallelism == 0 || argon.saltLength < 8 || argon.keyLength < 16 {
+			return nil, errors.New("invalid argon2id parameters")
+		}
+		hasher.current = argon
+	case PasswordAlgorithmBcrypt:
+		if bcryptHasher.cost < bcrypt.MinCost || bcryptHasher.cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		hasher.current = bcryptHasher
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %q", cfg.Algorithm)
+	}
+
+	return hasher, nil
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Supports reports whether encoded is in a format Verify understands
+func (h *PasswordHasher) Supports(encoded string) bool {
+	for _, scheme := range h.schemes {
+		if scheme.Matches(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks password against a hash in any supported format
+func (h *PasswordHasher) Verify(encoded, password string) (bool, error) {
+	for _, scheme := range h.schemes {
+		if scheme.Matches(encoded) {
+			return scheme.Verify(encoded, password)
+		}
+	}
+	return false, errUnknownPasswordHash
+}
+
+// NeedsRehash reports whether encoded should be replaced by a fresh hash the
+// next time the plaintext is known
+func (h *PasswordHasher) NeedsRehash(encoded string) bool {
+	if !h.current.Matches(encoded) {
+		return true
+	}
+	return h.current.Outdated(encoded)
+}
+
+// argon2idScheme stores hashes in the PHC string format:
+// $argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type argon2idScheme struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (s *argon2idScheme) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (s *argon2idScheme) Hash(password string) (string, error) {
+	salt := make([]byte, s.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, s.iterations, s.memory, s.parallelism, s.keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		s.memory,
+		s.iterations,
+		s.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (s *argon2idScheme) Verify(encoded, password string) (bool, error) {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (s *argon2idScheme) Outdated(encoded string) bool {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.memory < s.memory ||
+		params.iterations < s.iterations ||
+		params.parallelism < s.parallelism ||
+		uint32(len(params.salt)) < s.saltLength ||
+		uint32(len(params.key)) < s.keyLength
+}
+
+func parseArgon2id(encoded string) (*argon2idParams, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errors.New("unsupported argon2id version")
+	}
+
+	var params argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+
+	return &params, nil
+}
+
+// bcryptScheme uses bcrypt's own modular crypt format ($2a$, $2b$, $2y$), which
+// already carries the cost
+type bcryptScheme struct {
+	cost int
+}
+
+func (s *bcryptScheme) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (s *bcryptScheme) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (s *bcryptScheme) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *bcryptScheme) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < s.cost
+}
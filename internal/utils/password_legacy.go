@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Hash formats of systems users are imported from. They can only be verified;
+// NeedsRehash always reports them, so they are replaced on first login.
+
+// pbkdf2SHA256Scheme accepts the two common PBKDF2-SHA256 encodings:
+//
+//	pbkdf2_sha256$<iterations>$<salt>$<base64 key>      (Django, raw salt)
+//	$pbkdf2-sha256$<iterations>$<salt>$<key>            (passlib, adapted base64)
+type pbkdf2SHA256Scheme struct{}
+
+func (s *pbkdf2SHA256Scheme) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "pbkdf2_sha256$") || strings.HasPrefix(encoded, "$pbkdf2-sha256$")
+}
+
+func (s *pbkdf2SHA256Scheme) Verify(encoded, password string) (bool, error) {
+	var (
+		iterations int
+		salt, key  []byte
+		err        error
+	)
+
+	if rest, ok := strings.CutPrefix(encoded, "pbkdf2_sha256$"); ok {
+		parts := strings.Split(rest, "$")
+		if len(parts) != 3 {
+			return false, errors.New("invalid pbkdf2_sha256 hash")
+		}
+		if iterations, err = strconv.Atoi(parts[0]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2_sha256 iterations: %w", err)
+		}
+		salt = []byte(parts[1])
+		if key, err = base64.StdEncoding.DecodeString(parts[2]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2_sha256 key: %w", err)
+		}
+	} else {
+		parts := strings.Split(strings.TrimPrefix(encoded, "$pbkdf2-sha256$"), "$")
+		if len(parts) != 3 {
+			return false, errors.New("invalid pbkdf2-sha256 hash")
+		}
+		if iterations, err = strconv.Atoi(parts[0]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2-sha256 iterations: %w", err)
+		}
+		if salt, err = decodeAdaptedBase64(parts[1]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2-sha256 salt: %w", err)
+		}
+		if key, err = decodeAdaptedBase64(parts[2]); err != nil {
+			return false, fmt.Errorf("invalid pbkdf2-sha256 key: %w", err)
+		}
+	}
+
+	if iterations < 1 || len(key) == 0 {
+		return false, errors.New("invalid pbkdf2-sha256 parameters")
+	}
+
+	derived, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(derived, key) == 1, nil
+}
+
+// scryptScheme accepts the PHC/passlib scrypt encoding, where N = 2^ln:
+//
+//	$scrypt$ln=<log2 N>,r=<block size>,p=<parallelism>$<salt>$<key>
+type scryptScheme struct{}
+
+func (s *scryptScheme) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$scrypt$")
+}
+
+func (s *scryptScheme) Verify(encoded, password string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return false, errors.New("invalid scrypt hash")
+	}
+
+	var logN, r, p int
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &logN, &r, &p); err != nil {
+		return false, fmt.Errorf("invalid scrypt parameters: %w", err)
+	}
+	if logN < 1 || logN > 30 {
+		return false, errors.New("invalid scrypt cost")
+	}
+
+	salt, err := decodeAdaptedBase64(parts[3])
+	if err != nil {
+		return false, fmt.Errorf("invalid scrypt salt: %w", err)
+	}
+	key, err := decodeAdaptedBase64(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("invalid scrypt key: %w", err)
+	}
+
+	derived, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(derived, key) == 1, nil
+}
+
+// decodeAdaptedBase64 reads passlib's base64 variant ('.' instead of '+', no
+// padding) as well as plain unpadded base64
+func decodeAdaptedBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.ReplaceAll(s, ".", "+"), "=")
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/manish-npx/go-echo-pg/internal/config"
+)
+
+// Known-answer vectors. The RFC 7914 ones come from sections 11 and 12, the
+// passlib one from its scrypt documentation; the rest were computed with
+// Python's hashlib.
+const (
+	// RFC 7914 PBKDF2-HMAC-SHA256: P="passwd", S="salt", c=1, dkLen=64
+	rfcPBKDF2Django = "pbkdf2_sha256$1$salt$VawEblbjCJ/sFpHCJUS2BflBhSFt3gRl5oudV8INrLxJypzM8Xm2RZkWZLOdd+8xfHG4RbHjC9UJESBB06GXgw=="
+	// Django layout: "correct horse", salt "seasalt", 1000 iterations
+	djangoPBKDF2 = "pbkdf2_sha256$1000$seasalt$mQnueSakb748zqBAC1tmWVZsZbi2zPGZarEzTGdfmso="
+	// passlib layout: "correct horse", salt 0x01..0x10, 1000 iterations
+	passlibPBKDF2 = "$pbkdf2-sha256$1000$AQIDBAUGBwgJCgsMDQ4PEA$YwKfNMrnHLcz6zlkYSHCNthMmUVmRkplZtxCdAZgV8M"
+	// RFC 7914 scrypt: P="password", S="NaCl", N=1024, r=8, p=16, dkLen=64
+	rfcScrypt = "$scrypt$ln=10,r=8,p=16$TmFDbA$/bq.HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA"
+	// passlib documentation example for "password"
+	passlibScrypt = "$scrypt$ln=16,r=8,p=1$aM15713r3Xsvxbi31lqr1Q$nFNh2CVHVjNldFVKDHDlm4CbdRSCdEBsjjJxD.iCs5E"
+)
+
+func TestLegacyPasswordHashes(t *testing.T) {
+	hasher, err := NewPasswordHasher(&config.PasswordHashingConfig{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		encoded  string
+		password string
+		want     bool
+	}{
+		{name: "rfc pbkdf2 django", encoded: rfcPBKDF2Django, password: "passwd", want: true},
+		{name: "rfc pbkdf2 wrong password", encoded: rfcPBKDF2Django, password: "password", want: false},
+		{name: "django pbkdf2", encoded: djangoPBKDF2, password: "correct horse", want: true},
+		{name: "django pbkdf2 wrong password", encoded: djangoPBKDF2, password: "Correct horse", want: false},
+		{name: "passlib pbkdf2", encoded: passlibPBKDF2, password: "correct horse", want: true},
+		{name: "passlib pbkdf2 wrong password", encoded: passlibPBKDF2, password: "correct horse ", want: false},
+		{name: "rfc scrypt", encoded: rfcScrypt, password: "password", want: true},
+		{name: "rfc scrypt wrong password", encoded: rfcScrypt, password: "passwd", want: false},
+		{name: "passlib scrypt", encoded: passlibScrypt, password: "password", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !hasher.Supports(tt.encoded) {
+				t.Fatalf("Supports(%q) = false", tt.encoded)
+			}
+
+			got, err := hasher.Verify(tt.encoded, tt.password)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+
+			if !hasher.NeedsRehash(tt.encoded) {
+				t.Error("NeedsRehash = false for a legacy hash")
+			}
+		})
+	}
+}
+
+func TestLegacyPasswordHashesMalformed(t *testing.T) {
+	hasher, err := NewPasswordHasher(&config.PasswordHashingConfig{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+
+	for _, encoded := range []string{
+		"pbkdf2_sha256$abc$salt$VawEblbj",
+		"pbkdf2_sha256$0$salt$VawEblbj",
+		"pbkdf2_sha256$1$salt",
+		"$pbkdf2-sha256$1000$!!$YwKf",
+		"$scrypt$ln=0,r=8,p=1$TmFDbA$/bq.HJ00",
+		"$scrypt$ln=31,r=8,p=1$TmFDbA$/bq.HJ00",
+		"$scrypt$r=8$TmFDbA$/bq.HJ00",
+	} {
+		if ok, err := hasher.Verify(encoded, "password"); err == nil || ok {
+			t.Errorf("Verify(%q) = %v, %v; want an error", encoded, ok, err)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -84,11 +86,17 @@ func (r *ResponseHelper) Created(c echo.Context, data interface{}) error {
 
 // Error responses with logging
 func (r *ResponseHelper) Error(c echo.Context, status int, errorCode, message string, err error) error {
+	return r.ErrorWithDetails(c, status, errorCode, message, nil, err)
+}
+
+// ErrorWithDetails is Error with a machine-readable payload the client needs to recover
+func (r *ResponseHelper) ErrorWithDetails(c echo.Context, status int, errorCode, message string, details interface{}, err error) error {
 	response := r.buildBaseResponse(c)
 	response.Success = false
 	response.Error = &Error{
 		Code:    errorCode,
 		Message: message,
+		Details: details,
 	}
 
 	// Log appropriately based on status code
@@ -134,6 +142,15 @@ func (r *ResponseHelper) Conflict(c echo.Context, message string, err error) err
 	return r.Error(c, http.StatusConflict, "CONFLICT", message, err)
 }
 
+// Locked reports an account that is temporarily locked after repeated failures
+func (r *ResponseHelper) Locked(c echo.Context, message string, err error) error {
+	return r.Error(c, http.StatusLocked, "ACCOUNT_LOCKED", message, err)
+}
+
+func (r *ResponseHelper) TooManyRequests(c echo.Context, message string, err error) error {
+	return r.Error(c, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", message, err)
+}
+
 func (r *ResponseHelper) ValidationError(c echo.Context, details interface{}, err error) error {
 	response := r.buildBaseResponse(c)
 	response.Success = false
@@ -155,3 +172,8 @@ func (r *ResponseHelper) ValidationError(c echo.Context, details interface{}, er
 func (r *ResponseHelper) InternalServerError(c echo.Context, err error) error {
 	return r.Error(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error", err)
 }
+
+// SetRetryAfter sets the Retry-After header to d rounded up to whole seconds
+func SetRetryAfter(c echo.Context, d time.Duration) {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/manish-npx/go-echo-pg/internal/config"
+)
+
+type cookieSessionKey struct{}
+
+// WithCookieSession marks the request as asking for a cookie session rather than
+// bearer tokens. It is put in the request context by middleware.SessionMode.
+func WithCookieSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cookieSessionKey{}, true)
+}
+
+// CookieSessionRequested reports whether logins in ctx should start a cookie session
+func CookieSessionRequested(ctx context.Context) bool {
+	requested, _ := ctx.Value(cookieSessionKey{}).(bool)
+	return requested
+}
+
+// SetSessionCookies hands the browser its session. The secret is HttpOnly; the
+// CSRF token is readable by scripts so the app can echo it in a header.
+func SetSessionCookies(c echo.Context, cfg *config.SessionCookieConfig, secret, csrfToken string, expiresAt time.Time) {
+	c.SetCookie(sessionCookie(cfg, cfg.Name, secret, expiresAt, true))
+	c.SetCookie(sessionCookie(cfg, cfg.CSRFName, csrfToken, expiresAt, false))
+}
+
+// ClearSessionCookies expires both session cookies
+func ClearSessionCookies(c echo.Context, cfg *config.SessionCookieConfig) {
+	for _, cookie := range []*http.Cookie{
+		sessionCookie(cfg, cfg.Name, "", time.Unix(0, 0), true),
+		sessionCookie(cfg, cfg.CSRFName, "", time.Unix(0, 0), false),
+	} {
+		cookie.MaxAge = -1
+		c.SetCookie(cookie)
+	}
+}
+
+func sessionCookie(cfg *config.SessionCookieConfig, name, value string, expiresAt time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     cfg.Path,
+		Domain:   cfg.Domain,
+		Expires:  expiresAt,
+		Secure:   cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: sameSiteMode(cfg.SameSite),
+	}
+}
+
+func sameSiteMode(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
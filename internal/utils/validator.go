@@ -2,6 +2,7 @@ package utils
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -20,6 +21,12 @@ func NewValidator() *CustomValidator {
 	return &CustomValidator{validator: v}
 }
 
+// NormalizeEmail is the form emails are stored, looked up and rate limited in.
+// Addresses are treated case-insensitively throughout.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func (cv *CustomValidator) Validate(i interface{}) error {
 	if err := cv.validator.Struct(i); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
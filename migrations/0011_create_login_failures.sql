@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE login_failures (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
+
+-- +migrate Down
+DROP TABLE login_failures;
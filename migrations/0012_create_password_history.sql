@@ -0,0 +1,15 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE TABLE password_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_history_user_created ON password_history(user_id, created_at DESC);
+
+-- +migrate Down
+DROP TABLE password_history;
+ALTER TABLE users DROP COLUMN password_changed_at;
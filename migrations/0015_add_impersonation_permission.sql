@@ -0,0 +1,10 @@
+-- +migrate Up
+INSERT INTO permissions (name, description) VALUES
+    ('users:impersonate', 'Act as another user for support purposes');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'users:impersonate';
+
+-- +migrate Down
+DELETE FROM permissions WHERE name = 'users:impersonate';
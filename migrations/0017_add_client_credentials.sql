@@ -0,0 +1,11 @@
+-- +migrate Up
+ALTER TABLE oauth_clients
+    ADD COLUMN grant_types TEXT[] NOT NULL DEFAULT '{authorization_code,refresh_token}';
+
+-- Service tokens have no user
+ALTER TABLE revoked_tokens ALTER COLUMN user_id DROP NOT NULL;
+
+-- +migrate Down
+DELETE FROM revoked_tokens WHERE user_id IS NULL;
+ALTER TABLE revoked_tokens ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE oauth_clients DROP COLUMN grant_types;
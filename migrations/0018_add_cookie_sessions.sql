@@ -0,0 +1,11 @@
+-- +migrate Up
+ALTER TABLE user_sessions
+    ADD COLUMN cookie_hash VARCHAR(64) UNIQUE,
+    ADD COLUMN csrf_token_hash VARCHAR(64),
+    ADD COLUMN expires_at TIMESTAMPTZ;
+
+-- +migrate Down
+ALTER TABLE user_sessions
+    DROP COLUMN expires_at,
+    DROP COLUMN csrf_token_hash,
+    DROP COLUMN cookie_hash;
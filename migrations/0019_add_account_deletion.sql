@@ -0,0 +1,12 @@
+-- +migrate Up
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN purge_at TIMESTAMPTZ;
+
+CREATE INDEX idx_users_purge_at ON users(purge_at) WHERE purge_at IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX idx_users_purge_at;
+ALTER TABLE users
+    DROP COLUMN purge_at,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(255);
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN pending_email;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE user_tokens ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE user_tokens DROP COLUMN attempts;
@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE user_sessions
+    ADD COLUMN client_id VARCHAR(64),
+    ADD COLUMN scopes TEXT[];
+
+-- +migrate Down
+ALTER TABLE user_sessions
+    DROP COLUMN scopes,
+    DROP COLUMN client_id;
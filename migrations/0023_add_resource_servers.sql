@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE oauth_clients ADD COLUMN resource_server BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE oauth_clients DROP COLUMN resource_server;
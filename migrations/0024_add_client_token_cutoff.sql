@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE oauth_clients
+    ADD COLUMN disabled_at TIMESTAMPTZ,
+    ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
+
+-- +migrate Down
+ALTER TABLE oauth_clients
+    DROP COLUMN tokens_revoked_at,
+    DROP COLUMN disabled_at;
@@ -0,0 +1,13 @@
+-- +migrate Up
+-- Accounts that would collide once lowercased are left as they are
+UPDATE users u
+SET email = LOWER(u.email)
+WHERE u.email <> LOWER(u.email)
+    AND NOT EXISTS (SELECT 1 FROM users o WHERE o.id <> u.id AND LOWER(o.email) = LOWER(u.email));
+
+UPDATE users SET pending_email = LOWER(pending_email) WHERE pending_email <> LOWER(pending_email);
+
+CREATE INDEX idx_users_email_lower ON users(LOWER(email));
+
+-- +migrate Down
+DROP INDEX idx_users_email_lower;
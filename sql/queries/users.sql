@@ -5,7 +5,9 @@ RETURNING *;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users
-WHERE email = $1;
+WHERE LOWER(email) = LOWER($1)
+ORDER BY created_at
+LIMIT 1;
 
 -- name: GetUserByID :one
 SELECT * FROM users
@@ -13,15 +15,42 @@ WHERE id = $1;
 
 -- name: UpdateUser :one
 UPDATE users
-SET name = $2, email = $3, updated_at = NOW()
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetPendingEmail :exec
+UPDATE users
+SET pending_email = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ChangeEmail :one
+UPDATE users
+SET email = $2, pending_email = NULL, email_verified_at = NOW(), updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
 -- name: DeleteUser :exec
 DELETE FROM users
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NOT NULL AND purge_at <= NOW();
 
 -- name: ListUsers :many
 SELECT * FROM users
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
\ No newline at end of file
+LIMIT $1 OFFSET $2;
+
+-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = NOW(), purge_at = $2
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreUser :exec
+UPDATE users
+SET deleted_at = NULL, purge_at = NULL
+WHERE id = $1;
+
+-- name: ListUsersDueForPurge :many
+SELECT id FROM users
+WHERE purge_at <= NOW()
+ORDER BY purge_at
+LIMIT $1;
@@ -0,0 +1,16 @@
+-- Track when the user proved ownership of their email address
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Create user_tokens table (single-use tokens for email flows)
+CREATE TABLE user_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
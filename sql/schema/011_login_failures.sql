@@ -0,0 +1,7 @@
+-- Create login_failures table (consecutive failed logins per account, shared by all replicas)
+CREATE TABLE login_failures (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
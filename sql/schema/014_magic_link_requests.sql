@@ -0,0 +1,9 @@
+-- Create magic_link_requests table (per-email rate limit for login links)
+CREATE TABLE magic_link_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email VARCHAR(255) NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_magic_link_requests_email_requested ON magic_link_requests(email, requested_at);
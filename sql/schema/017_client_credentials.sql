@@ -0,0 +1,6 @@
+-- Record which grants each OAuth client may use (client_credentials for machine clients)
+ALTER TABLE oauth_clients
+    ADD COLUMN grant_types TEXT[] NOT NULL DEFAULT '{authorization_code,refresh_token}';
+
+-- Allow revoking service tokens, which have no user
+ALTER TABLE revoked_tokens ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,5 @@
+-- Let sessions be carried by a browser cookie instead of bearer tokens
+ALTER TABLE user_sessions
+    ADD COLUMN cookie_hash VARCHAR(64) UNIQUE,
+    ADD COLUMN csrf_token_hash VARCHAR(64),
+    ADD COLUMN expires_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- Soft-delete accounts and record when they are to be purged
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN purge_at TIMESTAMPTZ;
+
+-- Create indexes
+CREATE INDEX idx_users_purge_at ON users(purge_at) WHERE purge_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Guesses made against a token, e.g. TOTP codes sent with an MFA challenge
+ALTER TABLE user_tokens ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
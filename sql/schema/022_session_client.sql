@@ -0,0 +1,4 @@
+-- Sessions started for an OAuth client, and the scopes the user granted it
+ALTER TABLE user_sessions
+    ADD COLUMN client_id VARCHAR(64),
+    ADD COLUMN scopes TEXT[];
@@ -0,0 +1,2 @@
+-- APIs that validate our tokens; only they may call the introspection endpoint
+ALTER TABLE oauth_clients ADD COLUMN resource_server BOOLEAN NOT NULL DEFAULT FALSE;
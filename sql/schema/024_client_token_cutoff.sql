@@ -0,0 +1,5 @@
+-- Disabling a client or rotating its secret invalidates the tokens issued before
+-- tokens_revoked_at
+ALTER TABLE oauth_clients
+    ADD COLUMN disabled_at TIMESTAMPTZ,
+    ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
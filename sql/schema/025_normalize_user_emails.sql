@@ -0,0 +1,2 @@
+-- Emails are stored lowercased and looked up case-insensitively
+CREATE INDEX idx_users_email_lower ON users(LOWER(email));